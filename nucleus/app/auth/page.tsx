@@ -1,6 +1,6 @@
 "use client";
 
-import { useState } from "react";
+import { useEffect, useState } from "react";
 import { Button } from "@/components/ui/button";
 import {
   Card,
@@ -15,6 +15,7 @@ import { Tabs, TabsContent, TabsList, TabsTrigger } from "@/components/ui/tabs";
 import { Server, ArrowLeft, Eye, EyeOff } from "lucide-react";
 import Link from "next/link";
 import axios from "axios";
+import { API_URL, apiError } from "@/lib/api";
 import { useRouter } from "next/navigation";
 
 export default function AuthPage() {
@@ -25,6 +26,9 @@ export default function AuthPage() {
   const [loginEmail, setLoginEmail] = useState("");
   const [loginPassword, setLoginPassword] = useState("");
   const [loginError, setLoginError] = useState("");
+  const [mfaToken, setMfaToken] = useState("");
+  const [mfaCode, setMfaCode] = useState("");
+  const [ssoEnabled, setSsoEnabled] = useState(false);
   const [registerUsername, setRegisterUsername] = useState("");
   const [registerEmail, setRegisterEmail] = useState("");
   const [registerPassword, setRegisterPassword] = useState("");
@@ -32,12 +36,22 @@ export default function AuthPage() {
   const [registerError, setRegisterError] = useState("");
   const router = useRouter();
 
+  useEffect(() => {
+    axios
+      .get(`${API_URL}/auth/oidc`)
+      .then((res) => setSsoEnabled(res.data.enabled))
+      .catch(() => setSsoEnabled(false));
+  }, []);
+
   const handleLogin = async (e: React.FormEvent) => {
     e.preventDefault();
     setLoginError("");
+    if (mfaToken) {
+      return handleTwoFactor();
+    }
     try {
-      await axios.post(
-        "http://localhost:9872/auth/login",
+      const res = await axios.post(
+        `${API_URL}/auth/login`,
         // TODO: Add it in a env file
         {
           email: loginEmail,
@@ -48,9 +62,41 @@ export default function AuthPage() {
           validateStatus: (status) => status >= 200 && status < 300 
         },
       );
+      if (res.data.mfa_required) {
+        setMfaToken(res.data.mfa_token);
+        return;
+      }
+      if (res.data.mfa_enrollment_required) {
+        setLoginError("Two-factor authentication is required for your account. Set it up with /auth/2fa/setup.");
+        return;
+      }
+      router.push("/create-server");
+    } catch (err: any) {
+      setLoginError(apiError(err, "Login failed"));
+    }
+  };
+
+  // Second login step: a TOTP code, or a recovery code (xxxx-xxxx)
+  const handleTwoFactor = async () => {
+    const code = mfaCode.trim();
+    try {
+      await axios.post(
+        `${API_URL}/auth/2fa/login`,
+        code.length === 6
+          ? { mfa_token: mfaToken, code }
+          : { mfa_token: mfaToken, recovery_code: code },
+        {
+          withCredentials: true,
+          validateStatus: (status) => status >= 200 && status < 300
+        },
+      );
       router.push("/create-server");
     } catch (err: any) {
-      setLoginError("Login failed");
+      if (err.response?.status === 401 && err.response?.data?.code !== "invalid_code") {
+        setMfaToken("");
+        setMfaCode("");
+      }
+      setLoginError("Invalid code");
     }
   };
 
@@ -63,7 +109,7 @@ export default function AuthPage() {
     }
     try {
       await axios.post(
-        "http://localhost:9872/auth/register",
+        `${API_URL}/auth/register`,
         {
           username: registerUsername,
           email: registerEmail,
@@ -80,7 +126,7 @@ export default function AuthPage() {
       setRegisterPassword("");
       setRegisterConfirmPassword("");
     } catch (err: any) {
-      setRegisterError("Registration failed");
+      setRegisterError(apiError(err, "Registration failed"));
     }
   };
 
@@ -198,9 +244,28 @@ export default function AuthPage() {
                         </button>
                       </div>
                     </div>
+                    {mfaToken && (
+                      <div className="space-y-2">
+                        <Label htmlFor="login-code" className="text-white">
+                          Authentication code
+                        </Label>
+                        <Input
+                          id="login-code"
+                          type="text"
+                          inputMode="numeric"
+                          autoComplete="one-time-code"
+                          placeholder="6-digit code or recovery code"
+                          className="bg-white/10 border-white/20 text-white placeholder:text-slate-400 focus:border-blue-500"
+                          value={mfaCode}
+                          onChange={(e) => setMfaCode(e.target.value)}
+                          autoFocus
+                          required
+                        />
+                      </div>
+                    )}
                     <div className="flex items-center justify-between">
                       <Link
-                        href="#"
+                        href="/auth/reset-password"
                         className="text-sm text-blue-400 hover:text-blue-300"
                       >
                         Forgot password?
@@ -218,6 +283,15 @@ export default function AuthPage() {
                       "Sign In"
                     </Button>
                   </form>
+                  {ssoEnabled && (
+                    <Button
+                      asChild
+                      variant="outline"
+                      className="w-full border-white/20 bg-white/5 text-white hover:bg-white/10"
+                    >
+                      <a href={`${API_URL}/auth/oidc/login`}>Sign in with SSO</a>
+                    </Button>
+                  )}
                 </CardContent>
               </Card>
             </TabsContent>
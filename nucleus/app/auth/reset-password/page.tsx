@@ -0,0 +1,129 @@
+"use client";
+
+import { useEffect, useState } from "react";
+import { Button } from "@/components/ui/button";
+import {
+  Card,
+  CardContent,
+  CardDescription,
+  CardHeader,
+  CardTitle,
+} from "@/components/ui/card";
+import { Input } from "@/components/ui/input";
+import { Label } from "@/components/ui/label";
+import Link from "next/link";
+import axios from "axios";
+import { API_URL, apiError } from "@/lib/api";
+
+// Without a token this asks for the account email and mails a reset link;
+// the link brings the user back here with ?token= to choose a password.
+export default function ResetPasswordPage() {
+  const [token, setToken] = useState("");
+  const [email, setEmail] = useState("");
+  const [password, setPassword] = useState("");
+  const [confirmPassword, setConfirmPassword] = useState("");
+  const [message, setMessage] = useState("");
+  const [error, setError] = useState("");
+
+  useEffect(() => {
+    setToken(new URLSearchParams(window.location.search).get("token") ?? "");
+  }, []);
+
+  const handleForgot = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError("");
+    try {
+      const res = await axios.post(`${API_URL}/auth/password/forgot`, { email });
+      setMessage(res.data.message);
+    } catch (err: any) {
+      setError("Request failed");
+    }
+  };
+
+  const handleReset = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError("");
+    if (password !== confirmPassword) {
+      setError("Passwords do not match");
+      return;
+    }
+    try {
+      await axios.post(`${API_URL}/auth/password/reset`, { token, password });
+      setMessage("Your password was updated, you can now sign in.");
+    } catch (err: any) {
+      setError(apiError(err, "Reset failed"));
+    }
+  };
+
+  return (
+    <div className="min-h-screen bg-gradient-to-br from-slate-900 via-blue-900 to-slate-800 text-white flex items-center justify-center px-4">
+      <Card className="w-full max-w-md bg-white/10 backdrop-blur-sm border-white/20">
+        <CardHeader className="text-center">
+          <CardTitle className="text-2xl text-white">Reset Password</CardTitle>
+          <CardDescription className="text-slate-300">
+            {token ? "Choose a new password" : "We will email you a reset link"}
+          </CardDescription>
+        </CardHeader>
+        <CardContent className="space-y-4">
+          {message ? (
+            <div className="text-center space-y-4">
+              <p className="text-slate-200">{message}</p>
+              <Link href="/auth" className="text-sm text-blue-400 hover:text-blue-300">
+                Back to sign in
+              </Link>
+            </div>
+          ) : (
+            <form onSubmit={token ? handleReset : handleForgot} className="space-y-4">
+              {token ? (
+                <>
+                  <div className="space-y-2">
+                    <Label htmlFor="password" className="text-white">New password</Label>
+                    <Input
+                      id="password"
+                      type="password"
+                      className="bg-white/10 border-white/20 text-white"
+                      value={password}
+                      onChange={(e) => setPassword(e.target.value)}
+                      required
+                    />
+                  </div>
+                  <div className="space-y-2">
+                    <Label htmlFor="confirm-password" className="text-white">Confirm password</Label>
+                    <Input
+                      id="confirm-password"
+                      type="password"
+                      className="bg-white/10 border-white/20 text-white"
+                      value={confirmPassword}
+                      onChange={(e) => setConfirmPassword(e.target.value)}
+                      required
+                    />
+                  </div>
+                </>
+              ) : (
+                <div className="space-y-2">
+                  <Label htmlFor="email" className="text-white">Email</Label>
+                  <Input
+                    id="email"
+                    type="email"
+                    placeholder="Enter your email"
+                    className="bg-white/10 border-white/20 text-white placeholder:text-slate-400"
+                    value={email}
+                    onChange={(e) => setEmail(e.target.value)}
+                    required
+                  />
+                </div>
+              )}
+              {error && <div className="text-red-400 text-sm text-center">{error}</div>}
+              <Button
+                type="submit"
+                className="w-full bg-gradient-to-r from-blue-600 to-blue-700 hover:from-blue-700 hover:to-blue-800 text-white"
+              >
+                {token ? "Set Password" : "Send Reset Link"}
+              </Button>
+            </form>
+          )}
+        </CardContent>
+      </Card>
+    </div>
+  );
+}
@@ -0,0 +1,45 @@
+"use client";
+
+import { useEffect, useState } from "react";
+import {
+  Card,
+  CardContent,
+  CardHeader,
+  CardTitle,
+} from "@/components/ui/card";
+import Link from "next/link";
+import axios from "axios";
+import { API_URL } from "@/lib/api";
+
+// Landing page of the link mailed at registration.
+export default function VerifyEmailPage() {
+  const [status, setStatus] = useState("Verifying your email address...");
+
+  useEffect(() => {
+    const token = new URLSearchParams(window.location.search).get("token");
+    if (!token) {
+      setStatus("This link is missing its token.");
+      return;
+    }
+    axios
+      .post(`${API_URL}/auth/email/verify`, { token })
+      .then(() => setStatus("Your email address is verified."))
+      .catch(() => setStatus("This link is invalid or has expired."));
+  }, []);
+
+  return (
+    <div className="min-h-screen bg-gradient-to-br from-slate-900 via-blue-900 to-slate-800 text-white flex items-center justify-center px-4">
+      <Card className="w-full max-w-md bg-white/10 backdrop-blur-sm border-white/20">
+        <CardHeader className="text-center">
+          <CardTitle className="text-2xl text-white">Email Verification</CardTitle>
+        </CardHeader>
+        <CardContent className="text-center space-y-4">
+          <p className="text-slate-200">{status}</p>
+          <Link href="/auth" className="text-sm text-blue-400 hover:text-blue-300">
+            Go to sign in
+          </Link>
+        </CardContent>
+      </Card>
+    </div>
+  );
+}
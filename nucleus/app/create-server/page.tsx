@@ -4,6 +4,7 @@ import type React from "react"
 
 import { useState } from "react"
 import axios from "axios"
+import { API_URL } from "@/lib/api"
 import { Button } from "@/components/ui/button"
 import { Card, CardContent, CardDescription, CardHeader, CardTitle } from "@/components/ui/card"
 import { Input } from "@/components/ui/input"
@@ -334,14 +335,14 @@ function CreateServerPageContent() {
       }
 
       await axios.post(
-        "http://localhost:7402/lxc",
+        `${API_URL}/lxc`,
         payload,
         { withCredentials: true, validateStatus: (s) => s >= 200 && s < 300 },
       )
 
       // Get Proxmox server details to create LXC
       try {
-        const proxRes = await axios.get('http://localhost:7790/prox', { withCredentials: true })
+        const proxRes = await axios.get(`${API_URL}/prox`, { withCredentials: true })
         const prox = Array.isArray(proxRes.data) && proxRes.data.length > 0 ? proxRes.data[0] : null
         
         if (prox) {
@@ -373,13 +374,13 @@ function CreateServerPageContent() {
           console.log('Create Command:', createCommand)
           
           // Execute the create command via ssh-service
-          const createResponse = await axios.post('http://localhost:7789/execute', {
+          const createResponse = await axios.post(`${API_URL}/execute`, {
             host: prox.host.replace('https://', '').replace(':8006', ''), // Extract IP from URL
             port: "22", // SSH port
             username: prox.username,
             password: prox.password, // Note: This should be handled securely in production
             command: createCommand,
-          }, { withCredentials: false })
+          }, { withCredentials: true })
           
           // Wait a moment for the container to fully start
           await new Promise(resolve => setTimeout(resolve, 5000))
@@ -400,13 +401,13 @@ function CreateServerPageContent() {
             console.log('Install Command:', installCommand)
             
             // Execute the install command
-            const installResponse = await axios.post('http://localhost:7789/execute', {
+            const installResponse = await axios.post(`${API_URL}/execute`, {
               host: prox.host.replace('https://', '').replace(':8006', ''), // Extract IP from URL
               port: "22", // SSH port
               username: prox.username,
               password: prox.password,
               command: installCommand,
-            }, { withCredentials: false })
+            }, { withCredentials: true })
             
             console.log('Package installation completed')
           }
@@ -8,6 +8,7 @@ import { useToast } from "@/hooks/use-toast"
 import { ProxmoxServerModal } from "@/components/proxmox-server-modal"
 import { AuthGuard, useAuth } from "@/components/auth-guard"
 import axios from "axios"
+import { API_URL } from "@/lib/api"
 
 interface ServerItem {
   id: string
@@ -55,7 +56,7 @@ function ManageServersPageContent() {
     const loadServers = async () => {
       try {
         // LXC servers
-        const lxcRes = await axios.get('http://localhost:7402/lxc', { withCredentials: true })
+        const lxcRes = await axios.get(`${API_URL}/lxc`, { withCredentials: true })
         const lxcConfigs: LXCConfig[] = lxcRes.data
         const lxcServers: ServerItem[] = lxcConfigs.map(cfg => ({
           id: `lxc-${cfg.ID}`,
@@ -72,7 +73,7 @@ function ManageServersPageContent() {
           })(),
         }))
 
-        const response = await axios.get('http://localhost:7790/prox', {
+        const response = await axios.get(`${API_URL}/prox`, {
           withCredentials: true,
         })
         
@@ -111,7 +112,7 @@ function ManageServersPageContent() {
       if (server?.type === "Proxmox Server") {
         // Delete from backend
         const configId = id.replace('prox-', '')
-        await axios.delete(`http://localhost:7790/prox/${configId}`, {
+        await axios.delete(`${API_URL}/prox/${configId}`, {
           withCredentials: true,
         })
         
@@ -126,7 +127,7 @@ function ManageServersPageContent() {
         // For LXC servers, we need to destroy the actual container first
         try {
           // Get Proxmox server details
-          const proxRes = await axios.get('http://localhost:7790/prox', { withCredentials: true })
+          const proxRes = await axios.get(`${API_URL}/prox`, { withCredentials: true })
           const proxConfigs: ProxConfig[] = proxRes.data
           
           if (proxConfigs.length === 0) {
@@ -142,13 +143,13 @@ function ManageServersPageContent() {
           console.log('Searching for container with name:', name)
           console.log('Using command:', grepCommand)
           
-          const findResponse = await axios.post('http://localhost:7789/execute', {
+          const findResponse = await axios.post(`${API_URL}/execute`, {
             host: proxConfig.host.replace('https://', '').replace(':8006', ''),
             port: "22",
             username: proxConfig.username,
             password: proxConfig.password,
             command: grepCommand,
-          }, { withCredentials: false })
+          }, { withCredentials: true })
           
           const containerLine = findResponse.data.output?.trim()
           console.log('Found container line:', containerLine)
@@ -162,13 +163,13 @@ function ManageServersPageContent() {
             const shutdownCommand = `pct shutdown ${containerId}`
             
             try {
-              await axios.post('http://localhost:7789/execute', {
+              await axios.post(`${API_URL}/execute`, {
                 host: proxConfig.host.replace('https://', '').replace(':8006', ''),
                 port: "22",
                 username: proxConfig.username,
                 password: proxConfig.password,
                 command: shutdownCommand,
-              }, { withCredentials: false })
+              }, { withCredentials: true })
               
               console.log(`LXC container ${containerId} shutdown initiated`)
               
@@ -181,13 +182,13 @@ function ManageServersPageContent() {
             // Then destroy the LXC container
             const destroyCommand = `pct destroy ${containerId} --force`
             
-            await axios.post('http://localhost:7789/execute', {
+            await axios.post(`${API_URL}/execute`, {
               host: proxConfig.host.replace('https://', '').replace(':8006', ''),
               port: "22",
               username: proxConfig.username,
               password: proxConfig.password,
               command: destroyCommand,
-            }, { withCredentials: false })
+            }, { withCredentials: true })
             
             console.log(`LXC container ${containerId} destroyed successfully`)
           } else {
@@ -200,7 +201,7 @@ function ManageServersPageContent() {
         
         // Delete the configuration from lxc-service
         const configId = id.replace('lxc-', '')
-        await axios.delete(`http://localhost:7402/lxc/${configId}`, {
+        await axios.delete(`${API_URL}/lxc/${configId}`, {
           withCredentials: true,
         })
         
@@ -258,7 +259,7 @@ function ManageServersPageContent() {
       
       if (server?.type === "Nucleus Server") {
         // Get Proxmox server details
-        const proxRes = await axios.get('http://localhost:7790/prox', { withCredentials: true })
+        const proxRes = await axios.get(`${API_URL}/prox`, { withCredentials: true })
         const proxConfigs: ProxConfig[] = proxRes.data
         
         if (proxConfigs.length === 0) {
@@ -273,13 +274,13 @@ function ManageServersPageContent() {
         console.log('Searching for container to shutdown:', name)
         console.log('Using command:', grepCommand)
         
-        const findResponse = await axios.post('http://localhost:7789/execute', {
+        const findResponse = await axios.post(`${API_URL}/execute`, {
           host: proxConfig.host.replace('https://', '').replace(':8006', ''),
           port: "22",
           username: proxConfig.username,
           password: proxConfig.password,
           command: grepCommand,
-        }, { withCredentials: false })
+        }, { withCredentials: true })
         
         const containerLine = findResponse.data.output?.trim()
         console.log('Found container line:', containerLine)
@@ -292,13 +293,13 @@ function ManageServersPageContent() {
           // Shut down the LXC container gracefully
           const shutdownCommand = `pct shutdown ${containerId}`
           
-          await axios.post('http://localhost:7789/execute', {
+          await axios.post(`${API_URL}/execute`, {
             host: proxConfig.host.replace('https://', '').replace(':8006', ''),
             port: "22",
             username: proxConfig.username,
             password: proxConfig.password,
             command: shutdownCommand,
-          }, { withCredentials: false })
+          }, { withCredentials: true })
           
           console.log(`LXC container ${containerId} shutdown initiated`)
           
@@ -362,7 +363,7 @@ function ManageServersPageContent() {
       
       if (server?.type === "Nucleus Server") {
         // Get Proxmox server details
-        const proxRes = await axios.get('http://localhost:7790/prox', { withCredentials: true })
+        const proxRes = await axios.get(`${API_URL}/prox`, { withCredentials: true })
         const proxConfigs: ProxConfig[] = proxRes.data
         
         if (proxConfigs.length === 0) {
@@ -377,13 +378,13 @@ function ManageServersPageContent() {
         console.log('Searching for container to start:', name)
         console.log('Using command:', grepCommand)
         
-        const findResponse = await axios.post('http://localhost:7789/execute', {
+        const findResponse = await axios.post(`${API_URL}/execute`, {
           host: proxConfig.host.replace('https://', '').replace(':8006', ''),
           port: "22",
           username: proxConfig.username,
           password: proxConfig.password,
           command: grepCommand,
-        }, { withCredentials: false })
+        }, { withCredentials: true })
         
         const containerLine = findResponse.data.output?.trim()
         console.log('Found container line:', containerLine)
@@ -396,13 +397,13 @@ function ManageServersPageContent() {
           // Start the LXC container
           const startCommand = `pct start ${containerId}`
           
-          await axios.post('http://localhost:7789/execute', {
+          await axios.post(`${API_URL}/execute`, {
             host: proxConfig.host.replace('https://', '').replace(':8006', ''),
             port: "22",
             username: proxConfig.username,
             password: proxConfig.password,
             command: startCommand,
-          }, { withCredentials: false })
+          }, { withCredentials: true })
           
           console.log(`LXC container ${containerId} start initiated`)
           
@@ -464,7 +465,7 @@ function ManageServersPageContent() {
       
       if (server?.type === "Nucleus Server") {
         // Get Proxmox server details
-        const proxRes = await axios.get('http://localhost:7790/prox', { withCredentials: true })
+        const proxRes = await axios.get(`${API_URL}/prox`, { withCredentials: true })
         const proxConfigs: ProxConfig[] = proxRes.data
         
         if (proxConfigs.length === 0) {
@@ -479,13 +480,13 @@ function ManageServersPageContent() {
         console.log('Searching for container to open shell:', name)
         console.log('Using command:', grepCommand)
         
-        const findResponse = await axios.post('http://localhost:7789/execute', {
+        const findResponse = await axios.post(`${API_URL}/execute`, {
           host: proxConfig.host.replace('https://', '').replace(':8006', ''),
           port: "22",
           username: proxConfig.username,
           password: proxConfig.password,
           command: grepCommand,
-        }, { withCredentials: false })
+        }, { withCredentials: true })
         
         const containerLine = findResponse.data.output?.trim()
         console.log('Found container line:', containerLine)
@@ -499,13 +500,13 @@ function ManageServersPageContent() {
           const hostnameCommand = `hostname`
           
           try {
-            const hostnameResponse = await axios.post('http://localhost:7789/execute', {
+            const hostnameResponse = await axios.post(`${API_URL}/execute`, {
               host: proxConfig.host.replace('https://', '').replace(':8006', ''),
               port: "22",
               username: proxConfig.username,
               password: proxConfig.password,
               command: hostnameCommand,
-            }, { withCredentials: false })
+            }, { withCredentials: true })
             
             const actualHostname = hostnameResponse.data.output?.trim()
             console.log('Engine hostname:', actualHostname)
@@ -588,7 +589,7 @@ function ManageServersPageContent() {
       if (data.id) {
         // Editing existing server
         const configId = data.id.replace('prox-', '')
-        const response = await axios.put(`http://localhost:7790/prox/${configId}`, proxData, {
+        const response = await axios.put(`${API_URL}/prox/${configId}`, proxData, {
           withCredentials: true,
         })
 
@@ -630,7 +631,7 @@ function ManageServersPageContent() {
         }
 
         // Adding new server
-        const response = await axios.post('http://localhost:7790/prox', proxData, {
+        const response = await axios.post(`${API_URL}/prox`, proxData, {
           withCredentials: true,
         })
 
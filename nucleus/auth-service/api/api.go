@@ -0,0 +1,37 @@
+// Package api serves the OpenAPI document of auth-service. The handlers
+// are tested against it and the Go client is generated from it.
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"sync"
+
+	"github.com/gofiber/fiber/v3"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed openapi.yaml
+var document []byte
+
+// Spec returns the OpenAPI document as JSON.
+var Spec = sync.OnceValues(func() ([]byte, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(document, &doc); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+})
+
+// Register serves the OpenAPI document at /openapi.json. It must come
+// before the routes requiring authentication: the document is public.
+func Register(app *fiber.App) {
+	app.Get("/openapi.json", func(c fiber.Ctx) error {
+		spec, err := Spec()
+		if err != nil {
+			return err
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Send(spec)
+	})
+}
@@ -0,0 +1,75 @@
+package authn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Talfaza/authentification/config"
+	"github.com/Talfaza/authentification/logging"
+	"github.com/Talfaza/authentification/models"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCredentials is returned for an unknown login or a wrong
+// password alike.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Authenticator checks a login (an email, or a username for directories)
+// and password and returns the matching user. Its queries are made with
+// ctx, so they are traced under the login request.
+type Authenticator interface {
+	Authenticate(ctx context.Context, login, password string) (*models.User, error)
+}
+
+// Default is the authenticator used by Login, set up by Load.
+var Default Authenticator
+
+// Load picks the authenticators from cfg.AuthBackends, tried in order:
+// "local" checks passwords stored in the database, "ldap" binds against a
+// directory (see LoadLDAP).
+func Load(db *gorm.DB, cfg *config.Config) {
+	var chain Chain
+	for _, name := range cfg.AuthBackends {
+		switch name {
+		case "local":
+			chain = append(chain, &Local{DB: db})
+		case "ldap":
+			ldap, err := LoadLDAP(db, cfg)
+			if err != nil {
+				logging.Fatal("Invalid LDAP configuration", "error", err)
+			}
+			chain = append(chain, ldap)
+		default:
+			logging.Fatal("Unknown AUTH_BACKEND", "backend", name)
+		}
+	}
+
+	if len(chain) == 1 {
+		Default = chain[0]
+		return
+	}
+	Default = chain
+}
+
+// Chain tries each authenticator in turn until one accepts the login.
+type Chain []Authenticator
+
+func (c Chain) Authenticate(ctx context.Context, login, password string) (*models.User, error) {
+	var errs []error
+	for _, authenticator := range c {
+		user, err := authenticator.Authenticate(ctx, login, password)
+		if err == nil {
+			return user, nil
+		}
+		if !errors.Is(err, ErrInvalidCredentials) {
+			errs = append(errs, err)
+		}
+	}
+
+	// Report a backend being down rather than hiding it as a bad password
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCredentials, errors.Join(errs...))
+	}
+	return nil, ErrInvalidCredentials
+}
@@ -0,0 +1,273 @@
+package authn
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Talfaza/authentification/config"
+	"github.com/Talfaza/authentification/models"
+	"github.com/go-ldap/ldap/v3"
+	"gorm.io/gorm"
+)
+
+// Conn is the part of *ldap.Conn the LDAP authenticator uses, so tests can
+// stand in a directory.
+type Conn interface {
+	Bind(username, password string) error
+	Search(request *ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close() error
+}
+
+// LDAPConfig describes a directory and how its groups map to roles.
+type LDAPConfig struct {
+	// BindDN and BindPassword are the service account used to look users
+	// up. Leave empty to search anonymously.
+	BindDN       string
+	BindPassword string
+
+	BaseDN string
+	// UserFilter finds a user by login; every %s is replaced with the
+	// escaped login.
+	UserFilter    string
+	EmailAttr     string
+	UsernameAttr  string
+	GroupAttr     string
+	GroupRoles    map[string]string
+	DefaultRole   string
+	SearchTimeout time.Duration
+}
+
+// LDAP authenticates users by binding as them in a directory. Users are
+// provisioned on their first login, and when group roles are configured
+// their roles follow their directory groups.
+type LDAP struct {
+	Config LDAPConfig
+	Dial   func() (Conn, error)
+	DB     *gorm.DB
+}
+
+// Entry is a user found in the directory.
+type Entry struct {
+	DN       string
+	Email    string
+	Username string
+	Groups   []string
+}
+
+// LoadLDAP configures the LDAP authenticator from the ldap_* settings of
+// cfg, validated by config.Load.
+func LoadLDAP(db *gorm.DB, cfg *config.Config) (*LDAP, error) {
+	groupRoles, err := ParseLDAPGroupRoles(cfg.LDAPGroupRoles)
+	if err != nil {
+		return nil, err
+	}
+
+	ldapConfig := LDAPConfig{
+		BindDN:       cfg.LDAPBindDN,
+		BindPassword: cfg.LDAPBindPassword,
+		BaseDN:       cfg.LDAPBaseDN,
+		UserFilter:   cfg.LDAPUserFilter,
+		EmailAttr:    cfg.LDAPEmailAttr,
+		UsernameAttr: cfg.LDAPUsernameAttr,
+		GroupAttr:    cfg.LDAPGroupAttr,
+		GroupRoles:   groupRoles,
+		DefaultRole:  cfg.LDAPDefaultRole,
+	}
+	if ldapConfig.BaseDN == "" {
+		return nil, errors.New("LDAP base DN is required")
+	}
+
+	url := cfg.LDAPURL
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.LDAPInsecureSkipVerify}
+	startTLS := cfg.LDAPStartTLS
+	dial := func() (Conn, error) {
+		conn, err := ldap.DialURL(url, ldap.DialWithTLSConfig(tlsConfig))
+		if err != nil {
+			return nil, err
+		}
+		if startTLS {
+			if err := conn.StartTLS(tlsConfig); err != nil {
+				conn.Close()
+				return nil, err
+			}
+		}
+		return conn, nil
+	}
+
+	return NewLDAP(ldapConfig, dial, db)
+}
+
+// NewLDAP fills in defaults and validates config.
+func NewLDAP(config LDAPConfig, dial func() (Conn, error), db *gorm.DB) (*LDAP, error) {
+	if config.UserFilter == "" {
+		config.UserFilter = "(&(objectClass=person)(|(mail=%s)(uid=%s)))"
+	}
+	if config.EmailAttr == "" {
+		config.EmailAttr = "mail"
+	}
+	if config.UsernameAttr == "" {
+		config.UsernameAttr = "uid"
+	}
+	if config.GroupAttr == "" {
+		config.GroupAttr = "memberOf"
+	}
+	if config.DefaultRole == "" {
+		config.DefaultRole = models.RoleViewer
+	}
+	if !slices.Contains(models.Roles, config.DefaultRole) {
+		return nil, fmt.Errorf("unknown default role %q", config.DefaultRole)
+	}
+	if config.SearchTimeout == 0 {
+		config.SearchTimeout = 10 * time.Second
+	}
+	return &LDAP{Config: config, Dial: dial, DB: db}, nil
+}
+
+// ParseLDAPGroupRoles parses "group DN=role" pairs separated by ";". Group
+// DNs contain "=" themselves, so the role is what follows the last one.
+func ParseLDAPGroupRoles(s string) (map[string]string, error) {
+	groupRoles := map[string]string{}
+	for _, pair := range strings.Split(s, ";") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		i := strings.LastIndex(pair, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("expected group=role, got %q", pair)
+		}
+		group, role := normalizeDN(pair[:i]), strings.TrimSpace(pair[i+1:])
+		if !slices.Contains(models.Roles, role) {
+			return nil, fmt.Errorf("unknown role %q for group %q", role, group)
+		}
+		groupRoles[group] = role
+	}
+	return groupRoles, nil
+}
+
+// normalizeDN makes DNs comparable regardless of case and spacing.
+func normalizeDN(dn string) string {
+	if parsed, err := ldap.ParseDN(dn); err == nil {
+		parts := make([]string, 0, len(parsed.RDNs))
+		for _, rdn := range parsed.RDNs {
+			attrs := make([]string, 0, len(rdn.Attributes))
+			for _, attr := range rdn.Attributes {
+				attrs = append(attrs, strings.ToLower(attr.Type)+"="+strings.ToLower(attr.Value))
+			}
+			parts = append(parts, strings.Join(attrs, "+"))
+		}
+		return strings.Join(parts, ",")
+	}
+	return strings.ToLower(strings.TrimSpace(dn))
+}
+
+// Lookup finds the user in the directory and checks their password by
+// binding as them.
+func (l *LDAP) Lookup(login, password string) (*Entry, error) {
+	// An empty password would make an unauthenticated bind, which servers
+	// accept without checking anything
+	if login == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := l.Dial()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to LDAP: %w", err)
+	}
+	defer conn.Close()
+
+	if l.Config.BindDN != "" {
+		if err := conn.Bind(l.Config.BindDN, l.Config.BindPassword); err != nil {
+			return nil, fmt.Errorf("failed to bind LDAP search account: %w", err)
+		}
+	}
+
+	escaped := ldap.EscapeFilter(login)
+	result, err := conn.Search(ldap.NewSearchRequest(
+		l.Config.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(l.Config.SearchTimeout.Seconds()), false,
+		strings.ReplaceAll(l.Config.UserFilter, "%s", escaped),
+		[]string{l.Config.EmailAttr, l.Config.UsernameAttr, l.Config.GroupAttr},
+		nil,
+	))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("failed to search LDAP: %w", err)
+	}
+	// Ambiguous logins are refused rather than guessed
+	if result == nil || len(result.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	found := result.Entries[0]
+
+	if err := conn.Bind(found.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("failed to bind LDAP user: %w", err)
+	}
+
+	entry := &Entry{
+		DN:       found.DN,
+		Email:    found.GetAttributeValue(l.Config.EmailAttr),
+		Username: found.GetAttributeValue(l.Config.UsernameAttr),
+		Groups:   found.GetAttributeValues(l.Config.GroupAttr),
+	}
+	if entry.Email == "" {
+		return nil, fmt.Errorf("LDAP user %s has no %s attribute", found.DN, l.Config.EmailAttr)
+	}
+	return entry, nil
+}
+
+// Roles maps the entry's groups to Nucleus roles, falling back to the
+// default role.
+func (l *LDAP) Roles(groups []string) []string {
+	var roles []string
+	for _, group := range groups {
+		if role, ok := l.Config.GroupRoles[normalizeDN(group)]; ok && !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+	if len(roles) == 0 {
+		roles = []string{l.Config.DefaultRole}
+	}
+	slices.Sort(roles)
+	return roles
+}
+
+func (l *LDAP) Authenticate(ctx context.Context, login, password string) (*models.User, error) {
+	entry, err := l.Lookup(login, password)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *models.User
+	err = l.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing models.User
+		err := tx.Scopes(models.WithEmail(entry.Email)).First(&existing).Error
+		switch {
+		case err == nil:
+			user = &existing
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if user, err = ProvisionUser(tx, models.SourceLDAP, entry.Username, entry.Email); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		if len(l.Config.GroupRoles) > 0 {
+			return SyncRoles(tx, user, l.Roles(entry.Groups))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
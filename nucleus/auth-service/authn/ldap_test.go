@@ -0,0 +1,186 @@
+package authn
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Talfaza/authentification/models"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubDirectory is an in-process LDAP directory: it answers binds and
+// searches from a fixed set of entries.
+type stubDirectory struct {
+	passwords map[string]string
+	entries   []*ldap.Entry
+	searches  []string
+	down      bool
+}
+
+type stubConn struct {
+	dir   *stubDirectory
+	bound string
+}
+
+func (d *stubDirectory) dial() (Conn, error) {
+	if d.down {
+		return nil, errors.New("connection refused")
+	}
+	return &stubConn{dir: d}, nil
+}
+
+func (c *stubConn) Bind(username, password string) error {
+	if want, ok := c.dir.passwords[username]; !ok || want != password {
+		return ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))
+	}
+	c.bound = username
+	return nil
+}
+
+func (c *stubConn) Search(request *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	if c.bound != "cn=nucleus,ou=services,dc=lab" {
+		return nil, ldap.NewError(ldap.LDAPResultInsufficientAccessRights, errors.New("bind first"))
+	}
+	c.dir.searches = append(c.dir.searches, request.Filter)
+
+	// Only understands the default filter: match mail or uid
+	result := &ldap.SearchResult{}
+	for _, entry := range c.dir.entries {
+		for _, attr := range []string{"mail", "uid"} {
+			if strings.Contains(request.Filter, "("+attr+"="+entry.GetAttributeValue(attr)+")") {
+				result.Entries = append(result.Entries, entry)
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+func (c *stubConn) Close() error { return nil }
+
+func newStubDirectory() *stubDirectory {
+	return &stubDirectory{
+		passwords: map[string]string{
+			"cn=nucleus,ou=services,dc=lab": "service-secret",
+			"uid=alice,ou=people,dc=lab":    "alice-secret",
+			"uid=bob,ou=people,dc=lab":      "bob-secret",
+			"uid=noemail,ou=people,dc=lab":  "noemail-secret",
+		},
+		entries: []*ldap.Entry{
+			ldap.NewEntry("uid=alice,ou=people,dc=lab", map[string][]string{
+				"uid":      {"alice"},
+				"mail":     {"alice@lab.local"},
+				"memberOf": {"CN=Admins,OU=Groups,DC=lab", "cn=engineers,ou=groups,dc=lab"},
+			}),
+			ldap.NewEntry("uid=bob,ou=people,dc=lab", map[string][]string{
+				"uid":  {"bob"},
+				"mail": {"bob@lab.local"},
+			}),
+			ldap.NewEntry("uid=noemail,ou=people,dc=lab", map[string][]string{
+				"uid": {"noemail"},
+			}),
+		},
+	}
+}
+
+func TestLDAPLookup(t *testing.T) {
+	dir := newStubDirectory()
+	groupRoles, err := ParseLDAPGroupRoles("cn=admins,ou=groups,dc=lab=admin; cn=engineers, ou=groups, dc=lab=operator")
+	require.NoError(t, err)
+
+	l, err := NewLDAP(LDAPConfig{
+		BindDN:       "cn=nucleus,ou=services,dc=lab",
+		BindPassword: "service-secret",
+		BaseDN:       "ou=people,dc=lab",
+		GroupRoles:   groupRoles,
+	}, dir.dial, nil)
+	require.NoError(t, err)
+
+	t.Run("by email", func(t *testing.T) {
+		entry, err := l.Lookup("alice@lab.local", "alice-secret")
+		require.NoError(t, err)
+		assert.Equal(t, "uid=alice,ou=people,dc=lab", entry.DN)
+		assert.Equal(t, "alice@lab.local", entry.Email)
+		assert.Equal(t, "alice", entry.Username)
+		assert.Equal(t, []string{"admin", "operator"}, l.Roles(entry.Groups))
+	})
+
+	t.Run("by username", func(t *testing.T) {
+		entry, err := l.Lookup("bob", "bob-secret")
+		require.NoError(t, err)
+		assert.Equal(t, "bob@lab.local", entry.Email)
+		assert.Equal(t, []string{"viewer"}, l.Roles(entry.Groups))
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		_, err := l.Lookup("alice", "wrong")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("empty password", func(t *testing.T) {
+		_, err := l.Lookup("alice", "")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		_, err := l.Lookup("carol", "carol-secret")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("filter injection", func(t *testing.T) {
+		_, err := l.Lookup("*)(uid=*", "alice-secret")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+		assert.Contains(t, dir.searches[len(dir.searches)-1], `\2a\29\28uid=\2a`)
+	})
+
+	t.Run("no email", func(t *testing.T) {
+		_, err := l.Lookup("noemail", "noemail-secret")
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("directory down", func(t *testing.T) {
+		dir.down = true
+		defer func() { dir.down = false }()
+		_, err := l.Lookup("alice", "alice-secret")
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrInvalidCredentials)
+	})
+}
+
+func TestParseLDAPGroupRoles(t *testing.T) {
+	_, err := ParseLDAPGroupRoles("cn=admins,dc=lab")
+	assert.Error(t, err, "missing role")
+	_, err = ParseLDAPGroupRoles("=admin")
+	assert.Error(t, err)
+	_, err = ParseLDAPGroupRoles("cn=admins,dc=lab=root")
+	assert.Error(t, err)
+}
+
+type stubAuthenticator struct{ err error }
+
+func (s stubAuthenticator) Authenticate(ctx context.Context, login, password string) (*models.User, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &models.User{Email: login}, nil
+}
+
+func TestChain(t *testing.T) {
+	down := errors.New("connection refused")
+
+	user, err := Chain{stubAuthenticator{ErrInvalidCredentials}, stubAuthenticator{}}.Authenticate(context.Background(), "alice", "secret")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", user.Email)
+
+	_, err = Chain{stubAuthenticator{ErrInvalidCredentials}, stubAuthenticator{ErrInvalidCredentials}}.Authenticate(context.Background(), "alice", "secret")
+	assert.Equal(t, ErrInvalidCredentials, err)
+
+	_, err = Chain{stubAuthenticator{down}, stubAuthenticator{ErrInvalidCredentials}}.Authenticate(context.Background(), "alice", "secret")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	assert.ErrorIs(t, err, down)
+}
@@ -0,0 +1,39 @@
+package authn
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Talfaza/authentification/config"
+	"github.com/Talfaza/authentification/models"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Local checks the bcrypt password hashes stored with users. Users from a
+// directory or an SSO provider are refused even if they have a password,
+// so removing them there locks them out.
+type Local struct {
+	DB *gorm.DB
+}
+
+// dummyHash is compared against when the email is unknown so the answer
+// takes as long as for a wrong password.
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("nucleus"), config.Default.BcryptCost)
+	return hash
+})
+
+func (l *Local) Authenticate(ctx context.Context, email, password string) (*models.User, error) {
+	var user models.User
+	l.DB.WithContext(ctx).Scopes(models.WithEmail(email)).First(&user)
+
+	hash := []byte(user.Password)
+	if user.ID == 0 {
+		hash = dummyHash()
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil || user.ID == 0 || user.External() {
+		return nil, ErrInvalidCredentials
+	}
+	return &user, nil
+}
@@ -0,0 +1,74 @@
+package authn
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Talfaza/authentification/models"
+	"github.com/Talfaza/authentification/policy"
+	"gorm.io/gorm"
+)
+
+var usernameUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// ProvisionUser creates a user vouched for by an external identity source,
+// models.SourceLDAP or models.SourceSSO, so their email counts as
+// verified. It has no local password and cannot be given one. The username
+// is derived from the given one, adjusted to the username policy and made
+// unique.
+func ProvisionUser(tx *gorm.DB, source, username, email string) (*models.User, error) {
+	base := username
+	if base == "" {
+		base, _, _ = strings.Cut(email, "@")
+	}
+	base = strings.Trim(usernameUnsafe.ReplaceAllString(base, "-"), "-._")
+	if len(base) > 24 {
+		base = base[:24]
+	}
+	for len(base) < 3 {
+		base += "0"
+	}
+
+	username = base
+	for i := 2; ; i++ {
+		var count int64
+		tx.Model(&models.User{}).Where("username = ?", username).Count(&count)
+		if count == 0 && policy.Username(username) == nil {
+			break
+		}
+		if i > 100 {
+			return nil, errors.New("could not pick a username")
+		}
+		username = fmt.Sprintf("%s-%d", base, i)
+	}
+
+	var role models.Role
+	if err := tx.Where("name = ?", models.RoleOperator).First(&role).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user := &models.User{
+		Username:        username,
+		Email:           email,
+		Roles:           []models.Role{role},
+		EmailVerifiedAt: &now,
+		Source:          source,
+	}
+	if err := tx.Create(user).Error; err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// SyncRoles replaces the user's roles with the named ones.
+func SyncRoles(tx *gorm.DB, user *models.User, names []string) error {
+	var roles []models.Role
+	if err := tx.Where("name IN ?", names).Find(&roles).Error; err != nil {
+		return err
+	}
+	return tx.Model(user).Association("Roles").Replace(roles)
+}
@@ -0,0 +1,200 @@
+// Package config loads the settings of auth-service.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Config holds the settings of auth-service.
+type Config struct {
+	Addr            string   `yaml:"addr" env:"LISTEN_ADDR" flag:"addr" usage:"address to listen on"`
+	ShutdownTimeout int      `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" usage:"seconds in-flight requests get to finish on shutdown"`
+	LogLevel        string   `yaml:"log_level" env:"LOG_LEVEL" flag:"log-level" usage:"least severe level logged: debug, info, warn or error"`
+	OTLPEndpoint    string   `yaml:"otlp_endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" flag:"otlp-endpoint" usage:"OTLP/HTTP collector spans are exported to, e.g. http://localhost:4318 (default: no tracing)"`
+	DSN             string   `yaml:"dsn" env:"DSN" flag:"dsn" usage:"database DSN, mysql://, postgres:// or sqlite://" secret:"true"`
+	AutoMigrate     bool     `yaml:"auto_migrate" env:"AUTO_MIGRATE" flag:"auto-migrate" usage:"apply pending database migrations at startup"`
+	BcryptCost      int      `yaml:"bcrypt_cost" env:"BCRYPT_COST" flag:"bcrypt-cost" usage:"bcrypt cost of password hashes"`
+	TrustedProxies  []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES" flag:"trusted-proxies" usage:"comma-separated proxy addresses or CIDR ranges allowed to set X-Forwarded-For"`
+	ProxServiceURL  string   `yaml:"prox_service_url" env:"PROX_SERVICE_URL" flag:"prox-service-url" usage:"prox-service base URL, for deleting users"`
+	LXCServiceURL   string   `yaml:"lxc_service_url" env:"LXC_SERVICE_URL" flag:"lxc-service-url" usage:"lxc-service base URL, for deleting users"`
+	AppURL          string   `yaml:"app_url" env:"APP_URL" flag:"app-url" usage:"frontend base URL, for links in mails and after SSO logins"`
+
+	Require2FA            bool   `yaml:"require_2fa" env:"REQUIRE_2FA" flag:"require-2fa" usage:"make every user enable 2FA to log in"`
+	JWTKeysDir            string `yaml:"jwt_keys_dir" env:"JWT_KEYS_DIR" flag:"jwt-keys-dir" usage:"directory of the PEM signing keys, one is generated if it is empty"`
+	JWTActiveKID          string `yaml:"jwt_active_kid" env:"JWT_ACTIVE_KID" flag:"jwt-active-kid" usage:"kid of the signing key (default: the last in lexical order)"`
+	BreachedPasswordsFile string `yaml:"breached_passwords_file" env:"BREACHED_PASSWORDS_FILE" flag:"breached-passwords-file" usage:"file of breached passwords or their SHA-1 hashes, one per line"`
+
+	MailDriver   string `yaml:"mail_driver" env:"MAIL_DRIVER" flag:"mail-driver" usage:"how mails are delivered: log, smtp or file"`
+	MailFrom     string `yaml:"mail_from" env:"MAIL_FROM" flag:"mail-from" usage:"sender address of every mail"`
+	MailDir      string `yaml:"mail_dir" env:"MAIL_DIR" flag:"mail-dir" usage:"directory the file driver writes .eml files to"`
+	SMTPHost     string `yaml:"smtp_host" env:"SMTP_HOST" flag:"smtp-host" usage:"SMTP server, required by the smtp driver"`
+	SMTPPort     int    `yaml:"smtp_port" env:"SMTP_PORT" flag:"smtp-port" usage:"SMTP server port"`
+	SMTPUsername string `yaml:"smtp_username" env:"SMTP_USERNAME" flag:"smtp-username" usage:"SMTP username (default: no authentication)"`
+	SMTPPassword string `yaml:"smtp_password" env:"SMTP_PASSWORD" flag:"smtp-password" usage:"SMTP password" secret:"true"`
+
+	AuthBackends           []string `yaml:"auth_backends" env:"AUTH_BACKEND" flag:"auth-backend" usage:"authenticators tried in order: local and/or ldap"`
+	LDAPURL                string   `yaml:"ldap_url" env:"LDAP_URL" flag:"ldap-url" usage:"directory URL, ldap:// or ldaps://, required by the ldap backend"`
+	LDAPStartTLS           bool     `yaml:"ldap_starttls" env:"LDAP_STARTTLS" flag:"ldap-starttls" usage:"upgrade ldap:// connections with StartTLS"`
+	LDAPInsecureSkipVerify bool     `yaml:"ldap_insecure_skip_verify" env:"LDAP_INSECURE_SKIP_VERIFY" flag:"ldap-insecure-skip-verify" usage:"accept any directory certificate (lab only)"`
+	LDAPBindDN             string   `yaml:"ldap_bind_dn" env:"LDAP_BIND_DN" flag:"ldap-bind-dn" usage:"DN of the search account (default: search anonymously)"`
+	LDAPBindPassword       string   `yaml:"ldap_bind_password" env:"LDAP_BIND_PASSWORD" flag:"ldap-bind-password" usage:"password of the search account" secret:"true"`
+	LDAPBaseDN             string   `yaml:"ldap_base_dn" env:"LDAP_BASE_DN" flag:"ldap-base-dn" usage:"DN users are searched under, required by the ldap backend"`
+	LDAPUserFilter         string   `yaml:"ldap_user_filter" env:"LDAP_USER_FILTER" flag:"ldap-user-filter" usage:"filter finding a user, every %s is replaced with the login"`
+	LDAPEmailAttr          string   `yaml:"ldap_email_attr" env:"LDAP_EMAIL_ATTR" flag:"ldap-email-attr" usage:"attribute holding the email address"`
+	LDAPUsernameAttr       string   `yaml:"ldap_username_attr" env:"LDAP_USERNAME_ATTR" flag:"ldap-username-attr" usage:"attribute holding the username"`
+	LDAPGroupAttr          string   `yaml:"ldap_group_attr" env:"LDAP_GROUP_ATTR" flag:"ldap-group-attr" usage:"attribute listing the groups of a user"`
+	LDAPGroupRoles         string   `yaml:"ldap_group_roles" env:"LDAP_GROUP_ROLES" flag:"ldap-group-roles" usage:"\"group DN=role\" pairs separated by ;"`
+	LDAPDefaultRole        string   `yaml:"ldap_default_role" env:"LDAP_DEFAULT_ROLE" flag:"ldap-default-role" usage:"role of directory users in no mapped group"`
+
+	OIDCIssuer       string   `yaml:"oidc_issuer" env:"OIDC_ISSUER" flag:"oidc-issuer" usage:"OpenID Connect provider URL (default: SSO disabled)"`
+	OIDCClientID     string   `yaml:"oidc_client_id" env:"OIDC_CLIENT_ID" flag:"oidc-client-id" usage:"OpenID Connect client ID, required with an issuer"`
+	OIDCClientSecret string   `yaml:"oidc_client_secret" env:"OIDC_CLIENT_SECRET" flag:"oidc-client-secret" usage:"OpenID Connect client secret" secret:"true"`
+	OIDCRedirectURL  string   `yaml:"oidc_redirect_url" env:"OIDC_REDIRECT_URL" flag:"oidc-redirect-url" usage:"callback URL registered with the provider"`
+	OIDCScopes       []string `yaml:"oidc_scopes" env:"OIDC_SCOPES" flag:"oidc-scopes" usage:"scopes requested from the provider, openid is always added"`
+	OIDCGroupsClaim  string   `yaml:"oidc_groups_claim" env:"OIDC_GROUPS_CLAIM" flag:"oidc-groups-claim" usage:"ID token claim listing the groups of a user"`
+	OIDCGroupRoles   string   `yaml:"oidc_group_roles" env:"OIDC_GROUP_ROLES" flag:"oidc-group-roles" usage:"\"group=role\" pairs separated by commas"`
+	OIDCDefaultRole  string   `yaml:"oidc_default_role" env:"OIDC_DEFAULT_ROLE" flag:"oidc-default-role" usage:"role of SSO users in no mapped group"`
+	OIDCSuccessURL   string   `yaml:"oidc_success_url" env:"OIDC_SUCCESS_URL" flag:"oidc-success-url" usage:"where the browser goes after an SSO login (default: app_url + /create-server)"`
+
+	PrintConfig bool `yaml:"-"`
+	// Args is what follows the flags on the command line, e.g. migrate up
+	Args []string `yaml:"-"`
+}
+
+// Default is the configuration in use, set by main.
+var Default = Defaults()
+
+// Defaults returns the configuration used when nothing is set.
+func Defaults() *Config {
+	return &Config{
+		Addr:             ":9872",
+		ShutdownTimeout:  30,
+		LogLevel:         "info",
+		AutoMigrate:      true,
+		BcryptCost:       14,
+		ProxServiceURL:   "http://localhost:7790",
+		LXCServiceURL:    "http://localhost:7402",
+		AppURL:           "http://localhost:3000",
+		JWTKeysDir:       ".jwt-keys",
+		MailDriver:       "log",
+		MailFrom:         "Nucleus <no-reply@localhost>",
+		MailDir:          "mail",
+		SMTPPort:         587,
+		AuthBackends:     []string{"local"},
+		LDAPUserFilter:   "(&(objectClass=person)(|(mail=%s)(uid=%s)))",
+		LDAPEmailAttr:    "mail",
+		LDAPUsernameAttr: "uid",
+		LDAPGroupAttr:    "memberOf",
+		LDAPDefaultRole:  "viewer",
+		OIDCRedirectURL:  "http://localhost:9872/auth/oidc/callback",
+		OIDCScopes:       []string{"openid", "email", "profile"},
+		OIDCGroupsClaim:  "groups",
+		OIDCDefaultRole:  "viewer",
+	}
+}
+
+// Validate reports every invalid setting.
+func (c *Config) Validate() error {
+	var errs []error
+	if err := validateAddr("addr", c.Addr); err != nil {
+		errs = append(errs, err)
+	}
+	if c.ShutdownTimeout < 1 {
+		errs = append(errs, fmt.Errorf("shutdown_timeout: %d is not a positive number of seconds", c.ShutdownTimeout))
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		errs = append(errs, fmt.Errorf("log_level: %q is not debug, info, warn or error", c.LogLevel))
+	}
+	if c.OTLPEndpoint != "" {
+		if err := validateURL("otlp_endpoint", c.OTLPEndpoint); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if strings.TrimSpace(c.DSN) == "" {
+		errs = append(errs, errors.New("dsn: required"))
+	}
+	// Below 10 hashes are too cheap to brute-force offline
+	if c.BcryptCost < 10 || c.BcryptCost > bcrypt.MaxCost {
+		errs = append(errs, fmt.Errorf("bcrypt_cost: %d is not between 10 and %d", c.BcryptCost, bcrypt.MaxCost))
+	}
+	if err := validateProxies("trusted_proxies", c.TrustedProxies); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateURL("prox_service_url", c.ProxServiceURL); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateURL("lxc_service_url", c.LXCServiceURL); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateURL("app_url", c.AppURL); err != nil {
+		errs = append(errs, err)
+	}
+	if strings.TrimSpace(c.JWTKeysDir) == "" {
+		errs = append(errs, errors.New("jwt_keys_dir: required"))
+	}
+
+	switch c.MailDriver {
+	case "log", "file":
+	case "smtp":
+		if c.SMTPHost == "" {
+			errs = append(errs, errors.New("smtp_host: required by the smtp mail driver"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("mail_driver: %q is not log, smtp or file", c.MailDriver))
+	}
+	if _, err := mail.ParseAddress(c.MailFrom); err != nil {
+		errs = append(errs, fmt.Errorf("mail_from: %q is not an email address", c.MailFrom))
+	}
+	if c.SMTPPort < 1 || c.SMTPPort > 65535 {
+		errs = append(errs, fmt.Errorf("smtp_port: %d is not a port", c.SMTPPort))
+	}
+
+	if len(c.AuthBackends) == 0 {
+		errs = append(errs, errors.New("auth_backends: required"))
+	}
+	for _, backend := range c.AuthBackends {
+		switch backend {
+		case "local":
+		case "ldap":
+			if err := validateLDAPURL("ldap_url", c.LDAPURL); err != nil {
+				errs = append(errs, err)
+			}
+			if strings.TrimSpace(c.LDAPBaseDN) == "" {
+				errs = append(errs, errors.New("ldap_base_dn: required by the ldap backend"))
+			}
+			if err := validateRole("ldap_default_role", c.LDAPDefaultRole); err != nil {
+				errs = append(errs, err)
+			}
+		default:
+			errs = append(errs, fmt.Errorf("auth_backends: %q is not local or ldap", backend))
+		}
+	}
+
+	if c.OIDCIssuer != "" {
+		if err := validateURL("oidc_issuer", c.OIDCIssuer); err != nil {
+			errs = append(errs, err)
+		}
+		if c.OIDCClientID == "" {
+			errs = append(errs, errors.New("oidc_client_id: required with an issuer"))
+		}
+		if err := validateURL("oidc_redirect_url", c.OIDCRedirectURL); err != nil {
+			errs = append(errs, err)
+		}
+		if err := validateRole("oidc_default_role", c.OIDCDefaultRole); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.OIDCSuccessURL != "" {
+		if err := validateURL("oidc_success_url", c.OIDCSuccessURL); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "addr: \":9000\"\ndsn: from-file\nbcrypt_cost: 11\ntrusted_proxies: [10.0.0.1]\n")
+	t.Setenv("DSN", "from-env")
+	t.Setenv("BCRYPT_COST", "12")
+	t.Setenv("OIDC_SCOPES", "openid email groups")
+
+	cfg, err := Load([]string{"-config", path, "-bcrypt-cost", "13"})
+	require.NoError(t, err)
+
+	assert.Equal(t, ":9000", cfg.Addr)
+	assert.Equal(t, "from-env", cfg.DSN)
+	assert.Equal(t, 13, cfg.BcryptCost)
+	assert.Equal(t, []string{"10.0.0.1"}, cfg.TrustedProxies)
+	// Lists from the environment may be separated by spaces too
+	assert.Equal(t, []string{"openid", "email", "groups"}, cfg.OIDCScopes)
+	// Untouched settings keep their default
+	assert.Equal(t, "http://localhost:7790", cfg.ProxServiceURL)
+}
+
+func TestLoadErrors(t *testing.T) {
+	t.Setenv("DSN", "user:secret@tcp(db)/nucleus")
+
+	_, err := Load([]string{"-config", writeFile(t, "bcrypt_cost: 12\nbcrypt: 12\n")})
+	assert.ErrorContains(t, err, "bcrypt")
+
+	_, err = Load([]string{"-config", filepath.Join(t.TempDir(), "missing.yaml")})
+	assert.Error(t, err)
+
+	t.Setenv("BCRYPT_COST", "fourteen")
+	_, err = Load(nil)
+	assert.ErrorContains(t, err, "BCRYPT_COST")
+
+	// Every invalid setting is reported at once
+	t.Setenv("BCRYPT_COST", "4")
+	_, err = Load([]string{"-addr", "9872", "-trusted-proxies", "gateway", "-lxc-service-url", "localhost:7402",
+		"-mail-driver", "smtp", "-auth-backend", "local,ldap", "-oidc-issuer", "https://sso.example.com"})
+	require.Error(t, err)
+	for _, setting := range []string{"addr", "bcrypt_cost", "trusted_proxies", "lxc_service_url", "smtp_host", "ldap_url", "ldap_base_dn", "oidc_client_id"} {
+		assert.ErrorContains(t, err, setting)
+	}
+}
+
+func TestPrint(t *testing.T) {
+	t.Setenv("DSN", "user:secret@tcp(db)/nucleus")
+	t.Setenv("SMTP_PASSWORD", "smtp-secret")
+	t.Setenv("LDAP_BIND_PASSWORD", "ldap-secret")
+	t.Setenv("OIDC_CLIENT_SECRET", "oidc-secret")
+	cfg, err := Load([]string{"-print-config"})
+	require.NoError(t, err)
+	assert.True(t, cfg.PrintConfig)
+
+	var out strings.Builder
+	require.NoError(t, cfg.Print(&out))
+	for _, setting := range []string{"dsn", "smtp_password", "ldap_bind_password", "oidc_client_secret"} {
+		assert.Contains(t, out.String(), setting+": "+redacted)
+	}
+	assert.Contains(t, out.String(), "mail_driver: log")
+	assert.Contains(t, out.String(), "bcrypt_cost: 14")
+	for _, secret := range []string{"user:secret", "smtp-secret", "ldap-secret", "oidc-secret"} {
+		assert.NotContains(t, out.String(), secret)
+	}
+	// Printing does not touch the configuration itself
+	assert.Equal(t, "user:secret@tcp(db)/nucleus", cfg.DSN)
+}
@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"slices"
+
+	"github.com/Talfaza/authentification/database"
+	"github.com/Talfaza/authentification/middleware"
+	"github.com/Talfaza/authentification/models"
+	"github.com/Talfaza/authentification/problem"
+	"github.com/Talfaza/authentification/tracing"
+	"github.com/Talfaza/authentification/validation"
+	"github.com/gofiber/fiber/v3"
+)
+
+// SetUserRoles replaces the roles of a user. The new roles take effect the
+// next time the user logs in.
+func SetUserRoles(c fiber.Ctx) error {
+	var body struct {
+		Roles []string `json:"roles" validate:"dive,role"`
+	}
+	if err := c.Bind().Body(&body); err != nil {
+		return problem.New(fiber.StatusBadRequest, "Cannot parse JSON")
+	}
+	if errs := validation.Struct(&body); errs != nil {
+		return problem.Invalid(errs...)
+	}
+
+	var user models.User
+	if err := database.DB.WithContext(tracing.Context(c)).First(&user, c.Params("id")).Error; err != nil {
+		return problem.New(fiber.StatusNotFound, "User not found")
+	}
+
+	slices.Sort(body.Roles)
+	body.Roles = slices.Compact(body.Roles)
+
+	var roles []models.Role
+	if len(body.Roles) > 0 {
+		if err := database.DB.WithContext(tracing.Context(c)).Where("name IN ?", body.Roles).Find(&roles).Error; err != nil {
+			return problem.New(fiber.StatusInternalServerError, "Failed to load roles")
+		}
+	}
+	if len(roles) != len(body.Roles) {
+		return problem.New(fiber.StatusBadRequest, "Unknown role")
+	}
+
+	// Admins cannot demote themselves and lock everyone out
+	adminID, _ := middleware.UserID(c)
+	if adminID == user.ID && !slices.Contains(body.Roles, models.RoleAdmin) {
+		return problem.New(fiber.StatusConflict, "Cannot remove your own admin role")
+	}
+
+	if err := database.DB.WithContext(tracing.Context(c)).Model(&user).Association("Roles").Replace(roles); err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to update roles")
+	}
+	user.Roles = roles
+
+	return c.JSON(fiber.Map{
+		"user": fiber.Map{
+			"id":       user.ID,
+			"username": user.Username,
+			"email":    user.Email,
+			"roles":    user.RoleNames(),
+		},
+	})
+}
+
+// RequireTwoFactor makes 2FA mandatory for a user. A user who has not set
+// it up is sent through enrollment at their next login.
+func RequireTwoFactor(c fiber.Ctx) error {
+	var user models.User
+	if err := database.DB.WithContext(tracing.Context(c)).First(&user, c.Params("id")).Error; err != nil {
+		return problem.New(fiber.StatusNotFound, "User not found")
+	}
+
+	if err := database.DB.WithContext(tracing.Context(c)).Model(&user).Update("two_factor_required", true).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to update user")
+	}
+
+	return c.JSON(fiber.Map{"message": "Two-factor authentication required"})
+}
+
+// ResetTwoFactor removes a user's 2FA, e.g. after they lost both their
+// device and their recovery codes, and lifts the requirement for it.
+func ResetTwoFactor(c fiber.Ctx) error {
+	var user models.User
+	if err := database.DB.WithContext(tracing.Context(c)).First(&user, c.Params("id")).Error; err != nil {
+		return problem.New(fiber.StatusNotFound, "User not found")
+	}
+
+	if err := resetTwoFactor(tracing.Context(c), user.ID); err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to reset two-factor authentication")
+	}
+	if err := database.DB.WithContext(tracing.Context(c)).Model(&user).Update("two_factor_required", false).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to update user")
+	}
+
+	return c.JSON(fiber.Map{"message": "Two-factor authentication reset"})
+}
@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Talfaza/authentification/database"
+	"github.com/Talfaza/authentification/middleware"
+	"github.com/Talfaza/authentification/models"
+	"github.com/Talfaza/authentification/problem"
+	"github.com/Talfaza/authentification/tracing"
+	"github.com/Talfaza/authentification/validation"
+	"github.com/gofiber/fiber/v3"
+)
+
+const (
+	defaultAPITokenDays = 30
+)
+
+// CreateAPIToken creates a personal access token. The token itself is only
+// returned in this response.
+func CreateAPIToken(c fiber.Ctx) error {
+	var body struct {
+		Name          string   `json:"name" validate:"required,max=64"`
+		Scopes        []string `json:"scopes" validate:"min=1,dive,scope"`
+		ExpiresInDays int      `json:"expires_in_days" validate:"min=1,max=365"`
+	}
+	if err := c.Bind().Body(&body); err != nil {
+		return problem.New(fiber.StatusBadRequest, "Cannot parse JSON")
+	}
+
+	body.Name = strings.TrimSpace(body.Name)
+	if body.ExpiresInDays == 0 {
+		body.ExpiresInDays = defaultAPITokenDays
+	}
+	if errs := validation.Struct(&body); errs != nil {
+		return problem.Invalid(errs...)
+	}
+
+	slices.Sort(body.Scopes)
+	body.Scopes = slices.Compact(body.Scopes)
+
+	userID, err := middleware.UserID(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	token := models.APITokenPrefix + randomToken(32)
+	apiToken := models.APIToken{
+		UserID:    userID,
+		Name:      body.Name,
+		Prefix:    token[:len(models.APITokenPrefix)+8],
+		TokenHash: hashToken(token),
+		Scopes:    body.Scopes,
+		ExpiresAt: time.Now().AddDate(0, 0, body.ExpiresInDays),
+	}
+	if err := database.DB.WithContext(tracing.Context(c)).Create(&apiToken).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to create token")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"token": token, "api_token": apiToken})
+}
+
+// ListAPITokens lists the caller's personal access tokens, revoked ones
+// included.
+func ListAPITokens(c fiber.Ctx) error {
+	userID, err := middleware.UserID(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	var tokens []models.APIToken
+	if err := database.DB.WithContext(tracing.Context(c)).Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to retrieve tokens")
+	}
+	return c.JSON(tokens)
+}
+
+// RevokeAPIToken revokes one of the caller's personal access tokens.
+// Services cache introspection results briefly, so a revoked token may
+// keep working there for up to 30 seconds.
+func RevokeAPIToken(c fiber.Ctx) error {
+	userID, err := middleware.UserID(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	result := database.DB.WithContext(tracing.Context(c)).Model(&models.APIToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", c.Params("id"), userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to revoke token")
+	}
+	if result.RowsAffected == 0 {
+		return problem.New(fiber.StatusNotFound, "Token not found")
+	}
+
+	return c.JSON(fiber.Map{"message": "Token revoked"})
+}
+
+// IntrospectAPIToken lets the other services resolve a personal access
+// token into claims, in the spirit of RFC 7662. Unknown, expired and
+// revoked tokens are reported as inactive.
+func IntrospectAPIToken(c fiber.Ctx) error {
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := c.Bind().Body(&body); err != nil {
+		return problem.New(fiber.StatusBadRequest, "Cannot parse JSON")
+	}
+
+	claims, err := middleware.APITokenClaims(tracing.Context(c), body.Token)
+	if err != nil {
+		return c.JSON(fiber.Map{"active": false})
+	}
+
+	return c.JSON(struct {
+		Active bool `json:"active"`
+		*middleware.Claims
+	}{true, claims})
+}
@@ -1,32 +1,101 @@
 package controller
 
 import (
+	"time"
+
+	"github.com/Talfaza/authentification/authn"
+	"github.com/Talfaza/authentification/config"
 	"github.com/Talfaza/authentification/database"
+	"github.com/Talfaza/authentification/keys"
+	"github.com/Talfaza/authentification/logging"
+	"github.com/Talfaza/authentification/metrics"
+	"github.com/Talfaza/authentification/middleware"
 	"github.com/Talfaza/authentification/models"
+	"github.com/Talfaza/authentification/policy"
+	"github.com/Talfaza/authentification/problem"
+	"github.com/Talfaza/authentification/tracing"
+	"github.com/Talfaza/authentification/validation"
 	"github.com/gofiber/fiber/v3"
 	"golang.org/x/crypto/bcrypt"
-	"github.com/golang-jwt/jwt/v5"
-	"time"
-	"os"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func Register(c fiber.Ctx) error {
-	data := new(map[string]string)
+	var body struct {
+		Username string `json:"username" validate:"required"`
+		Email    string `json:"email" validate:"required"`
+		Password string `json:"password" validate:"required"`
+	}
 
-	if err := c.Bind().Body(data); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+	if err := c.Bind().Body(&body); err != nil {
+		return problem.New(fiber.StatusBadRequest, "Cannot parse JSON")
+	}
+	if errs := validation.Struct(&body); errs != nil {
+		return problem.Invalid(errs...)
+	}
+
+	email, err := policy.Email(body.Email)
+	if err != nil {
+		return problem.Invalid(problem.FieldError{Field: "email", Message: err.Error()})
+	}
+	if err := policy.Username(body.Username); err != nil {
+		return problem.Invalid(problem.FieldError{Field: "username", Message: err.Error()})
+	}
+	if err := policy.Password(body.Password, body.Username, email); err != nil {
+		return problem.Invalid(problem.FieldError{Field: "password", Message: err.Error()})
+	}
+
+	password, err := bcrypt.GenerateFromPassword([]byte(body.Password), config.Default.BcryptCost)
+	if err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to hash password")
 	}
 
-	password, _ := bcrypt.GenerateFromPassword([]byte((*data)["password"]), 14)
+	var existing int64
+	database.DB.WithContext(tracing.Context(c)).Model(&models.User{}).Scopes(models.WithEmail(email)).Count(&existing)
+	if existing > 0 {
+		return problem.New(fiber.StatusConflict, "User already exists")
+	}
 
 	user := models.User{
-		Username: (*data)["username"],
-		Email:    (*data)["email"],
+		Username: body.Username,
+		Email:    email,
 		Password: string(password),
 	}
 
-	if err := database.DB.Create(&user).Error; err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "User already exists"})
+	// The first account administers the instance, everyone after it
+	// starts as an operator. Locking the admin role makes concurrent
+	// registrations count the users one after the other.
+	err = database.DB.WithContext(tracing.Context(c)).Transaction(func(tx *gorm.DB) error {
+		var admin models.Role
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", models.RoleAdmin).First(&admin).Error; err != nil {
+			return problem.New(fiber.StatusInternalServerError, "Role not found")
+		}
+		var count int64
+		if err := tx.Model(&models.User{}).Count(&count).Error; err != nil {
+			return problem.New(fiber.StatusInternalServerError, "Failed to count users")
+		}
+
+		user.Roles = []models.Role{admin}
+		if count > 0 {
+			var operator models.Role
+			if err := tx.Where("name = ?", models.RoleOperator).First(&operator).Error; err != nil {
+				return problem.New(fiber.StatusInternalServerError, "Role not found")
+			}
+			user.Roles = []models.Role{operator}
+		}
+
+		if err := tx.Create(&user).Error; err != nil {
+			return problem.New(fiber.StatusConflict, "User already exists")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := sendVerificationMail(tracing.Context(c), user); err != nil {
+		logging.Logger(c).Error("Failed to create verification token", "user_id", user.ID, "error", err)
 	}
 
 	return c.JSON(user)
@@ -36,30 +105,77 @@ func Login(c fiber.Ctx) error {
 	data := new(map[string]string)
 
 	if err := c.Bind().Body(data); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		return problem.New(fiber.StatusBadRequest, "Cannot parse JSON")
 	}
 
-	var user models.User
-	database.DB.Where("email = ?", (*data)["email"]).First(&user)
+	email := (*data)["email"]
+	if wait := loginLocked(c.IP(), email); wait > 0 {
+		metrics.Logins.WithLabelValues(metrics.LoginPassword, metrics.LoginLocked).Inc()
+		return tooManyAttempts(c, wait)
+	}
 
-	if user.ID == 0 {
-		return c.Status(404).JSON(fiber.Map{"error": "User not found"})
+	authenticated, err := authn.Default.Authenticate(tracing.Context(c), email, (*data)["password"])
+	if err != nil {
+		if err != authn.ErrInvalidCredentials {
+			logging.Logger(c).Error("Login failed", "error", err)
+		}
+		loginFailed(tracing.Context(c), c.IP(), email)
+		metrics.Logins.WithLabelValues(metrics.LoginPassword, metrics.LoginFailure).Inc()
+		return problem.New(fiber.StatusUnauthorized, errInvalidCredentials).WithCode(problem.CodeInvalidCredentials)
+	}
+	user := *authenticated
+	if perr := loginBlocked(user); perr != nil {
+		metrics.Logins.WithLabelValues(metrics.LoginPassword, metrics.LoginBlocked).Inc()
+		return perr
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte((*data)["password"])); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Invalid password"})
+	// With 2FA the session is only started once a code is verified
+	if user.TOTPEnabled {
+		metrics.Logins.WithLabelValues(metrics.LoginPassword, metrics.LoginMFARequired).Inc()
+		return startPreAuth(c, user, middleware.PreAuthAudience)
+	}
+	if requires2FA(user) {
+		metrics.Logins.WithLabelValues(metrics.LoginPassword, metrics.LoginMFARequired).Inc()
+		return startPreAuth(c, user, middleware.EnrollAudience)
 	}
 
-	claims := jwt.MapClaims{
-		"sub": user.ID,
-		"exp": time.Now().Add(time.Hour * 24).Unix(),
+	loginSucceeded(tracing.Context(c), email, user.ID)
+	if err := startSession(c, user.ID); err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to start session")
 	}
+	metrics.Logins.WithLabelValues(metrics.LoginPassword, metrics.LoginSuccess).Inc()
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	secret := os.Getenv("JWT_SECRET")
-	t, err := token.SignedString([]byte(secret))
+	return c.JSON(fiber.Map{"message": "Login successful"})
+}
+
+// startSession signs a token for the user and sets it as the jwt cookie.
+// The user's roles and memberships are reloaded so the token reflects
+// them, which also makes it usable to refresh a session after they change.
+func startSession(c fiber.Ctx, userID uint) error {
+	var user models.User
+	if err := database.DB.WithContext(tracing.Context(c)).Preload("Roles").Preload("Memberships").First(&user, userID).Error; err != nil {
+		return err
+	}
+
+	claims := middleware.NewClaims(user, time.Hour*24)
+	t, err := keys.Default.Sign(claims)
 	if err != nil {
-		return c.SendStatus(fiber.StatusInternalServerError)
+		return err
+	}
+
+	userAgent := c.Get(fiber.HeaderUserAgent)
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	session := models.Session{
+		UserID:    user.ID,
+		JTI:       claims.ID,
+		IP:        c.IP(),
+		UserAgent: userAgent,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}
+	if err := database.DB.WithContext(tracing.Context(c)).Create(&session).Error; err != nil {
+		return err
 	}
 
 	c.Cookie(&fiber.Cookie{
@@ -72,15 +188,14 @@ func Login(c fiber.Ctx) error {
 		Domain:   "",
 		Path:     "/",
 	})
-
-	return c.JSON(fiber.Map{"message": "Login successful"})
+	return nil
 }
 
 func MailCheck(c fiber.Ctx) error {
 	email := c.Query("email")
 	var user models.User
 
-	database.DB.Where("email = ?", email).First(&user)
+	database.DB.WithContext(tracing.Context(c)).Scopes(models.WithEmail(email)).First(&user)
 	if user.ID != 0 {
 		return c.JSON(fiber.Map{"exists": true})
 	}
@@ -88,6 +203,11 @@ func MailCheck(c fiber.Ctx) error {
 }
 
 func Logout(c fiber.Ctx) error {
+	// Revoke the session so a copy of the cookie stops working too
+	if claims, err := middleware.ParseToken(c.Cookies("jwt"), middleware.SessionAudience); err == nil {
+		database.DB.WithContext(tracing.Context(c)).Model(&models.Session{}).Where("jti = ? AND revoked_at IS NULL", claims.ID).Update("revoked_at", time.Now())
+	}
+
 	c.Cookie(&fiber.Cookie{
 		Name:     "jwt",
 		Value:    "",
@@ -99,39 +219,46 @@ func Logout(c fiber.Ctx) error {
 
 func Verify(c fiber.Ctx) error {
 	cookie := c.Cookies("jwt")
-	
+
 	// Debug: log cookie presence
 	if cookie == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "No JWT cookie found"})
+		return problem.New(fiber.StatusUnauthorized, "No JWT cookie found")
 	}
 
-	token, err := jwt.Parse(cookie, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
-
-	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token", "details": err.Error()})
+	claims, err := middleware.ParseToken(cookie, middleware.SessionAudience)
+	if err == nil && middleware.SessionRevoked(tracing.Context(c), claims.ID) {
+		err = middleware.ErrSessionRevoked
 	}
-	
-	if !token.Valid {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token not valid"})
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, "Invalid token: "+err.Error()).WithCode(problem.CodeInvalidToken)
 	}
 
-	claims := token.Claims.(jwt.MapClaims)
-	userID := claims["sub"]
+	userID, err := claims.UserID()
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, "Invalid token: "+err.Error()).WithCode(problem.CodeInvalidToken)
+	}
 
 	var user models.User
-	database.DB.Where("id = ?", userID).First(&user)
+	database.DB.WithContext(tracing.Context(c)).Preload("Roles").Where("id = ?", userID).First(&user)
 
 	if user.ID == 0 {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+		return problem.New(fiber.StatusUnauthorized, "User not found").WithCode(problem.CodeInvalidToken)
 	}
 
 	return c.JSON(fiber.Map{
 		"user": fiber.Map{
-			"id":       user.ID,
-			"username": user.Username,
-			"email":    user.Email,
+			"id":             user.ID,
+			"username":       user.Username,
+			"email":          user.Email,
+			"email_verified": user.EmailVerifiedAt != nil,
+			"roles":          user.RoleNames(),
 		},
 	})
 }
+
+// JWKS publishes the public signing keys so other services can verify
+// tokens without holding any secret.
+func JWKS(c fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(keys.Default.JWKS())
+}
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/Talfaza/authentification/database"
+	"github.com/Talfaza/authentification/middleware"
+	"github.com/Talfaza/authentification/models"
+	"github.com/Talfaza/authentification/problem"
+	"github.com/Talfaza/authentification/tracing"
+	"github.com/Talfaza/authentification/validation"
+	"github.com/gofiber/fiber/v3"
+)
+
+// VerifyEmail marks the address of the token's user as verified.
+func VerifyEmail(c fiber.Ctx) error {
+	var body struct {
+		Token string `json:"token" validate:"required"`
+	}
+	if err := c.Bind().Body(&body); err != nil {
+		return problem.New(fiber.StatusBadRequest, "Cannot parse JSON")
+	}
+	if errs := validation.Struct(&body); errs != nil {
+		return problem.Invalid(errs...)
+	}
+
+	userToken, err := consumeUserToken(tracing.Context(c), body.Token, models.PurposeVerifyEmail)
+	if err != nil {
+		return problem.New(fiber.StatusBadRequest, "Invalid or expired token").WithCode(problem.CodeInvalidToken)
+	}
+
+	if err := database.DB.WithContext(tracing.Context(c)).Model(&models.User{}).Where("id = ?", userToken.UserID).
+		Update("email_verified_at", time.Now()).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to verify email")
+	}
+
+	return c.JSON(fiber.Map{"message": "Email verified"})
+}
+
+// ResendVerification mails the caller a new verification link.
+func ResendVerification(c fiber.Ctx) error {
+	userID, err := middleware.UserID(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	var user models.User
+	if err := database.DB.WithContext(tracing.Context(c)).First(&user, userID).Error; err != nil {
+		return problem.New(fiber.StatusNotFound, "User not found")
+	}
+	if user.EmailVerifiedAt != nil {
+		return problem.New(fiber.StatusConflict, "Email already verified")
+	}
+
+	if err := sendVerificationMail(tracing.Context(c), user); err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to send verification mail")
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"message": "Verification mail sent"})
+}
@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Talfaza/authentification/database"
+	"github.com/Talfaza/authentification/models"
+	"github.com/Talfaza/authentification/problem"
+	"github.com/Talfaza/authentification/throttle"
+	"github.com/Talfaza/authentification/tracing"
+	"github.com/gofiber/fiber/v3"
+)
+
+var (
+	// ipThrottle allows more failures than accountThrottle since an office
+	// or VPN can put many users behind one address.
+	ipThrottle      = throttle.New(20, 15*time.Minute, time.Minute, time.Hour)
+	accountThrottle = throttle.New(5, 15*time.Minute, time.Minute, time.Hour)
+)
+
+// errInvalidCredentials is the only answer to a failed login, whether the
+// email or the password is wrong.
+const errInvalidCredentials = "Invalid email or password"
+
+func accountKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// loginLocked returns how long the client has to wait before trying to
+// log in to email again, or 0.
+func loginLocked(ip, email string) time.Duration {
+	return max(ipThrottle.Locked(ip), accountThrottle.Locked(accountKey(email)))
+}
+
+// loginFailed counts a failed login from ip to email and records any
+// lockout it causes.
+func loginFailed(ctx context.Context, ip, email string) {
+	if lockedFor, failures := ipThrottle.Fail(ip); lockedFor > 0 {
+		recordLockout(ctx, models.LockoutIP, ip, email, failures, lockedFor)
+	}
+	if lockedFor, failures := accountThrottle.Fail(accountKey(email)); lockedFor > 0 {
+		recordLockout(ctx, models.LockoutAccount, ip, email, failures, lockedFor)
+	}
+}
+
+// loginSucceeded clears the failures of the login and records the time
+// of the user's last login. Failures of the IP are kept, so an attacker
+// owning one account cannot use it to reset them.
+func loginSucceeded(ctx context.Context, login string, userID uint) {
+	accountThrottle.Reset(accountKey(login))
+	database.DB.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).UpdateColumn("last_login_at", time.Now())
+}
+
+func recordLockout(ctx context.Context, scope, ip, email string, failures int, lockedFor time.Duration) {
+	event := models.LockoutEvent{
+		Scope:       scope,
+		IP:          ip,
+		Email:       accountKey(email),
+		Failures:    failures,
+		LockedUntil: time.Now().Add(lockedFor),
+	}
+	if err := database.DB.WithContext(ctx).Create(&event).Error; err != nil {
+		slog.Error("Failed to record lockout", "scope", scope, "ip", ip, "error", err)
+	}
+}
+
+// tooManyAttempts answers a request from a locked out client.
+func tooManyAttempts(c fiber.Ctx, wait time.Duration) error {
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(wait.Seconds())+1))
+	return problem.New(fiber.StatusTooManyRequests, "Too many failed attempts, try again later")
+}
+
+// ListLockouts returns the latest lockouts, optionally for one email.
+func ListLockouts(c fiber.Ctx) error {
+	query := database.DB.WithContext(tracing.Context(c)).Order("id DESC").Limit(100)
+	if email := c.Query("email"); email != "" {
+		query = query.Where("email = ?", accountKey(email))
+	}
+
+	var events []models.LockoutEvent
+	if err := query.Find(&events).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to load lockouts")
+	}
+
+	return c.JSON(events)
+}
+
+// UnlockUser lifts a lockout of a user's account before it expires.
+func UnlockUser(c fiber.Ctx) error {
+	var user models.User
+	if err := database.DB.WithContext(tracing.Context(c)).First(&user, c.Params("id")).Error; err != nil {
+		return problem.New(fiber.StatusNotFound, "User not found")
+	}
+
+	accountThrottle.Reset(accountKey(user.Email))
+	return c.JSON(fiber.Map{"message": "Account unlocked"})
+}
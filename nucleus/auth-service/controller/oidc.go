@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Talfaza/authentification/authn"
+	"github.com/Talfaza/authentification/database"
+	"github.com/Talfaza/authentification/metrics"
+	"github.com/Talfaza/authentification/models"
+	"github.com/Talfaza/authentification/problem"
+	"github.com/Talfaza/authentification/sso"
+	"github.com/Talfaza/authentification/tracing"
+	"github.com/gofiber/fiber/v3"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+// oidcFlowTTL is how long the user has to log in at the provider.
+const oidcFlowTTL = 10 * time.Minute
+
+// OIDCConfig tells the frontend whether to offer SSO.
+func OIDCConfig(c fiber.Ctx) error {
+	return c.JSON(fiber.Map{"enabled": sso.Default != nil})
+}
+
+// OIDCLogin starts an SSO login by sending the browser to the provider.
+// The state, nonce and PKCE verifier are kept in a short-lived cookie
+// until the provider redirects back.
+func OIDCLogin(c fiber.Ctx) error {
+	if sso.Default == nil {
+		return problem.New(fiber.StatusNotFound, "SSO is not configured")
+	}
+
+	state, nonce, verifier := randomToken(16), randomToken(16), oauth2.GenerateVerifier()
+	c.Cookie(&fiber.Cookie{
+		Name:     "oidc",
+		Value:    state + "." + nonce + "." + verifier,
+		Expires:  time.Now().Add(oidcFlowTTL),
+		HTTPOnly: true,
+		Secure:   false, // Set to true in production with HTTPS
+		SameSite: "Lax",
+		Path:     "/auth/oidc",
+	})
+
+	return c.Redirect().To(sso.Default.AuthCodeURL(state, nonce, verifier))
+}
+
+// OIDCCallback finishes an SSO login: it redeems the code, finds or
+// provisions the user and starts their session.
+func OIDCCallback(c fiber.Ctx) error {
+	if sso.Default == nil {
+		return problem.New(fiber.StatusNotFound, "SSO is not configured")
+	}
+	if e := c.Query("error"); e != "" {
+		metrics.Logins.WithLabelValues(metrics.LoginSSO, metrics.LoginFailure).Inc()
+		return problem.New(fiber.StatusUnauthorized, "SSO login failed: "+e)
+	}
+
+	flow := strings.Split(c.Cookies("oidc"), ".")
+	c.Cookie(&fiber.Cookie{
+		Name:     "oidc",
+		Value:    "",
+		Expires:  time.Now().Add(-time.Hour),
+		HTTPOnly: true,
+		Path:     "/auth/oidc",
+	})
+	if len(flow) != 3 || subtle.ConstantTimeCompare([]byte(flow[0]), []byte(c.Query("state"))) != 1 {
+		return problem.New(fiber.StatusUnauthorized, "SSO login expired, start again").WithCode(problem.CodeLoginExpired)
+	}
+
+	identity, err := sso.Default.Exchange(c.Context(), c.Query("code"), flow[1], flow[2])
+	if err != nil {
+		metrics.Logins.WithLabelValues(metrics.LoginSSO, metrics.LoginFailure).Inc()
+		return problem.New(fiber.StatusUnauthorized, "SSO login failed: "+err.Error())
+	}
+
+	user, err := ssoUser(tracing.Context(c), identity)
+	if err != nil {
+		metrics.Logins.WithLabelValues(metrics.LoginSSO, metrics.LoginFailure).Inc()
+		return problem.New(fiber.StatusForbidden, err.Error())
+	}
+
+	// The provider handles MFA and passwords, so neither local 2FA nor a
+	// pending password reset is asked for
+	if user.DisabledAt != nil {
+		metrics.Logins.WithLabelValues(metrics.LoginSSO, metrics.LoginBlocked).Inc()
+		return problem.New(fiber.StatusForbidden, "Account disabled").WithCode(problem.CodeAccountDisabled)
+	}
+	loginSucceeded(tracing.Context(c), user.Email, user.ID)
+	if err := startSession(c, user.ID); err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to start session")
+	}
+	metrics.Logins.WithLabelValues(metrics.LoginSSO, metrics.LoginSuccess).Inc()
+
+	return c.Redirect().To(sso.SuccessURL)
+}
+
+// ssoUser returns the user linked to identity. An unknown identity is
+// linked to the account with the same email if the provider verified the
+// address, otherwise a new user is provisioned.
+func ssoUser(ctx context.Context, identity *sso.Identity) (*models.User, error) {
+	var user models.User
+	err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var link models.UserIdentity
+		err := tx.Where("issuer = ? AND subject = ?", identity.Issuer, identity.Subject).First(&link).Error
+		switch {
+		case err == nil:
+			if err := tx.First(&user, link.UserID).Error; err != nil {
+				return err
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if identity.Email == "" || !identity.EmailVerified {
+				return errors.New("Your identity provider did not share a verified email address")
+			}
+			if err := tx.Scopes(models.WithEmail(identity.Email)).First(&user).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+				provisioned, err := authn.ProvisionUser(tx, models.SourceSSO, identity.Username, identity.Email)
+				if err != nil {
+					return err
+				}
+				user = *provisioned
+			} else if err != nil {
+				return err
+			}
+			link = models.UserIdentity{UserID: user.ID, Issuer: identity.Issuer, Subject: identity.Subject, Email: identity.Email}
+			if err := tx.Create(&link).Error; err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		if identity.EmailVerified && user.EmailVerifiedAt == nil && strings.EqualFold(user.Email, identity.Email) {
+			if err := tx.Model(&user).Update("email_verified_at", time.Now()).Error; err != nil {
+				return err
+			}
+		}
+		if sso.Default.SyncsRoles() {
+			return authn.SyncRoles(tx, &user, sso.Default.Roles(identity.Groups))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
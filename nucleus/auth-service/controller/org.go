@@ -0,0 +1,315 @@
+package controller
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Talfaza/authentification/database"
+	"github.com/Talfaza/authentification/mailer"
+	"github.com/Talfaza/authentification/middleware"
+	"github.com/Talfaza/authentification/models"
+	"github.com/Talfaza/authentification/problem"
+	"github.com/Talfaza/authentification/tracing"
+	"github.com/Talfaza/authentification/validation"
+	"github.com/gofiber/fiber/v3"
+)
+
+// invitationTTL is how long an invitation can be accepted.
+const invitationTTL = 7 * 24 * time.Hour
+
+// membershipOf loads the caller's membership in the organization from the
+// :id route parameter and checks it grants at least perm.
+func membershipOf(c fiber.Ctx, perm string) (*models.Membership, *problem.Error) {
+	userID, err := middleware.UserID(c)
+	if err != nil {
+		return nil, problem.New(fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	var membership models.Membership
+	if err := database.DB.WithContext(tracing.Context(c)).Preload("Organization").
+		Where("organization_id = ? AND user_id = ?", c.Params("id"), userID).
+		First(&membership).Error; err != nil {
+		return nil, problem.New(fiber.StatusNotFound, "Organization not found")
+	}
+
+	if !models.PermissionAtLeast(membership.Permission, perm) {
+		return nil, problem.New(fiber.StatusForbidden, "Forbidden")
+	}
+	return &membership, nil
+}
+
+// CreateOrganization creates an organization with the caller as its admin.
+func CreateOrganization(c fiber.Ctx) error {
+	var body struct {
+		Name string `json:"name" validate:"required,max=64"`
+	}
+	if err := c.Bind().Body(&body); err != nil {
+		return problem.New(fiber.StatusBadRequest, "Cannot parse JSON")
+	}
+	body.Name = strings.TrimSpace(body.Name)
+	if errs := validation.Struct(&body); errs != nil {
+		return problem.Invalid(errs...)
+	}
+
+	userID, err := middleware.UserID(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	org := models.Organization{Name: body.Name}
+	if err := database.DB.WithContext(tracing.Context(c)).Create(&org).Error; err != nil {
+		return problem.New(fiber.StatusConflict, "Organization already exists")
+	}
+
+	membership := models.Membership{OrganizationID: org.ID, UserID: userID, Permission: models.PermissionAdmin}
+	if err := database.DB.WithContext(tracing.Context(c)).Create(&membership).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to create membership")
+	}
+
+	// Refresh the session so the new organization is in the token
+	if err := startSession(c, userID); err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to start session")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(org)
+}
+
+// ListOrganizations lists the caller's organizations and their permission
+// in each.
+func ListOrganizations(c fiber.Ctx) error {
+	userID, err := middleware.UserID(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	var memberships []models.Membership
+	if err := database.DB.WithContext(tracing.Context(c)).Preload("Organization").Where("user_id = ?", userID).Find(&memberships).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to retrieve organizations")
+	}
+	return c.JSON(memberships)
+}
+
+// ListMembers lists the members of an organization.
+func ListMembers(c fiber.Ctx) error {
+	if _, perr := membershipOf(c, models.PermissionRead); perr != nil {
+		return perr
+	}
+
+	var memberships []models.Membership
+	if err := database.DB.WithContext(tracing.Context(c)).Preload("User").Where("organization_id = ?", c.Params("id")).Find(&memberships).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to retrieve members")
+	}
+
+	members := make([]fiber.Map, 0, len(memberships))
+	for _, m := range memberships {
+		members = append(members, fiber.Map{
+			"user_id":    m.UserID,
+			"username":   m.User.Username,
+			"email":      m.User.Email,
+			"permission": m.Permission,
+		})
+	}
+	return c.JSON(members)
+}
+
+// UpdateMember changes a member's permission.
+func UpdateMember(c fiber.Ctx) error {
+	caller, perr := membershipOf(c, models.PermissionAdmin)
+	if perr != nil {
+		return perr
+	}
+
+	var body struct {
+		Permission string `json:"permission" validate:"required,permission"`
+	}
+	if err := c.Bind().Body(&body); err != nil {
+		return problem.New(fiber.StatusBadRequest, "Cannot parse JSON")
+	}
+	if errs := validation.Struct(&body); errs != nil {
+		return problem.Invalid(errs...)
+	}
+
+	var member models.Membership
+	if err := database.DB.WithContext(tracing.Context(c)).Where("organization_id = ? AND user_id = ?", c.Params("id"), c.Params("userID")).First(&member).Error; err != nil {
+		return problem.New(fiber.StatusNotFound, "Member not found")
+	}
+
+	if member.Permission == models.PermissionAdmin && body.Permission != models.PermissionAdmin && lastAdmin(tracing.Context(c), member) {
+		return problem.New(fiber.StatusConflict, "An organization needs at least one admin")
+	}
+
+	downgraded := !models.PermissionAtLeast(body.Permission, member.Permission)
+	member.Permission = body.Permission
+	if err := database.DB.WithContext(tracing.Context(c)).Save(&member).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to update member")
+	}
+	if downgraded {
+		if perr := endMemberSessions(c, caller.UserID, member.UserID); perr != nil {
+			return perr
+		}
+	}
+	return c.JSON(member)
+}
+
+// RemoveMember removes a member from an organization. Members may always
+// remove themselves; removing others takes the admin permission.
+func RemoveMember(c fiber.Ctx) error {
+	userID, err := middleware.UserID(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	perm := models.PermissionAdmin
+	if c.Params("userID") == strconv.FormatUint(uint64(userID), 10) {
+		perm = models.PermissionRead
+	}
+	if _, perr := membershipOf(c, perm); perr != nil {
+		return perr
+	}
+
+	var member models.Membership
+	if err := database.DB.WithContext(tracing.Context(c)).Where("organization_id = ? AND user_id = ?", c.Params("id"), c.Params("userID")).First(&member).Error; err != nil {
+		return problem.New(fiber.StatusNotFound, "Member not found")
+	}
+
+	if member.Permission == models.PermissionAdmin && lastAdmin(tracing.Context(c), member) {
+		return problem.New(fiber.StatusConflict, "An organization needs at least one admin")
+	}
+
+	if err := database.DB.WithContext(tracing.Context(c)).Unscoped().Delete(&member).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to remove member")
+	}
+
+	if perr := endMemberSessions(c, userID, member.UserID); perr != nil {
+		return perr
+	}
+	return c.JSON(fiber.Map{"message": "Member removed"})
+}
+
+// endMemberSessions revokes the sessions of a member who lost permissions,
+// which their tokens would grant until they expire otherwise. Callers who
+// lost them themselves get a new session.
+func endMemberSessions(c fiber.Ctx, callerID, memberID uint) *problem.Error {
+	if err := revokeSessions(tracing.Context(c), memberID, ""); err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to revoke sessions")
+	}
+	claims, err := middleware.ClaimsFrom(c)
+	if memberID != callerID || err != nil || claims.Scopes != nil {
+		return nil
+	}
+	if err := startSession(c, callerID); err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to start session")
+	}
+	return nil
+}
+
+// lastAdmin reports whether member is the only admin of its organization.
+func lastAdmin(ctx context.Context, member models.Membership) bool {
+	var admins int64
+	database.DB.WithContext(ctx).Model(&models.Membership{}).
+		Where("organization_id = ? AND permission = ?", member.OrganizationID, models.PermissionAdmin).
+		Count(&admins)
+	return admins <= 1
+}
+
+// CreateInvitation invites an email address to join an organization. The
+// token is only returned once; the invitee accepts it after logging in.
+func CreateInvitation(c fiber.Ctx) error {
+	membership, perr := membershipOf(c, models.PermissionAdmin)
+	if perr != nil {
+		return perr
+	}
+
+	var body struct {
+		Email      string `json:"email" validate:"required,email"`
+		Permission string `json:"permission" validate:"permission"`
+	}
+	if err := c.Bind().Body(&body); err != nil {
+		return problem.New(fiber.StatusBadRequest, "Cannot parse JSON")
+	}
+	body.Email = strings.TrimSpace(body.Email)
+	if body.Permission == "" {
+		body.Permission = models.PermissionRead
+	}
+	if errs := validation.Struct(&body); errs != nil {
+		return problem.Invalid(errs...)
+	}
+
+	token := randomToken(32)
+	invitation := models.Invitation{
+		OrganizationID: membership.OrganizationID,
+		Email:          body.Email,
+		Permission:     body.Permission,
+		TokenHash:      hashToken(token),
+		InvitedByID:    membership.UserID,
+		ExpiresAt:      time.Now().Add(invitationTTL),
+		Organization:   membership.Organization,
+	}
+	if err := database.DB.WithContext(tracing.Context(c)).Omit("Organization").Create(&invitation).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to create invitation")
+	}
+
+	var inviter models.User
+	database.DB.WithContext(tracing.Context(c)).First(&inviter, membership.UserID)
+	sendMail("invitation", invitation.Email, map[string]string{
+		"Organization": membership.Organization.Name,
+		"InvitedBy":    inviter.Username,
+		"Permission":   invitation.Permission,
+		"Link":         mailer.AppURL("/invitations/accept") + "?token=" + token,
+		"ExpiresIn":    "7 days",
+	})
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"invitation": invitation, "token": token})
+}
+
+// AcceptInvitation adds the caller to the organization they were invited
+// to. The invitation must have been sent to the caller's email address.
+func AcceptInvitation(c fiber.Ctx) error {
+	var body struct {
+		Token string `json:"token" validate:"required"`
+	}
+	if err := c.Bind().Body(&body); err != nil {
+		return problem.New(fiber.StatusBadRequest, "Cannot parse JSON")
+	}
+	if errs := validation.Struct(&body); errs != nil {
+		return problem.Invalid(errs...)
+	}
+
+	userID, err := middleware.UserID(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, "User not authenticated")
+	}
+	var user models.User
+	if err := database.DB.WithContext(tracing.Context(c)).First(&user, userID).Error; err != nil {
+		return problem.New(fiber.StatusNotFound, "User not found")
+	}
+
+	var invitation models.Invitation
+	err = database.DB.WithContext(tracing.Context(c)).Where("token_hash = ? AND accepted_at IS NULL AND expires_at > ?", hashToken(body.Token), time.Now()).
+		First(&invitation).Error
+	if err != nil || !strings.EqualFold(invitation.Email, user.Email) {
+		return problem.New(fiber.StatusNotFound, "Invitation not found or expired")
+	}
+
+	membership := models.Membership{
+		OrganizationID: invitation.OrganizationID,
+		UserID:         user.ID,
+		Permission:     invitation.Permission,
+	}
+	if err := database.DB.WithContext(tracing.Context(c)).Create(&membership).Error; err != nil {
+		return problem.New(fiber.StatusConflict, "Already a member")
+	}
+
+	now := time.Now()
+	invitation.AcceptedAt = &now
+	database.DB.WithContext(tracing.Context(c)).Save(&invitation)
+
+	// Refresh the session so the new organization is in the token
+	if err := startSession(c, user.ID); err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to start session")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(membership)
+}
@@ -0,0 +1,167 @@
+package controller
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Talfaza/authentification/config"
+	"github.com/Talfaza/authentification/database"
+	"github.com/Talfaza/authentification/mailer"
+	"github.com/Talfaza/authentification/middleware"
+	"github.com/Talfaza/authentification/models"
+	"github.com/Talfaza/authentification/policy"
+	"github.com/Talfaza/authentification/problem"
+	"github.com/Talfaza/authentification/tracing"
+	"github.com/Talfaza/authentification/validation"
+	"github.com/gofiber/fiber/v3"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// ForgotPassword mails a password reset link if the email belongs to a
+// local account; directory and SSO accounts have no password here. It
+// always answers the same way so it cannot be used to find out which
+// addresses are registered.
+func ForgotPassword(c fiber.Ctx) error {
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := c.Bind().Body(&body); err != nil {
+		return problem.New(fiber.StatusBadRequest, "Cannot parse JSON")
+	}
+
+	var user models.User
+	email := strings.TrimSpace(body.Email)
+	if email != "" && database.DB.WithContext(tracing.Context(c)).Scopes(models.WithEmail(email)).First(&user).Error == nil && !user.External() {
+		if token, err := issueUserToken(tracing.Context(c), user.ID, models.PurposeResetPassword, resetPasswordTTL); err == nil {
+			sendMail("password_reset", user.Email, map[string]string{
+				"Username":  user.Username,
+				"Link":      mailer.AppURL("/auth/reset-password") + "?token=" + token,
+				"ExpiresIn": "1 hour",
+			})
+		}
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"message": "If the address belongs to an account, a reset link was sent to it"})
+}
+
+// errExternalAccount refuses to give a directory or SSO account a password,
+// which would let it log in after being removed there.
+var errExternalAccount = problem.New(fiber.StatusForbidden, "The account logs in through its directory or SSO provider and has no password here").WithCode(problem.CodeExternalAccount)
+
+// ResetPassword sets a new password with a token from ForgotPassword.
+func ResetPassword(c fiber.Ctx) error {
+	var body struct {
+		Token    string `json:"token" validate:"required"`
+		Password string `json:"password" validate:"required"`
+	}
+	if err := c.Bind().Body(&body); err != nil {
+		return problem.New(fiber.StatusBadRequest, "Cannot parse JSON")
+	}
+	if errs := validation.Struct(&body); errs != nil {
+		return problem.Invalid(errs...)
+	}
+
+	// The token is only looked at here, so a password the policy rejects
+	// does not use it up
+	var user models.User
+	var pending models.UserToken
+	if database.DB.WithContext(tracing.Context(c)).Where("token_hash = ? AND purpose = ?", hashToken(body.Token), models.PurposeResetPassword).First(&pending).Error == nil {
+		database.DB.WithContext(tracing.Context(c)).First(&user, pending.UserID)
+	}
+	if user.External() {
+		return errExternalAccount
+	}
+	if err := policy.Password(body.Password, user.Username, user.Email); err != nil {
+		return problem.Invalid(problem.FieldError{Field: "password", Message: err.Error()})
+	}
+
+	password, err := bcrypt.GenerateFromPassword([]byte(body.Password), config.Default.BcryptCost)
+	if err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to hash password")
+	}
+
+	userToken, err := consumeUserToken(tracing.Context(c), body.Token, models.PurposeResetPassword)
+	if err != nil {
+		return problem.New(fiber.StatusBadRequest, "Invalid or expired token").WithCode(problem.CodeInvalidToken)
+	}
+
+	// Receiving the mail proves the address too
+	now := time.Now()
+	err = database.DB.WithContext(tracing.Context(c)).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userToken.UserID).
+			Updates(map[string]interface{}{"password": string(password), "password_reset_required": false}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).Where("id = ? AND email_verified_at IS NULL", userToken.UserID).
+			Update("email_verified_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ? AND purpose = ? AND used_at IS NULL", userToken.UserID, models.PurposeResetPassword).
+			Delete(&models.UserToken{}).Error
+	})
+	if err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to reset password")
+	}
+
+	// Whoever knew the old password is logged out
+	if err := revokeSessions(tracing.Context(c), userToken.UserID, ""); err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to revoke sessions")
+	}
+
+	return c.JSON(fiber.Map{"message": "Password updated"})
+}
+
+// ChangePassword sets a new password for the caller, who must confirm
+// their current one, and logs out their other sessions.
+func ChangePassword(c fiber.Ctx) error {
+	var body struct {
+		CurrentPassword string `json:"current_password" validate:"required"`
+		NewPassword     string `json:"new_password" validate:"required"`
+	}
+	if err := c.Bind().Body(&body); err != nil {
+		return problem.New(fiber.StatusBadRequest, "Cannot parse JSON")
+	}
+	if errs := validation.Struct(&body); errs != nil {
+		return problem.Invalid(errs...)
+	}
+
+	claims, err := middleware.ClaimsFrom(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, "User not authenticated")
+	}
+	user, perr := currentUser(c)
+	if perr != nil {
+		return perr
+	}
+
+	// A stolen session must not be usable to guess the password
+	if wait := loginLocked(c.IP(), user.Email); wait > 0 {
+		return tooManyAttempts(c, wait)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(body.CurrentPassword)) != nil {
+		loginFailed(tracing.Context(c), c.IP(), user.Email)
+		return problem.New(fiber.StatusUnauthorized, "Current password is incorrect")
+	}
+
+	if body.NewPassword == body.CurrentPassword {
+		return problem.Invalid(problem.FieldError{Field: "new_password", Message: policy.ErrPasswordUnchanged.Error()})
+	}
+	if err := policy.Password(body.NewPassword, user.Username, user.Email); err != nil {
+		return problem.Invalid(problem.FieldError{Field: "new_password", Message: err.Error()})
+	}
+
+	password, err := bcrypt.GenerateFromPassword([]byte(body.NewPassword), config.Default.BcryptCost)
+	if err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to hash password")
+	}
+	if err := database.DB.WithContext(tracing.Context(c)).Model(user).Update("password", string(password)).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to update password")
+	}
+
+	if err := revokeSessions(tracing.Context(c), user.ID, claims.ID); err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to revoke sessions")
+	}
+
+	return c.JSON(fiber.Map{"message": "Password updated"})
+}
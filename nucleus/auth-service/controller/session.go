@@ -0,0 +1,38 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/Talfaza/authentification/database"
+	"github.com/Talfaza/authentification/models"
+	"github.com/Talfaza/authentification/problem"
+	"github.com/Talfaza/authentification/tracing"
+	"github.com/gofiber/fiber/v3"
+)
+
+// revokeSessions revokes the user's unexpired sessions, except the one
+// with exceptJTI if it is set.
+func revokeSessions(ctx context.Context, userID uint, exceptJTI string) error {
+	query := database.DB.WithContext(ctx).Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now())
+	if exceptJTI != "" {
+		query = query.Where("jti <> ?", exceptJTI)
+	}
+	return query.Update("revoked_at", time.Now()).Error
+}
+
+// RevokedSessions lists the jti of every revoked session whose token has
+// not expired yet. Services poll it to reject those tokens; a jti alone
+// cannot be used to authenticate, so the list is public.
+func RevokedSessions(c fiber.Ctx) error {
+	revoked := []string{}
+	if err := database.DB.WithContext(tracing.Context(c)).Model(&models.Session{}).
+		Where("revoked_at IS NOT NULL AND expires_at > ?", time.Now()).
+		Pluck("jti", &revoked).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to load sessions")
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(fiber.Map{"revoked": revoked})
+}
@@ -0,0 +1,365 @@
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Talfaza/authentification/config"
+	"github.com/Talfaza/authentification/database"
+	"github.com/Talfaza/authentification/keys"
+	"github.com/Talfaza/authentification/metrics"
+	"github.com/Talfaza/authentification/middleware"
+	"github.com/Talfaza/authentification/models"
+	"github.com/Talfaza/authentification/problem"
+	"github.com/Talfaza/authentification/tracing"
+	"github.com/Talfaza/authentification/validation"
+	"github.com/gofiber/fiber/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// preAuthTTL is how long the second login step can take.
+	preAuthTTL = 5 * time.Minute
+	// totpPeriod is the TOTP time step in seconds.
+	totpPeriod = 30
+	// recoveryCodeCount is how many recovery codes enrollment hands out.
+	recoveryCodeCount = 10
+)
+
+// requires2FA reports whether the user must have 2FA enabled to log in,
+// either because an admin flagged the account or require_2fa is set.
+func requires2FA(user models.User) bool {
+	return user.TwoFactorRequired || config.Default.Require2FA
+}
+
+// startPreAuth answers the password step of a login for a user who still
+// has to pass 2FA. The token it hands out, also set as the mfa cookie, is
+// only valid for audience: completing a login or enrolling in 2FA.
+func startPreAuth(c fiber.Ctx, user models.User, audience string) error {
+	now := time.Now()
+	claims := &middleware.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			Audience:  jwt.ClaimStrings{audience},
+			ID:        randomToken(16),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(preAuthTTL)),
+		},
+	}
+	t, err := keys.Default.Sign(claims)
+	if err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to start login")
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     "mfa",
+		Value:    t,
+		Expires:  now.Add(preAuthTTL),
+		HTTPOnly: true,
+		Secure:   false, // Set to true in production with HTTPS
+		SameSite: "Lax",
+		Path:     "/auth/2fa",
+	})
+
+	step := "mfa_required"
+	if audience == middleware.EnrollAudience {
+		step = "mfa_enrollment_required"
+	}
+	return c.JSON(fiber.Map{step: true, "mfa_token": t})
+}
+
+func clearPreAuth(c fiber.Ctx) {
+	c.Cookie(&fiber.Cookie{
+		Name:     "mfa",
+		Value:    "",
+		Expires:  time.Now().Add(-time.Hour),
+		HTTPOnly: true,
+		Path:     "/auth/2fa",
+	})
+}
+
+// checkTOTP validates code against the user's secret, allowing one step of
+// clock skew either way. A step is only ever accepted once, even by
+// concurrent requests: the step is recorded only if it is newer than the
+// stored one.
+func checkTOTP(ctx context.Context, user *models.User, code string) bool {
+	opts := totp.ValidateOpts{Period: totpPeriod, Digits: otp.DigitsSix, Algorithm: otp.AlgorithmSHA1}
+	now := time.Now()
+	for _, skew := range []int64{-1, 0, 1} {
+		t := now.Add(time.Duration(skew*totpPeriod) * time.Second)
+		step := t.Unix() / totpPeriod
+		if step <= user.TOTPLastStep {
+			continue
+		}
+		expected, err := totp.GenerateCodeCustom(user.TOTPSecret, t, opts)
+		if err == nil && subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			result := database.DB.WithContext(ctx).Model(&models.User{}).
+				Where("id = ? AND totp_last_step < ?", user.ID, step).
+				UpdateColumn("totp_last_step", step)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return false
+			}
+			user.TOTPLastStep = step
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeRecoveryCode accepts codes in any case, with or without the dash.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	if len(code) != 8 {
+		return code
+	}
+	return code[:4] + "-" + code[4:]
+}
+
+// newRecoveryCodes replaces the user's recovery codes and returns the new
+// ones in clear, which is the only time they are available.
+func newRecoveryCodes(ctx context.Context, userID uint) ([]string, error) {
+	if err := database.DB.WithContext(ctx).Unscoped().Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		_, _ = rand.Read(b)
+		code := normalizeRecoveryCode(base32.StdEncoding.EncodeToString(b))
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		if err := database.DB.WithContext(ctx).Create(&models.RecoveryCode{UserID: userID, CodeHash: string(hash)}).Error; err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// useRecoveryCode consumes a matching unused recovery code.
+func useRecoveryCode(ctx context.Context, userID uint, code string) bool {
+	code = normalizeRecoveryCode(code)
+
+	var stored []models.RecoveryCode
+	database.DB.WithContext(ctx).Where("user_id = ? AND used_at IS NULL", userID).Find(&stored)
+	for _, rc := range stored {
+		if bcrypt.CompareHashAndPassword([]byte(rc.CodeHash), []byte(code)) == nil {
+			result := database.DB.WithContext(ctx).Model(&rc).Where("used_at IS NULL").Update("used_at", time.Now())
+			return result.Error == nil && result.RowsAffected == 1
+		}
+	}
+	return false
+}
+
+// currentUser loads the authenticated user.
+func currentUser(c fiber.Ctx) (*models.User, *problem.Error) {
+	userID, err := middleware.UserID(c)
+	if err != nil {
+		return nil, problem.New(fiber.StatusUnauthorized, "User not authenticated")
+	}
+	var user models.User
+	if err := database.DB.WithContext(tracing.Context(c)).First(&user, userID).Error; err != nil {
+		return nil, problem.New(fiber.StatusNotFound, "User not found")
+	}
+	return &user, nil
+}
+
+// TwoFactorSetup generates a new TOTP secret for the caller. 2FA is only
+// enabled once a code from it has been verified.
+func TwoFactorSetup(c fiber.Ctx) error {
+	user, perr := currentUser(c)
+	if perr != nil {
+		return perr
+	}
+	if user.TOTPEnabled {
+		return problem.New(fiber.StatusConflict, "Two-factor authentication is already enabled")
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      "Nucleus",
+		AccountName: user.Email,
+		Period:      totpPeriod,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to generate secret")
+	}
+
+	user.TOTPSecret = key.Secret()
+	user.TOTPLastStep = 0
+	if err := database.DB.WithContext(tracing.Context(c)).Save(user).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to save secret")
+	}
+
+	return c.JSON(fiber.Map{"secret": key.Secret(), "otpauth_uri": key.URL()})
+}
+
+// TwoFactorVerify enables 2FA once the caller proves their authenticator
+// works, and returns their recovery codes. When the caller was forced to
+// enroll during login, it also starts their session.
+func TwoFactorVerify(c fiber.Ctx) error {
+	var body struct {
+		Code string `json:"code" validate:"required"`
+	}
+	if err := c.Bind().Body(&body); err != nil {
+		return problem.New(fiber.StatusBadRequest, "Cannot parse JSON")
+	}
+	if errs := validation.Struct(&body); errs != nil {
+		return problem.Invalid(errs...)
+	}
+
+	user, perr := currentUser(c)
+	if perr != nil {
+		return perr
+	}
+	if user.TOTPEnabled {
+		return problem.New(fiber.StatusConflict, "Two-factor authentication is already enabled")
+	}
+	if user.TOTPSecret == "" {
+		return problem.New(fiber.StatusConflict, "Call /auth/2fa/setup first")
+	}
+	if !checkTOTP(tracing.Context(c), user, body.Code) {
+		return problem.New(fiber.StatusBadRequest, "Invalid code").WithCode(problem.CodeInvalidCode)
+	}
+
+	if err := database.DB.WithContext(tracing.Context(c)).Model(user).Update("totp_enabled", true).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to enable two-factor authentication")
+	}
+	codes, err := newRecoveryCodes(tracing.Context(c), user.ID)
+	if err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to generate recovery codes")
+	}
+
+	if enrolling, _ := c.Locals("enrolling").(bool); enrolling {
+		if perr := loginBlocked(*user); perr != nil {
+			return perr
+		}
+		loginSucceeded(tracing.Context(c), user.Email, user.ID)
+		clearPreAuth(c)
+		if err := startSession(c, user.ID); err != nil {
+			return problem.New(fiber.StatusInternalServerError, "Failed to start session")
+		}
+	}
+
+	return c.JSON(fiber.Map{"recovery_codes": codes})
+}
+
+// TwoFactorLogin completes a login with a TOTP or recovery code and the
+// token from the password step.
+func TwoFactorLogin(c fiber.Ctx) error {
+	var body struct {
+		MFAToken     string `json:"mfa_token"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := c.Bind().Body(&body); err != nil {
+		return problem.New(fiber.StatusBadRequest, "Cannot parse JSON")
+	}
+
+	raw := body.MFAToken
+	if raw == "" {
+		raw = middleware.PreAuthToken(c)
+	}
+	claims, err := middleware.ParseToken(raw, middleware.PreAuthAudience)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, "Login expired, start again").WithCode(problem.CodeLoginExpired)
+	}
+	userID, err := claims.UserID()
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, "Login expired, start again").WithCode(problem.CodeLoginExpired)
+	}
+
+	var user models.User
+	if err := database.DB.WithContext(tracing.Context(c)).First(&user, userID).Error; err != nil || !user.TOTPEnabled {
+		return problem.New(fiber.StatusUnauthorized, "Login expired, start again").WithCode(problem.CodeLoginExpired)
+	}
+
+	if perr := loginBlocked(user); perr != nil {
+		metrics.Logins.WithLabelValues(metrics.LoginTOTP, metrics.LoginBlocked).Inc()
+		return perr
+	}
+
+	// Codes are only 6 digits, so guesses count like wrong passwords
+	if wait := loginLocked(c.IP(), user.Email); wait > 0 {
+		metrics.Logins.WithLabelValues(metrics.LoginTOTP, metrics.LoginLocked).Inc()
+		return tooManyAttempts(c, wait)
+	}
+
+	ok := body.Code != "" && checkTOTP(tracing.Context(c), &user, body.Code)
+	if !ok && body.RecoveryCode != "" {
+		ok = useRecoveryCode(tracing.Context(c), user.ID, body.RecoveryCode)
+	}
+	if !ok {
+		loginFailed(tracing.Context(c), c.IP(), user.Email)
+		metrics.Logins.WithLabelValues(metrics.LoginTOTP, metrics.LoginFailure).Inc()
+		return problem.New(fiber.StatusUnauthorized, "Invalid code").WithCode(problem.CodeInvalidCode)
+	}
+
+	loginSucceeded(tracing.Context(c), user.Email, user.ID)
+	clearPreAuth(c)
+	if err := startSession(c, user.ID); err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to start session")
+	}
+	metrics.Logins.WithLabelValues(metrics.LoginTOTP, metrics.LoginSuccess).Inc()
+
+	return c.JSON(fiber.Map{"message": "Login successful"})
+}
+
+// TwoFactorDisable turns 2FA off after checking a current code, unless an
+// admin requires it for the caller.
+func TwoFactorDisable(c fiber.Ctx) error {
+	var body struct {
+		Code string `json:"code" validate:"required"`
+	}
+	if err := c.Bind().Body(&body); err != nil {
+		return problem.New(fiber.StatusBadRequest, "Cannot parse JSON")
+	}
+	if errs := validation.Struct(&body); errs != nil {
+		return problem.Invalid(errs...)
+	}
+
+	user, perr := currentUser(c)
+	if perr != nil {
+		return perr
+	}
+	if !user.TOTPEnabled {
+		return problem.New(fiber.StatusConflict, "Two-factor authentication is not enabled")
+	}
+	if requires2FA(*user) {
+		return problem.New(fiber.StatusForbidden, "Two-factor authentication is required for your account").WithCode(problem.CodeTwoFactorRequired)
+	}
+	if !checkTOTP(tracing.Context(c), user, body.Code) {
+		return problem.New(fiber.StatusBadRequest, "Invalid code").WithCode(problem.CodeInvalidCode)
+	}
+
+	if err := resetTwoFactor(tracing.Context(c), user.ID); err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to disable two-factor authentication")
+	}
+	return c.JSON(fiber.Map{"message": "Two-factor authentication disabled"})
+}
+
+// resetTwoFactor removes the user's TOTP secret and recovery codes.
+func resetTwoFactor(ctx context.Context, userID uint) error {
+	err := database.DB.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"totp_secret":    "",
+		"totp_enabled":   false,
+		"totp_last_step": 0,
+	}).Error
+	if err != nil {
+		return err
+	}
+	return database.DB.WithContext(ctx).Unscoped().Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+}
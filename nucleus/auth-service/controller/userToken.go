@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Talfaza/authentification/database"
+	"github.com/Talfaza/authentification/mailer"
+	"github.com/Talfaza/authentification/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// verifyEmailTTL is how long an email verification link works.
+	verifyEmailTTL = 48 * time.Hour
+	// resetPasswordTTL is how long a password reset link works.
+	resetPasswordTTL = time.Hour
+)
+
+var errInvalidUserToken = errors.New("invalid or expired token")
+
+// issueUserToken creates a single-use token for purpose, replacing any
+// unused one the user still has for it, and returns it in clear.
+func issueUserToken(ctx context.Context, userID uint, purpose string, ttl time.Duration) (string, error) {
+	token := randomToken(32)
+	err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+			Delete(&models.UserToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.UserToken{
+			UserID:    userID,
+			Purpose:   purpose,
+			TokenHash: hashToken(token),
+			ExpiresAt: time.Now().Add(ttl),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// consumeUserToken marks an unexpired token for purpose as used and
+// returns it. A token can only be consumed once, even by concurrent
+// requests.
+func consumeUserToken(ctx context.Context, token, purpose string) (*models.UserToken, error) {
+	var userToken models.UserToken
+	err := database.DB.WithContext(ctx).Where("token_hash = ? AND purpose = ?", hashToken(token), purpose).First(&userToken).Error
+	if err != nil || userToken.UsedAt != nil || time.Now().After(userToken.ExpiresAt) {
+		return nil, errInvalidUserToken
+	}
+
+	result := database.DB.WithContext(ctx).Model(&userToken).Where("used_at IS NULL").Update("used_at", time.Now())
+	if result.Error != nil || result.RowsAffected != 1 {
+		return nil, errInvalidUserToken
+	}
+	return &userToken, nil
+}
+
+// sendMail renders and sends a mail in the background, so neither SMTP
+// latency nor failures show in the response. That would otherwise tell
+// callers whether an account exists.
+func sendMail(name, to string, data interface{}) {
+	go func() {
+		if err := mailer.Send(name, to, data); err != nil {
+			slog.Error("Failed to send mail", "mail", name, "error", err)
+		}
+	}()
+}
+
+// sendVerificationMail mails the user a link to verify their address.
+func sendVerificationMail(ctx context.Context, user models.User) error {
+	token, err := issueUserToken(ctx, user.ID, models.PurposeVerifyEmail, verifyEmailTTL)
+	if err != nil {
+		return err
+	}
+	sendMail("verify_email", user.Email, map[string]string{
+		"Username":  user.Username,
+		"Link":      mailer.AppURL("/auth/verify-email") + "?token=" + token,
+		"ExpiresIn": "48 hours",
+	})
+	return nil
+}
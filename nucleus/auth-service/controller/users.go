@@ -0,0 +1,239 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Talfaza/authentification/config"
+	"github.com/Talfaza/authentification/database"
+	"github.com/Talfaza/authentification/logging"
+	"github.com/Talfaza/authentification/mailer"
+	"github.com/Talfaza/authentification/middleware"
+	"github.com/Talfaza/authentification/models"
+	"github.com/Talfaza/authentification/problem"
+	"github.com/Talfaza/authentification/tracing"
+	"github.com/Talfaza/client"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultUsersPerPage = 20
+	maxUsersPerPage     = 100
+)
+
+// loginBlocked returns why the user may not log in, if anything does.
+func loginBlocked(user models.User) *problem.Error {
+	if user.DisabledAt != nil {
+		return problem.New(fiber.StatusForbidden, "Account disabled").WithCode(problem.CodeAccountDisabled)
+	}
+	if user.PasswordResetRequired {
+		return problem.New(fiber.StatusForbidden, "Password reset required, use the link mailed to you or request a new one").WithCode(problem.CodePasswordResetRequired)
+	}
+	return nil
+}
+
+// ListUsers lists users, optionally filtered by q matching their username
+// or email, a page at a time.
+func ListUsers(c fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	perPage, _ := strconv.Atoi(c.Query("per_page", strconv.Itoa(defaultUsersPerPage)))
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > maxUsersPerPage {
+		perPage = defaultUsersPerPage
+	}
+
+	query := database.DB.WithContext(tracing.Context(c)).Model(&models.User{})
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		// ! escapes the wildcards, backslashes are not portable across
+		// databases
+		like := "%" + strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(strings.ToLower(q)) + "%"
+		query = query.Where("LOWER(username) LIKE ? ESCAPE '!' OR LOWER(email) LIKE ? ESCAPE '!'", like, like)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to count users")
+	}
+
+	var users []models.User
+	if err := query.Preload("Roles").Order("id").
+		Offset((page - 1) * perPage).Limit(perPage).
+		Find(&users).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to load users")
+	}
+
+	return c.JSON(fiber.Map{"users": users, "total": total, "page": page, "per_page": perPage})
+}
+
+// GetUser returns one user.
+func GetUser(c fiber.Ctx) error {
+	var user models.User
+	if err := database.DB.WithContext(tracing.Context(c)).Preload("Roles").First(&user, c.Params("id")).Error; err != nil {
+		return problem.New(fiber.StatusNotFound, "User not found")
+	}
+	return c.JSON(user)
+}
+
+// adminTarget loads the user from the :id route parameter for an action
+// an admin cannot take against their own account.
+func adminTarget(c fiber.Ctx) (*models.User, *problem.Error) {
+	var user models.User
+	if err := database.DB.WithContext(tracing.Context(c)).First(&user, c.Params("id")).Error; err != nil {
+		return nil, problem.New(fiber.StatusNotFound, "User not found")
+	}
+	if adminID, _ := middleware.UserID(c); adminID == user.ID {
+		return nil, problem.New(fiber.StatusConflict, "Cannot do this to your own account")
+	}
+	return &user, nil
+}
+
+// DisableUser blocks an account from logging in and ends its sessions.
+// Its personal access tokens stop working too.
+func DisableUser(c fiber.Ctx) error {
+	user, perr := adminTarget(c)
+	if perr != nil {
+		return perr
+	}
+
+	if user.DisabledAt == nil {
+		if err := database.DB.WithContext(tracing.Context(c)).Model(user).Update("disabled_at", time.Now()).Error; err != nil {
+			return problem.New(fiber.StatusInternalServerError, "Failed to disable user")
+		}
+	}
+	if err := revokeSessions(tracing.Context(c), user.ID, ""); err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to revoke sessions")
+	}
+
+	return c.JSON(user)
+}
+
+// EnableUser lets a disabled account log in again.
+func EnableUser(c fiber.Ctx) error {
+	var user models.User
+	if err := database.DB.WithContext(tracing.Context(c)).First(&user, c.Params("id")).Error; err != nil {
+		return problem.New(fiber.StatusNotFound, "User not found")
+	}
+
+	if err := database.DB.WithContext(tracing.Context(c)).Model(&user).Update("disabled_at", nil).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to enable user")
+	}
+
+	return c.JSON(user)
+}
+
+// ForcePasswordReset ends the user's sessions and makes them choose a new
+// password through the reset link mailed to them before logging in again.
+func ForcePasswordReset(c fiber.Ctx) error {
+	user, perr := adminTarget(c)
+	if perr != nil {
+		return perr
+	}
+	if user.External() {
+		return errExternalAccount
+	}
+
+	if err := database.DB.WithContext(tracing.Context(c)).Model(user).Update("password_reset_required", true).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to update user")
+	}
+	if err := revokeSessions(tracing.Context(c), user.ID, ""); err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to revoke sessions")
+	}
+
+	token, err := issueUserToken(tracing.Context(c), user.ID, models.PurposeResetPassword, resetPasswordTTL)
+	if err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to create reset token")
+	}
+	sendMail("password_reset", user.Email, map[string]string{
+		"Username":  user.Username,
+		"Link":      mailer.AppURL("/auth/reset-password") + "?token=" + token,
+		"ExpiresIn": "1 hour",
+	})
+
+	return c.JSON(user)
+}
+
+// DeleteUser deletes a user and everything they own, including their
+// Proxmox servers and LXC configs in the other services. Those are
+// deleted first so a failure there can be retried.
+func DeleteUser(c fiber.Ctx) error {
+	user, perr := adminTarget(c)
+	if perr != nil {
+		return perr
+	}
+
+	if err := deleteUserData(c, user.ID); err != nil {
+		return problem.New(fiber.StatusBadGateway, "Failed to delete the user's data: "+err.Error())
+	}
+
+	if err := revokeSessions(tracing.Context(c), user.ID, ""); err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to revoke sessions")
+	}
+
+	err := database.DB.WithContext(tracing.Context(c)).Transaction(func(tx *gorm.DB) error {
+		for _, owned := range []interface{}{
+			&models.Membership{},
+			&models.APIToken{},
+			&models.RecoveryCode{},
+			&models.UserToken{},
+			&models.UserIdentity{},
+		} {
+			if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(owned).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(user).Association("Roles").Clear(); err != nil {
+			return err
+		}
+		// Hard delete so the username and email can be used again
+		return tx.Unscoped().Delete(user).Error
+	})
+	if err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to delete user")
+	}
+
+	return c.JSON(fiber.Map{"message": "User deleted"})
+}
+
+var serviceClient = &http.Client{Timeout: 10 * time.Second, Transport: logging.Transport{Base: tracing.Transport{}}}
+
+// deleteUserData asks prox-service and lxc-service to delete the user's
+// data, on behalf of the admin making the request and under its request ID.
+func deleteUserData(c fiber.Ctx, userID uint) error {
+	credential := middleware.BearerToken(c)
+	if credential == "" {
+		credential = c.Cookies("jwt")
+	}
+	ctx := logging.Context(c)
+	opts := []client.Option{client.WithHTTPClient(serviceClient), client.WithToken(credential)}
+
+	proxClient, err := client.NewProx(config.Default.ProxServiceURL, opts...)
+	if err != nil {
+		return err
+	}
+	servers, err := proxClient.DeleteUserServersWithResponse(ctx, int(userID))
+	if err == nil {
+		err = client.Check(servers.HTTPResponse, servers.Body)
+	}
+	if err != nil {
+		return fmt.Errorf("prox-service: %w", err)
+	}
+
+	lxcClient, err := client.NewLXC(config.Default.LXCServiceURL, opts...)
+	if err != nil {
+		return err
+	}
+	configs, err := lxcClient.DeleteUserConfigsWithResponse(ctx, int(userID))
+	if err == nil {
+		err = client.Check(configs.HTTPResponse, configs.Body)
+	}
+	if err != nil {
+		return fmt.Errorf("lxc-service: %w", err)
+	}
+	return nil
+}
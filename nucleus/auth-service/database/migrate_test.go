@@ -0,0 +1,236 @@
+package database
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Talfaza/authentification/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// schema returns the SQL SQLite keeps for every table and index, except
+// the migration bookkeeping. GORM emits foreign keys in no particular
+// order, so they are sorted, and separates added columns with a space.
+func schema(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+
+	var statements []string
+	err := db.Raw("SELECT sql FROM sqlite_master WHERE sql IS NOT NULL AND name NOT LIKE 'schema_migrations%' ORDER BY name").
+		Scan(&statements).Error
+	require.NoError(t, err)
+	for i, statement := range statements {
+		statement = strings.ReplaceAll(statement, ", `", ",`")
+		parts := strings.Split(strings.TrimSuffix(statement, ")"), ",CONSTRAINT ")
+		sort.Strings(parts[1:])
+		statements[i] = strings.Join(parts, ",CONSTRAINT ")
+	}
+	return statements
+}
+
+func TestMigrationsMatchModels(t *testing.T) {
+	migrated, err := Open("sqlite://:memory:")
+	require.NoError(t, err)
+	require.NoError(t, Migrate(migrated))
+	// Migrating twice, as every restart does, changes nothing
+	require.NoError(t, Migrate(migrated))
+
+	var roles int64
+	migrated.Table("roles").Count(&roles)
+	assert.Equal(t, int64(3), roles)
+
+	// The schema the models describe, as created before migrations existed
+	legacy, err := Open("sqlite://:memory:")
+	require.NoError(t, err)
+	require.NoError(t, legacy.SetupJoinTable(&models.User{}, "Roles", &models.UserRole{}))
+	require.NoError(t, legacy.AutoMigrate(
+		&models.User{},
+		&models.Role{},
+		&models.UserRole{},
+		&models.Organization{},
+		&models.Membership{},
+		&models.Invitation{},
+		&models.APIToken{},
+		&models.RecoveryCode{},
+		&models.UserToken{},
+		&models.LockoutEvent{},
+		&models.Session{},
+		&models.UserIdentity{},
+	))
+	want := schema(t, legacy)
+	assert.Equal(t, want, schema(t, migrated), "a model changed without a migration")
+
+	// Such a database is adopted as is
+	require.NoError(t, Migrate(legacy))
+	assert.Equal(t, want, schema(t, legacy))
+}
+
+func TestUpDown(t *testing.T) {
+	db, err := Open("sqlite://:memory:")
+	require.NoError(t, err)
+
+	type widget struct {
+		ID   uint
+		Name string
+	}
+	steps := []Migration{
+		{
+			Version: 1,
+			Name:    "widgets",
+			Up:      func(tx *gorm.DB) error { return tx.Migrator().CreateTable(&widget{}) },
+			Down:    func(tx *gorm.DB) error { return tx.Migrator().DropTable(&widget{}) },
+		},
+		{
+			Version: 2,
+			Name:    "widget colors",
+			Up:      func(tx *gorm.DB) error { return tx.Exec("ALTER TABLE widgets ADD color VARCHAR(16)").Error },
+			Down:    func(tx *gorm.DB) error { return tx.Exec("ALTER TABLE widgets DROP COLUMN color").Error },
+		},
+	}
+
+	applied, err := Up(db, steps)
+	require.NoError(t, err)
+	assert.Equal(t, 2, applied)
+	assert.True(t, db.Migrator().HasColumn(&widget{}, "color"))
+
+	applied, err = Up(db, steps)
+	require.NoError(t, err)
+	assert.Equal(t, 0, applied)
+
+	reverted, err := Down(db, steps, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, reverted)
+	assert.False(t, db.Migrator().HasColumn(&widget{}, "color"))
+	assert.True(t, db.Migrator().HasTable(&widget{}))
+
+	statuses, err := Status(db, steps)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.Nil(t, statuses[1].AppliedAt)
+	pending, err := Pending(db, steps)
+	require.NoError(t, err)
+	assert.Equal(t, 1, pending)
+
+	// A failing migration is rolled back and not recorded
+	broken := append(steps[:1:1], Migration{
+		Version: 2,
+		Name:    "broken",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Exec("ALTER TABLE widgets ADD color VARCHAR(16)").Error; err != nil {
+				return err
+			}
+			return errors.New("boom")
+		},
+		Down: func(tx *gorm.DB) error { return nil },
+	})
+	_, err = Up(db, broken)
+	assert.ErrorContains(t, err, "boom")
+	assert.False(t, db.Migrator().HasColumn(&widget{}, "color"))
+
+	reverted, err = Down(db, steps, 5)
+	require.NoError(t, err)
+	assert.Equal(t, 1, reverted)
+	assert.False(t, db.Migrator().HasTable(&widget{}))
+
+	_, err = Up(db, []Migration{steps[1], steps[0]})
+	assert.ErrorContains(t, err, "out of order")
+}
+
+func TestLock(t *testing.T) {
+	db, err := Open("sqlite://:memory:")
+	require.NoError(t, err)
+	require.NoError(t, createTable(db, &migrationLock{}))
+	require.NoError(t, db.Create(&migrationLock{ID: 1, Owner: "other:1", LockedAt: time.Now()}).Error)
+
+	timeout := LockTimeout
+	LockTimeout = 0
+	defer func() { LockTimeout = timeout }()
+
+	_, err = Up(db, migrations)
+	assert.ErrorContains(t, err, "locked by other:1")
+	assert.False(t, db.Migrator().HasTable("users"))
+
+	// The lock is released once the other process is done
+	require.NoError(t, db.Delete(&migrationLock{}, 1).Error)
+	_, err = Up(db, migrations)
+	require.NoError(t, err)
+	var locks int64
+	db.Model(&migrationLock{}).Count(&locks)
+	assert.Equal(t, int64(0), locks)
+
+	// The other process may release the lock between the failed insert and
+	// the look up of who holds it
+	db, err = Open("sqlite://:memory:")
+	require.NoError(t, err)
+	require.NoError(t, createTable(db, &migrationLock{}))
+	require.NoError(t, db.Create(&migrationLock{ID: 1, Owner: "other:1", LockedAt: time.Now()}).Error)
+	LockTimeout = time.Minute
+	released := false
+	require.NoError(t, db.Callback().Query().Before("gorm:query").Register("test:release", func(tx *gorm.DB) {
+		if tx.Statement.Table == (migrationLock{}).TableName() && !released {
+			released = true
+			require.NoError(t, db.Exec("DELETE FROM "+migrationLock{}.TableName()).Error)
+		}
+	}))
+	_, err = Up(db, migrations)
+	require.NoError(t, err)
+	assert.True(t, released)
+	assert.True(t, db.Migrator().HasTable("users"))
+}
+
+func TestAssignRoles(t *testing.T) {
+	db, err := Open("sqlite://:memory:")
+	require.NoError(t, err)
+	_, err = Up(db, migrations[:1])
+	require.NoError(t, err)
+
+	// Accounts created before roles existed
+	for _, name := range []string{"alice", "bob", "carol"} {
+		require.NoError(t, db.Create(&baselineUser{Username: name, Email: name + "@lab.local"}).Error)
+	}
+	var viewer baselineRole
+	require.NoError(t, db.Where("name = ?", "viewer").First(&viewer).Error)
+	require.NoError(t, db.Omit("User", "Role").Create(&baselineUserRole{UserID: 3, RoleID: viewer.ID}).Error)
+
+	require.NoError(t, Migrate(db))
+
+	var got []struct {
+		Username string
+		Name     string
+	}
+	err = db.Table("user_roles").
+		Select("users.username, roles.name").
+		Joins("JOIN users ON users.id = user_roles.user_id").
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Order("users.id").Scan(&got).Error
+	require.NoError(t, err)
+	assert.Equal(t, []struct {
+		Username string
+		Name     string
+	}{{"alice", "admin"}, {"bob", "operator"}, {"carol", "viewer"}}, got)
+}
+
+func TestUserSources(t *testing.T) {
+	db, err := Open("sqlite://:memory:")
+	require.NoError(t, err)
+	_, err = Up(db, migrations[:2])
+	require.NoError(t, err)
+
+	// A registered account, and two created by a directory and an SSO
+	// provider without a password
+	require.NoError(t, db.Create(&baselineUser{Username: "alice", Email: "alice@lab.local", Password: "hash"}).Error)
+	require.NoError(t, db.Create(&baselineUser{Username: "bob", Email: "bob@lab.local"}).Error)
+	require.NoError(t, db.Create(&baselineUser{Username: "carol", Email: "carol@lab.local"}).Error)
+	require.NoError(t, db.Create(&baselineUserIdentity{UserID: 3, Issuer: "https://sso.lab.local", Subject: "carol"}).Error)
+
+	require.NoError(t, Migrate(db))
+
+	var sources []string
+	require.NoError(t, db.Table("users").Order("id").Pluck("source", &sources).Error)
+	assert.Equal(t, []string{"local", "ldap", "sso"}, sources)
+}
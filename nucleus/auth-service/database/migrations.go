@@ -0,0 +1,284 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// migrations is the schema of auth-service, oldest first. Each one works
+// on its own copy of the models as they were when it was written, so
+// later changes to package models cannot alter what it does.
+var migrations = []Migration{
+	{Version: 1, Name: "baseline", Up: baselineUp, Down: baselineDown},
+	{Version: 2, Name: "assign roles", Up: assignRolesUp, Down: assignRolesDown},
+	{Version: 3, Name: "user sources", Up: userSourcesUp, Down: userSourcesDown},
+}
+
+// The baseline is the schema the services used to AutoMigrate at startup.
+// Up adopts a database created that way: AutoMigrate only adds what is
+// missing.
+
+type baselineUser struct {
+	gorm.Model
+	Username              string `gorm:"unique"`
+	Email                 string `gorm:"unique"`
+	Password              string
+	Memberships           []baselineMembership `gorm:"foreignKey:UserID"`
+	EmailVerifiedAt       *time.Time
+	TOTPSecret            string
+	TOTPEnabled           bool
+	TOTPLastStep          int64
+	TwoFactorRequired     bool
+	DisabledAt            *time.Time
+	PasswordResetRequired bool
+	LastLoginAt           *time.Time
+}
+
+func (baselineUser) TableName() string { return "users" }
+
+type baselineRole struct {
+	gorm.Model
+	Name string `gorm:"uniqueIndex;size:32"`
+}
+
+func (baselineRole) TableName() string { return "roles" }
+
+// baselineUserRole spells out the constraints AutoMigrate derived from
+// the many2many relation between users and roles.
+type baselineUserRole struct {
+	UserID uint `gorm:"primaryKey"`
+	RoleID uint `gorm:"primaryKey"`
+	User   baselineUser
+	Role   baselineRole
+}
+
+func (baselineUserRole) TableName() string { return "user_roles" }
+
+type baselineOrganization struct {
+	gorm.Model
+	Name string `gorm:"uniqueIndex;size:64"`
+}
+
+func (baselineOrganization) TableName() string { return "organizations" }
+
+type baselineMembership struct {
+	gorm.Model
+	OrganizationID uint   `gorm:"uniqueIndex:idx_membership"`
+	UserID         uint   `gorm:"uniqueIndex:idx_membership"`
+	Permission     string `gorm:"size:16"`
+	Organization   baselineOrganization
+	User           baselineUser
+}
+
+func (baselineMembership) TableName() string { return "memberships" }
+
+type baselineInvitation struct {
+	gorm.Model
+	OrganizationID uint
+	Email          string
+	Permission     string `gorm:"size:16"`
+	TokenHash      string `gorm:"uniqueIndex;size:64"`
+	InvitedByID    uint
+	ExpiresAt      time.Time
+	AcceptedAt     *time.Time
+	Organization   baselineOrganization
+}
+
+func (baselineInvitation) TableName() string { return "invitations" }
+
+type baselineAPIToken struct {
+	gorm.Model
+	UserID     uint     `gorm:"index"`
+	Name       string   `gorm:"size:64"`
+	Prefix     string   `gorm:"size:16"`
+	TokenHash  string   `gorm:"uniqueIndex;size:64"`
+	Scopes     []string `gorm:"serializer:json"`
+	ExpiresAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+func (baselineAPIToken) TableName() string { return "api_tokens" }
+
+type baselineRecoveryCode struct {
+	gorm.Model
+	UserID   uint `gorm:"index"`
+	CodeHash string
+	UsedAt   *time.Time
+}
+
+func (baselineRecoveryCode) TableName() string { return "recovery_codes" }
+
+type baselineUserToken struct {
+	gorm.Model
+	UserID    uint   `gorm:"index"`
+	Purpose   string `gorm:"size:32"`
+	TokenHash string `gorm:"uniqueIndex;size:64"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+func (baselineUserToken) TableName() string { return "user_tokens" }
+
+type baselineLockoutEvent struct {
+	gorm.Model
+	Scope       string `gorm:"size:16"`
+	IP          string `gorm:"size:64"`
+	Email       string `gorm:"index"`
+	Failures    int
+	LockedUntil time.Time
+}
+
+func (baselineLockoutEvent) TableName() string { return "lockout_events" }
+
+type baselineSession struct {
+	gorm.Model
+	UserID    uint       `gorm:"index"`
+	JTI       string     `gorm:"uniqueIndex;size:64"`
+	IP        string     `gorm:"size:64"`
+	UserAgent string     `gorm:"size:255"`
+	ExpiresAt time.Time  `gorm:"index"`
+	RevokedAt *time.Time `gorm:"index"`
+}
+
+func (baselineSession) TableName() string { return "sessions" }
+
+type baselineUserIdentity struct {
+	gorm.Model
+	UserID  uint   `gorm:"index"`
+	Issuer  string `gorm:"uniqueIndex:idx_identity;size:255"`
+	Subject string `gorm:"uniqueIndex:idx_identity;size:255"`
+	Email   string
+}
+
+func (baselineUserIdentity) TableName() string { return "user_identities" }
+
+// baselineTables is in creation order; they are dropped in reverse.
+var baselineTables = []interface{}{
+	&baselineUser{},
+	&baselineRole{},
+	&baselineUserRole{},
+	&baselineOrganization{},
+	&baselineMembership{},
+	&baselineInvitation{},
+	&baselineAPIToken{},
+	&baselineRecoveryCode{},
+	&baselineUserToken{},
+	&baselineLockoutEvent{},
+	&baselineSession{},
+	&baselineUserIdentity{},
+}
+
+func baselineUp(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(baselineTables...); err != nil {
+		return err
+	}
+	for _, name := range []string{"admin", "operator", "viewer"} {
+		if err := tx.FirstOrCreate(&baselineRole{}, baselineRole{Name: name}).Error; err != nil {
+			return fmt.Errorf("failed to seed roles: %v", err)
+		}
+	}
+	return nil
+}
+
+func baselineDown(tx *gorm.DB) error {
+	for i := len(baselineTables) - 1; i >= 0; i-- {
+		if err := tx.Migrator().DropTable(baselineTables[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Accounts created before roles existed have none, and would be forbidden
+// everywhere. assignRolesUp makes them operators, except the oldest account,
+// which administers the instance unless someone already does, so there is
+// an admin to fix the others.
+func assignRolesUp(tx *gorm.DB) error {
+	var roles []baselineRole
+	if err := tx.Where("name IN ?", []string{"admin", "operator"}).Find(&roles).Error; err != nil {
+		return err
+	}
+	ids := map[string]uint{}
+	for _, role := range roles {
+		ids[role.Name] = role.ID
+	}
+	if ids["admin"] == 0 || ids["operator"] == 0 {
+		return errors.New("the admin and operator roles are missing")
+	}
+	grant := func(userID, roleID uint) error {
+		return tx.Omit(clause.Associations).Create(&baselineUserRole{UserID: userID, RoleID: roleID}).Error
+	}
+
+	var admins int64
+	if err := tx.Model(&baselineUserRole{}).Where("role_id = ?", ids["admin"]).Count(&admins).Error; err != nil {
+		return err
+	}
+	if admins == 0 {
+		var oldest []baselineUser
+		if err := tx.Order("id").Limit(1).Find(&oldest).Error; err != nil {
+			return err
+		}
+		if len(oldest) == 0 {
+			return nil
+		}
+		if err := grant(oldest[0].ID, ids["admin"]); err != nil {
+			return err
+		}
+	}
+
+	var roleless []uint
+	err := tx.Model(&baselineUser{}).
+		Where("id NOT IN (?)", tx.Model(&baselineUserRole{}).Select("user_id")).
+		Order("id").Pluck("id", &roleless).Error
+	if err != nil {
+		return err
+	}
+	for _, id := range roleless {
+		if err := grant(id, ids["operator"]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assignRolesDown keeps the roles: they cannot be told apart from the ones
+// given since.
+func assignRolesDown(tx *gorm.DB) error {
+	return nil
+}
+
+// Users record where they come from, so directory and SSO accounts cannot
+// be given a local password. Those created before have none, which tells
+// them apart: the ones linked to an SSO identity come from the provider,
+// the others from the directory.
+
+type sourcesUser struct {
+	Source string `gorm:"size:16;not null;default:local"`
+}
+
+func (sourcesUser) TableName() string { return "users" }
+
+func userSourcesUp(tx *gorm.DB) error {
+	if tx.Migrator().HasColumn(&sourcesUser{}, "Source") {
+		return nil
+	}
+	if err := tx.Migrator().AddColumn(&sourcesUser{}, "Source"); err != nil {
+		return err
+	}
+
+	passwordless := tx.Model(&baselineUser{}).Where("password = ? OR password IS NULL", "")
+	linked := tx.Model(&baselineUserIdentity{}).Select("user_id")
+	if err := passwordless.Session(&gorm.Session{}).Where("id IN (?)", linked).Update("source", "sso").Error; err != nil {
+		return err
+	}
+	return passwordless.Session(&gorm.Session{}).Where("id NOT IN (?)", linked).Update("source", "ldap").Error
+}
+
+func userSourcesDown(tx *gorm.DB) error {
+	return tx.Migrator().DropColumn(&sourcesUser{}, "Source")
+}
@@ -0,0 +1,80 @@
+// Package health serves the liveness and readiness probes and shuts the
+// service down gracefully.
+package health
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// Check reports whether a dependency the service needs is usable.
+type Check func(ctx context.Context) error
+
+// checkTimeout bounds each check so a hung dependency cannot hang the probe.
+const checkTimeout = 2 * time.Second
+
+// Register serves /healthz, which answers as long as the process does, and
+// /readyz, which runs every check. Register it before any middleware so
+// probes are not logged, limited or authenticated.
+func Register(app *fiber.App, checks map[string]Check) {
+	app.Get("/healthz", func(c fiber.Ctx) error {
+		return c.JSON(fiber.Map{"status": "ok"})
+	})
+
+	app.Get("/readyz", func(c fiber.Ctx) error {
+		results := make(map[string]string, len(checks))
+		ready := true
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for name, check := range checks {
+			wg.Add(1)
+			go func(name string, check Check) {
+				defer wg.Done()
+				ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+				defer cancel()
+
+				err := check(ctx)
+				mu.Lock()
+				defer mu.Unlock()
+				results[name] = "ok"
+				if err != nil {
+					results[name] = err.Error()
+					ready = false
+				}
+			}(name, check)
+		}
+		wg.Wait()
+
+		if !ready {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"status": "unavailable", "checks": results})
+		}
+		return c.JSON(fiber.Map{"status": "ok", "checks": results})
+	})
+}
+
+// Serve runs app on addr until SIGINT or SIGTERM. It then stops accepting
+// connections and gives in-flight requests up to timeout to finish; the
+// error reports those it had to cut off.
+func Serve(app *fiber.App, addr string, timeout time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- app.Listen(addr)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	return app.ShutdownWithTimeout(timeout)
+}
@@ -0,0 +1,94 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func probe(t *testing.T, app *fiber.App, path string) (int, map[string]interface{}) {
+	t.Helper()
+
+	resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+	require.NoError(t, err)
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	return resp.StatusCode, body
+}
+
+func TestProbes(t *testing.T) {
+	var dbErr error
+	app := fiber.New()
+	Register(app, map[string]Check{
+		"database": func(ctx context.Context) error { return dbErr },
+		"cache":    func(ctx context.Context) error { return nil },
+	})
+
+	status, _ := probe(t, app, "/healthz")
+	assert.Equal(t, fiber.StatusOK, status)
+
+	status, body := probe(t, app, "/readyz")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, map[string]interface{}{"database": "ok", "cache": "ok"}, body["checks"])
+
+	dbErr = errors.New("connection refused")
+	status, body = probe(t, app, "/readyz")
+	assert.Equal(t, fiber.StatusServiceUnavailable, status)
+	assert.Equal(t, map[string]interface{}{"database": "connection refused", "cache": "ok"}, body["checks"])
+
+	// A failing dependency does not make the process unhealthy
+	status, _ = probe(t, app, "/healthz")
+	assert.Equal(t, fiber.StatusOK, status)
+}
+
+func TestServeDrains(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	started := make(chan struct{})
+	app := fiber.New()
+	Register(app, nil)
+	app.Get("/slow", func(c fiber.Ctx) error {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		return c.SendString("done")
+	})
+
+	served := make(chan error, 1)
+	go func() { served <- Serve(app, addr, 5*time.Second) }()
+	require.Eventually(t, func() bool {
+		resp, err := http.Get("http://" + addr + "/healthz")
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+
+	responses := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		assert.NoError(t, err)
+		responses <- resp
+	}()
+	<-started
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+
+	// The request in flight finishes before Serve returns
+	resp := <-responses
+	require.NotNil(t, resp)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+	assert.NoError(t, <-served)
+}
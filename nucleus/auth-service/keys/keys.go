@@ -0,0 +1,232 @@
+package keys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Talfaza/authentification/logging"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key is a private signing key identified by its kid.
+type Key struct {
+	ID      string
+	Private crypto.Signer
+	Method  jwt.SigningMethod
+}
+
+// KeySet holds every key auth-service knows about. Tokens are signed with
+// the active key; the others stay published so tokens signed before a
+// rotation keep verifying until they expire.
+type KeySet struct {
+	keys   map[string]*Key
+	active *Key
+}
+
+// JWK is the public half of a key in RFC 7517 form.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// JWKS is the document served at /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+var Default *KeySet
+
+// Load reads the signing keys from dir. Every *.pem file in the directory
+// is a key whose kid is the file name without the extension. activeKID
+// selects the signing key, otherwise the last kid in lexical order is used,
+// so naming keys by date makes the newest one active. An empty directory
+// gets a freshly generated Ed25519 key.
+func Load(dir, activeKID string) {
+	set, err := LoadDir(dir, activeKID)
+	if err != nil {
+		logging.Fatal("Failed to load JWT signing keys", "error", err)
+	}
+	Default = set
+}
+
+// LoadDir builds a KeySet from the PEM files in dir.
+func LoadDir(dir, activeKID string) (*KeySet, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		path, err := generate(dir)
+		if err != nil {
+			return nil, err
+		}
+		files = []string{path}
+	}
+	sort.Strings(files)
+
+	set := &KeySet{keys: make(map[string]*Key)}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+		key, err := ParsePEM(kid, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		set.keys[kid] = key
+		set.active = key
+	}
+
+	if activeKID != "" {
+		key, ok := set.keys[activeKID]
+		if !ok {
+			return nil, fmt.Errorf("active key %q not found in %s", activeKID, dir)
+		}
+		set.active = key
+	}
+
+	return set, nil
+}
+
+// ParsePEM decodes a PKCS#8 (Ed25519 or RSA) or PKCS#1 (RSA) private key.
+func ParsePEM(kid string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		rsaKey, rsaErr := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if rsaErr != nil {
+			return nil, errors.New("unsupported private key encoding")
+		}
+		parsed = rsaKey
+	}
+
+	return NewKey(kid, parsed)
+}
+
+// NewKey wraps an Ed25519 or RSA private key.
+func NewKey(kid string, private interface{}) (*Key, error) {
+	switch k := private.(type) {
+	case ed25519.PrivateKey:
+		return &Key{ID: kid, Private: k, Method: jwt.SigningMethodEdDSA}, nil
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		return &Key{ID: kid, Private: k, Method: jwt.SigningMethodRS256}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", private)
+	}
+}
+
+// NewKeySet builds a KeySet from keys already in memory. The last key is
+// the active one.
+func NewKeySet(keys ...*Key) *KeySet {
+	set := &KeySet{keys: make(map[string]*Key)}
+	for _, key := range keys {
+		set.keys[key.ID] = key
+		set.active = key
+	}
+	return set
+}
+
+// generate writes a new Ed25519 key named after the current date.
+func generate(dir string) (string, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, time.Now().UTC().Format("20060102-150405")+".pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// ActiveKID returns the kid new tokens are signed with.
+func (s *KeySet) ActiveKID() string {
+	return s.active.ID
+}
+
+// Sign signs claims with the active key and stamps its kid in the header.
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.active.Method, claims)
+	token.Header["kid"] = s.active.ID
+	return token.SignedString(s.active.Private)
+}
+
+// Keyfunc resolves the public key for a token by its kid.
+func (s *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key.Private.Public(), nil
+}
+
+// Methods lists the algorithms tokens may be signed with.
+func (s *KeySet) Methods() []string {
+	return []string{jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodRS256.Alg()}
+}
+
+// JWKS returns the public keys in JWK Set form.
+func (s *KeySet) JWKS() JWKS {
+	kids := make([]string, 0, len(s.keys))
+	for kid := range s.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	set := JWKS{Keys: make([]JWK, 0, len(kids))}
+	for _, kid := range kids {
+		key := s.keys[kid]
+		jwk := JWK{Kid: kid, Use: "sig", Alg: key.Method.Alg()}
+		switch pub := key.Private.Public().(type) {
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
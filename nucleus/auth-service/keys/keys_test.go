@@ -0,0 +1,65 @@
+package keys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+
+	// An empty directory gets a generated key
+	set, err := LoadDir(dir, "")
+	require.NoError(t, err)
+	assert.Len(t, set.JWKS().Keys, 1)
+
+	// Loading again reuses the key written to disk
+	again, err := LoadDir(dir, "")
+	require.NoError(t, err)
+	assert.Equal(t, set.ActiveKID(), again.ActiveKID())
+
+	_, err = LoadDir(dir, "missing")
+	assert.Error(t, err)
+}
+
+func TestRotation(t *testing.T) {
+	_, oldPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	oldKey, _ := NewKey("2024-01", oldPrivate)
+	rsaPrivate, _ := rsa.GenerateKey(rand.Reader, 2048)
+	newKey, err := NewKey("2024-02", rsaPrivate)
+	require.NoError(t, err)
+
+	claims := jwt.MapClaims{"sub": "1", "exp": time.Now().Add(time.Hour).Unix()}
+
+	// Token signed before the rotation
+	oldToken, err := NewKeySet(oldKey).Sign(claims)
+	require.NoError(t, err)
+
+	set := NewKeySet(oldKey, newKey)
+	newToken, err := set.Sign(claims)
+	require.NoError(t, err)
+
+	for _, raw := range []string{oldToken, newToken} {
+		token, err := jwt.Parse(raw, set.Keyfunc, jwt.WithValidMethods(set.Methods()))
+		require.NoError(t, err)
+		assert.True(t, token.Valid)
+	}
+
+	token, _, _ := jwt.NewParser().ParseUnverified(newToken, jwt.MapClaims{})
+	assert.Equal(t, "2024-02", token.Header["kid"])
+	assert.Equal(t, "RS256", token.Header["alg"])
+
+	jwks := set.JWKS()
+	require.Len(t, jwks.Keys, 2)
+	assert.Equal(t, "OKP", jwks.Keys[0].Kty)
+	assert.Equal(t, "Ed25519", jwks.Keys[0].Crv)
+	assert.Equal(t, "RSA", jwks.Keys[1].Kty)
+	assert.Equal(t, "AQAB", jwks.Keys[1].E)
+}
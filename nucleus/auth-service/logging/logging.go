@@ -0,0 +1,157 @@
+// Package logging writes structured JSON logs and tags every request with
+// an ID that follows it across services.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Talfaza/authentification/tracing"
+	"github.com/gofiber/fiber/v3"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type requestIDKey struct{}
+
+// maxRequestIDLength bounds the IDs accepted from callers, which end up in
+// every log line of the request.
+const maxRequestIDLength = 128
+
+// Setup makes slog, and the standard log package through it, write JSON
+// lines to stderr from level up: debug, info, warn or error.
+func Setup(level string) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: l})))
+	return nil
+}
+
+// Fatal logs msg at error level and exits.
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// Register tags the requests to the routes registered after it with an ID
+// and logs them once answered.
+func Register(app *fiber.App) {
+	app.Use(requestID)
+	app.Use(accessLog)
+}
+
+// requestID keeps the X-Request-ID of the caller, usually the gateway, or
+// generates one, and echoes it in the response.
+func requestID(c fiber.Ctx) error {
+	id := c.Get(fiber.HeaderXRequestID)
+	if !validRequestID(id) {
+		id = newRequestID()
+	}
+	c.Locals(requestIDKey{}, id)
+	c.Set(fiber.HeaderXRequestID, id)
+	return c.Next()
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r <= ' ' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+func accessLog(c fiber.Ctx) error {
+	start := time.Now()
+	err := c.Next()
+
+	status := c.Response().StatusCode()
+	var ferr *fiber.Error
+	if errors.As(err, &ferr) {
+		status = ferr.Code
+	} else if err != nil {
+		status = fiber.StatusInternalServerError
+	}
+
+	level := slog.LevelInfo
+	if status >= fiber.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	attrs := []any{
+		"method", c.Method(),
+		"path", c.Path(),
+		"route", c.Route().Path,
+		"status", status,
+		"duration_ms", time.Since(start).Milliseconds(),
+		"ip", c.IP(),
+	}
+	if err != nil {
+		attrs = append(attrs, "error", err.Error())
+	}
+	Logger(c).Log(context.Background(), level, "request", attrs...)
+	return err
+}
+
+// ID returns the ID of the request, or "" outside of Register.
+func ID(c fiber.Ctx) string {
+	id, _ := c.Locals(requestIDKey{}).(string)
+	return id
+}
+
+// Logger returns the default logger with the request and trace IDs
+// attached.
+func Logger(c fiber.Ctx) *slog.Logger {
+	logger := slog.Default()
+	if id := ID(c); id != "" {
+		logger = logger.With("request_id", id)
+	}
+	if span := trace.SpanContextFromContext(tracing.Context(c)); span.IsValid() {
+		logger = logger.With("trace_id", span.TraceID().String())
+	}
+	return logger
+}
+
+// Context returns a context carrying the request ID, for outbound calls
+// made through Transport. It must not outlive the handler.
+func Context(c fiber.Ctx) context.Context {
+	return context.WithValue(tracing.Context(c), requestIDKey{}, ID(c))
+}
+
+// Transport passes the request ID found in the context of each request
+// on to the service called.
+type Transport struct {
+	// Base makes the requests, http.DefaultTransport if nil
+	Base http.RoundTripper
+}
+
+func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	id, _ := req.Context().Value(requestIDKey{}).(string)
+	if id == "" || req.Header.Get(fiber.HeaderXRequestID) != "" {
+		return base.RoundTrip(req)
+	}
+
+	// A RoundTripper must not modify the request it was given
+	req = req.Clone(req.Context())
+	req.Header.Set(fiber.HeaderXRequestID, id)
+	return base.RoundTrip(req)
+}
@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestID(t *testing.T) {
+	// Setup: a service answering with the request ID it received
+	var received string
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(fiber.HeaderXRequestID)
+	}))
+	defer service.Close()
+	client := &http.Client{Transport: Transport{}}
+
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+	defer slog.SetDefault(defaultLogger)
+
+	app := fiber.New()
+	Register(app)
+	app.Get("/call", func(c fiber.Ctx) error {
+		req, err := http.NewRequestWithContext(Context(c), http.MethodGet, service.URL, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return c.SendString(ID(c))
+	})
+
+	call := func(id string) (*http.Response, map[string]interface{}) {
+		logs.Reset()
+		req := httptest.NewRequest("GET", "/call", nil)
+		if id != "" {
+			req.Header.Set(fiber.HeaderXRequestID, id)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+
+		var line map[string]interface{}
+		require.NoError(t, json.Unmarshal(logs.Bytes(), &line))
+		return resp, line
+	}
+
+	t.Run("kept", func(t *testing.T) {
+		resp, line := call("gateway-42")
+		assert.Equal(t, "gateway-42", resp.Header.Get(fiber.HeaderXRequestID))
+		assert.Equal(t, "gateway-42", received)
+		assert.Equal(t, "gateway-42", line["request_id"])
+		assert.Equal(t, "request", line["msg"])
+		assert.Equal(t, "/call", line["route"])
+		assert.Equal(t, float64(fiber.StatusOK), line["status"])
+	})
+
+	t.Run("generated", func(t *testing.T) {
+		resp, line := call("")
+		id := resp.Header.Get(fiber.HeaderXRequestID)
+		assert.Len(t, id, 32)
+		assert.Equal(t, id, received)
+		assert.Equal(t, id, line["request_id"])
+	})
+
+	t.Run("invalid replaced", func(t *testing.T) {
+		for _, id := range []string{"has space", strings.Repeat("a", maxRequestIDLength+1)} {
+			resp, _ := call(id)
+			assert.Len(t, resp.Header.Get(fiber.HeaderXRequestID), 32)
+			assert.NotEqual(t, id, received)
+		}
+	})
+}
+
+func TestSetup(t *testing.T) {
+	defaultLogger := slog.Default()
+	defer slog.SetDefault(defaultLogger)
+
+	require.NoError(t, Setup("warn"))
+	assert.False(t, slog.Default().Enabled(context.Background(), slog.LevelInfo))
+	assert.True(t, slog.Default().Enabled(context.Background(), slog.LevelWarn))
+	assert.Error(t, Setup("loud"))
+}
@@ -0,0 +1,39 @@
+package mailer
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// LogMailer prints mails to the log instead of sending them. Links in
+// them, e.g. password resets, can be copied from the service output.
+type LogMailer struct{}
+
+func (LogMailer) Send(msg Message) error {
+	slog.Info("Mail", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
+
+// FileMailer writes each mail to Dir as a .eml file that mail clients can
+// open.
+type FileMailer struct {
+	Dir  string
+	From string
+
+	seq atomic.Uint64
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	if err := os.MkdirAll(m.Dir, 0o700); err != nil {
+		return err
+	}
+
+	recipient := strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(msg.To)
+	name := fmt.Sprintf("%s-%d-%s.eml", time.Now().UTC().Format("20060102-150405"), m.seq.Add(1), recipient)
+	return os.WriteFile(filepath.Join(m.Dir, name), encode(m.From, msg), 0o600)
+}
@@ -0,0 +1,101 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/Talfaza/authentification/config"
+	"github.com/Talfaza/authentification/logging"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages. Implementations must be safe for concurrent
+// use.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Default is the mailer used by the handlers, set up by Load.
+var Default Mailer = LogMailer{}
+
+// From is the sender address of every mail.
+var From = "Nucleus <no-reply@localhost>"
+
+// Load picks the mailer from cfg.MailDriver:
+//   - "smtp" sends through smtp_host:smtp_port, authenticating with
+//     smtp_username and smtp_password when set
+//   - "file" writes each mail to mail_dir as a .eml file
+//   - "log" prints mails to the log
+//
+// It also sets From and the base of AppURL.
+func Load(cfg *config.Config) {
+	From = cfg.MailFrom
+	appURL = cfg.AppURL
+
+	switch cfg.MailDriver {
+	case "smtp":
+		Default = &SMTPMailer{
+			Addr:     net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort)),
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     From,
+		}
+	case "file":
+		Default = &FileMailer{Dir: cfg.MailDir, From: From}
+	case "log":
+		Default = LogMailer{}
+	default:
+		logging.Fatal("Unknown mail driver", "driver", cfg.MailDriver)
+	}
+}
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var templates = template.Must(template.ParseFS(templateFS, "templates/*.tmpl"))
+
+// Render builds a message from the template name (e.g. "verify_email").
+// Each template defines "<name>_subject" and "<name>_body".
+func Render(name, to string, data interface{}) (Message, error) {
+	var subject, body bytes.Buffer
+	if err := templates.ExecuteTemplate(&subject, name+"_subject", data); err != nil {
+		return Message{}, fmt.Errorf("render %s: %w", name, err)
+	}
+	if err := templates.ExecuteTemplate(&body, name+"_body", data); err != nil {
+		return Message{}, fmt.Errorf("render %s: %w", name, err)
+	}
+	return Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Body:    strings.TrimLeft(body.String(), "\n"),
+	}, nil
+}
+
+// Send renders the template name and delivers it with the default mailer.
+func Send(name, to string, data interface{}) error {
+	msg, err := Render(name, to, data)
+	if err != nil {
+		return err
+	}
+	return Default.Send(msg)
+}
+
+// appURL is the frontend base URL, set by Load.
+var appURL = "http://localhost:3000"
+
+// AppURL builds a link into the frontend.
+func AppURL(p string) string {
+	return strings.TrimRight(appURL, "/") + path.Clean("/"+p)
+}
@@ -0,0 +1,62 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Talfaza/authentification/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	for _, name := range []string{"verify_email", "password_reset", "invitation"} {
+		t.Run(name, func(t *testing.T) {
+			msg, err := Render(name, "alice@example.com", map[string]string{
+				"Username":     "alice",
+				"Link":         "http://localhost:3000/x?token=abc",
+				"ExpiresIn":    "1 hour",
+				"Organization": "ops",
+				"InvitedBy":    "bob",
+				"Permission":   "read",
+			})
+			require.NoError(t, err)
+			assert.Equal(t, "alice@example.com", msg.To)
+			assert.NotEmpty(t, msg.Subject)
+			assert.NotContains(t, msg.Subject, "\n")
+			assert.Contains(t, msg.Body, "http://localhost:3000/x?token=abc")
+		})
+	}
+
+	_, err := Render("missing", "alice@example.com", nil)
+	assert.Error(t, err)
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	m := &FileMailer{Dir: dir, From: "Nucleus <no-reply@localhost>"}
+
+	err := m.Send(Message{To: "alice@example.com", Subject: "Hello\r\nBcc: eve@example.com", Body: "line 1\nline 2\n"})
+	require.NoError(t, err)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.Len(t, files, 1)
+	data, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+
+	assert.Contains(t, string(data), "To: alice@example.com\r\n")
+	assert.Contains(t, string(data), "line 1\r\nline 2\r\n")
+	// Line breaks in headers cannot add new ones
+	assert.False(t, strings.Contains(string(data), "\r\nBcc:"))
+}
+
+func TestAppURL(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.AppURL = "https://nucleus.example.com/"
+	Load(cfg)
+	t.Cleanup(func() { Load(config.Defaults()) })
+
+	assert.Equal(t, "https://nucleus.example.com/auth/reset-password", AppURL("auth/reset-password"))
+}
@@ -1,33 +1,84 @@
 package main
 
 import (
+	"github.com/Talfaza/authentification/api"
+	"github.com/Talfaza/authentification/authn"
+	"github.com/Talfaza/authentification/config"
 	"github.com/Talfaza/authentification/database"
+	"github.com/Talfaza/authentification/health"
+	"github.com/Talfaza/authentification/keys"
+	"github.com/Talfaza/authentification/logging"
+	"github.com/Talfaza/authentification/mailer"
+	"github.com/Talfaza/authentification/metrics"
+	"github.com/Talfaza/authentification/policy"
+	"github.com/Talfaza/authentification/problem"
 	"github.com/Talfaza/authentification/routes"
+	"github.com/Talfaza/authentification/sso"
+	"github.com/Talfaza/authentification/tracing"
 	"github.com/gofiber/fiber/v3"
-	"github.com/joho/godotenv"
-  "github.com/gofiber/fiber/v3/middleware/cors"
 
-	"log"
+	"context"
+	"log/slog"
+	"os"
+	"time"
 )
 
 func main() {
-	err := godotenv.Load()
+	cfg, err := config.Load(os.Args[1:])
 	if err != nil {
-		log.Fatal("Error loading .env")
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	if err := logging.Setup(cfg.LogLevel); err != nil {
+		logging.Fatal("Invalid log level", "error", err)
+	}
+	if cfg.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			logging.Fatal("Failed to print configuration", "error", err)
+		}
+		return
+	}
+	if len(cfg.Args) > 0 {
+		if cfg.Args[0] != "migrate" {
+			logging.Fatal("Unknown command", "command", cfg.Args[0])
+		}
+		if err := database.Command(cfg.DSN, cfg.Args[1:], os.Stdout); err != nil {
+			logging.Fatal("Migration failed", "error", err)
+		}
+		return
+	}
+	config.Default = cfg
+	flushSpans, err := tracing.Setup("auth-service", cfg.OTLPEndpoint)
+	if err != nil {
+		logging.Fatal("Failed to set up tracing", "error", err)
 	}
 
-	app := fiber.New()
-
-
-	app.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:3000"},
-		AllowMethods:     []string{"GET", "POST", "HEAD", "PUT", "DELETE", "PATCH", "OPTIONS"},
-		AllowHeaders:     []string{"Content-Type", "Authorization"},
-		AllowCredentials: true,
-	}))
+	fiberConfig := fiber.Config{ErrorHandler: problem.Handler}
+	if len(cfg.TrustedProxies) > 0 {
+		fiberConfig.TrustProxy = true
+		fiberConfig.TrustProxyConfig = fiber.TrustProxyConfig{Proxies: cfg.TrustedProxies}
+		fiberConfig.ProxyHeader = fiber.HeaderXForwardedFor
+	}
+	app := fiber.New(fiberConfig)
 
-	database.Connect()
+	database.Connect(cfg.DSN, cfg.AutoMigrate)
+	authn.Load(database.DB, cfg)
+	keys.Load(cfg.JWTKeysDir, cfg.JWTActiveKID)
+	mailer.Load(cfg)
+	policy.Load(cfg.BreachedPasswordsFile)
+	sso.Load(cfg)
+	health.Register(app, map[string]health.Check{"database": database.Ping})
+	metrics.Register(app)
+	tracing.Register(app)
+	logging.Register(app)
+	api.Register(app)
 	routes.Setup(app)
 
-	log.Fatal(app.Listen(":9872"))
+	if err := health.Serve(app, cfg.Addr, time.Duration(cfg.ShutdownTimeout)*time.Second); err != nil {
+		logging.Fatal("Server failed", "error", err)
+	}
+	_ = database.Close()
+	if err := flushSpans(context.Background()); err != nil {
+		slog.Warn("Failed to export spans", "error", err)
+	}
+	slog.Info("Server stopped")
 }
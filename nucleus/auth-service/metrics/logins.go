@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Steps a user logs in with.
+const (
+	LoginPassword = "password"
+	LoginTOTP     = "totp"
+	LoginSSO      = "sso"
+)
+
+// Results of a login step. A password step for an account with 2FA ends
+// in LoginMFARequired; LoginBlocked is a disabled account or one that must
+// reset its password, LoginLocked a throttled client.
+const (
+	LoginSuccess     = "success"
+	LoginFailure     = "failure"
+	LoginMFARequired = "mfa_required"
+	LoginBlocked     = "blocked"
+	LoginLocked      = "locked"
+)
+
+// Logins counts login steps by method and result.
+var Logins = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "nucleus_auth_logins_total",
+	Help: "Login steps by method and result.",
+}, []string{"method", "result"})
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegister(t *testing.T) {
+	app := fiber.New()
+	app.Get("/healthz", func(c fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+	Register(app)
+	app.Get("/users/:id", func(c fiber.Ctx) error {
+		if c.Params("id") == "0" {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+	app.Get("/fail", func(c fiber.Ctx) error { return fiber.ErrBadGateway })
+
+	for _, path := range []string{"/users/1", "/users/2", "/users/0", "/fail", "/nowhere", "/healthz"} {
+		_, err := app.Test(httptest.NewRequest("GET", path, nil))
+		require.NoError(t, err)
+	}
+
+	// Routes are counted by pattern, not path
+	assert.Equal(t, 2.0, testutil.ToFloat64(requests.WithLabelValues("/users/:id", "GET", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(requests.WithLabelValues("/users/:id", "GET", "404")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(requests.WithLabelValues("/fail", "GET", "502")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(requests.WithLabelValues(unmatched, "GET", "404")))
+	// Probes registered before are not counted
+	assert.Equal(t, 0.0, testutil.ToFloat64(requests.WithLabelValues("/healthz", "GET", "200")))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/metrics", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `nucleus_http_request_duration_seconds_count{method="GET",route="/users/:id"} 3`)
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/Talfaza/authentification/database"
+	"github.com/Talfaza/authentification/models"
+	"github.com/Talfaza/authentification/problem"
+	"github.com/gofiber/fiber/v3"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrInvalidAPIToken = errors.New("invalid or expired API token")
+
+// APITokenClaims resolves a personal access token into claims for its
+// owner, limited to the token's scopes, and records that it was used.
+func APITokenClaims(ctx context.Context, token string) (*Claims, error) {
+	sum := sha256.Sum256([]byte(token))
+
+	var apiToken models.APIToken
+	err := database.DB.WithContext(ctx).
+		Where("token_hash = ? AND revoked_at IS NULL AND expires_at > ?", hex.EncodeToString(sum[:]), time.Now()).
+		First(&apiToken).Error
+	if err != nil {
+		return nil, ErrInvalidAPIToken
+	}
+
+	var user models.User
+	if err := database.DB.WithContext(ctx).Preload("Roles").Preload("Memberships").First(&user, apiToken.UserID).Error; err != nil {
+		return nil, ErrInvalidAPIToken
+	}
+	if user.DisabledAt != nil {
+		return nil, ErrInvalidAPIToken
+	}
+
+	now := time.Now()
+	database.DB.WithContext(ctx).Model(&apiToken).UpdateColumn("last_used_at", now)
+
+	claims := NewClaims(user, 0)
+	claims.ID = "pat-" + strconv.FormatUint(uint64(apiToken.ID), 10)
+	claims.ExpiresAt = jwt.NewNumericDate(apiToken.ExpiresAt)
+	claims.Scopes = apiToken.Scopes
+	return claims, nil
+}
+
+// RequireSession rejects personal access tokens, so a leaked token cannot
+// be used to mint or revoke others. It must run after AuthRequired.
+func RequireSession(c fiber.Ctx) error {
+	claims, err := ClaimsFrom(c)
+	if err != nil || claims.Scopes != nil {
+		return problem.New(fiber.StatusForbidden, "This endpoint requires a login session").WithCode(problem.CodeSessionRequired)
+	}
+	return c.Next()
+}
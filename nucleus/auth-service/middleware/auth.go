@@ -1,23 +1,96 @@
 package middleware
 
 import (
+	"errors"
+	"strings"
+
+	"github.com/Talfaza/authentification/keys"
+	"github.com/Talfaza/authentification/models"
+	"github.com/Talfaza/authentification/problem"
+	"github.com/Talfaza/authentification/tracing"
 	"github.com/gofiber/fiber/v3"
 	"github.com/golang-jwt/jwt/v5"
-	"os"
 )
 
+var ErrSessionRevoked = errors.New("session was revoked")
+
+// AuthRequired accepts the jwt session cookie, or an Authorization: Bearer
+// header carrying either a JWT or a personal access token.
 func AuthRequired(c fiber.Ctx) error {
-	cookie := c.Cookies("jwt")
+	raw := BearerToken(c)
+	if raw == "" {
+		raw = c.Cookies("jwt")
+	}
+
+	var claims *Claims
+	var err error
+	if strings.HasPrefix(raw, models.APITokenPrefix) {
+		claims, err = APITokenClaims(tracing.Context(c), raw)
+	} else {
+		claims, err = ParseToken(raw, SessionAudience)
+		if err == nil && SessionRevoked(tracing.Context(c), claims.ID) {
+			err = ErrSessionRevoked
+		}
+	}
 
-	token, err := jwt.Parse(cookie, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, "Unauthorized")
+	}
 
-	if err != nil || !token.Valid {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	if _, err := claims.UserID(); err != nil {
+		return problem.New(fiber.StatusUnauthorized, "Unauthorized")
 	}
 
-	claims := token.Claims.(jwt.MapClaims)
-	c.Locals("userID", claims["sub"])
+	c.Locals("claims", claims)
 	return c.Next()
 }
+
+// BearerToken returns the credential from the Authorization header, if any.
+func BearerToken(c fiber.Ctx) string {
+	header := c.Get(fiber.HeaderAuthorization)
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+// ParseToken verifies a JWT signed by auth-service for audience.
+func ParseToken(raw, audience string) (*Claims, error) {
+	claims := new(Claims)
+	token, err := jwt.ParseWithClaims(raw, claims, keys.Default.Keyfunc,
+		jwt.WithValidMethods(keys.Default.Methods()),
+		jwt.WithAudience(audience),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, jwt.ErrTokenUnverifiable
+	}
+	return claims, nil
+}
+
+// PreAuthToken returns the token handed out by the password step of a 2FA
+// login, from the Authorization header or the mfa cookie.
+func PreAuthToken(c fiber.Ctx) string {
+	if raw := BearerToken(c); raw != "" {
+		return raw
+	}
+	return c.Cookies("mfa")
+}
+
+// AuthOrEnrollment is AuthRequired that also accepts the token handed out
+// at login when an admin requires 2FA the user has not set up yet. Such a
+// token only opens the 2FA setup endpoints.
+func AuthOrEnrollment(c fiber.Ctx) error {
+	if raw := PreAuthToken(c); raw != "" {
+		if claims, err := ParseToken(raw, EnrollAudience); err == nil {
+			if _, err := claims.UserID(); err == nil {
+				c.Locals("claims", claims)
+				c.Locals("enrolling", true)
+				return c.Next()
+			}
+		}
+	}
+	return AuthRequired(c)
+}
@@ -1,12 +1,14 @@
 package middleware
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"testing"
 	"time"
 
+	"github.com/Talfaza/authentification/keys"
 	"github.com/gofiber/fiber/v3"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
@@ -14,7 +16,9 @@ import (
 
 func TestAuthRequired(t *testing.T) {
 	// Setup
-	_ = os.Setenv("JWT_SECRET", "test_secret")
+	_, private, _ := ed25519.GenerateKey(rand.Reader)
+	key, _ := keys.NewKey("test", private)
+	keys.Default = keys.NewKeySet(key)
 	app := fiber.New()
 	app.Use(AuthRequired)
 	app.Get("/protected", func(c fiber.Ctx) error {
@@ -23,11 +27,11 @@ func TestAuthRequired(t *testing.T) {
 
 	// Create valid JWT token
 	claims := jwt.MapClaims{
-		"sub": 123,
+		"sub": "123",
+		"aud": SessionAudience,
 		"exp": time.Now().Add(time.Hour).Unix(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	validToken, _ := token.SignedString([]byte("test_secret"))
+	validToken, _ := keys.Default.Sign(claims)
 
 	t.Run("valid token", func(t *testing.T) {
 		// Prepare
@@ -71,11 +75,11 @@ func TestAuthRequired(t *testing.T) {
 	t.Run("expired token", func(t *testing.T) {
 		// Create expired token
 		expiredClaims := jwt.MapClaims{
-			"sub": 123,
+			"sub": "123",
+			"aud": SessionAudience,
 			"exp": time.Now().Add(-time.Hour).Unix(),
 		}
-		expiredToken := jwt.NewWithClaims(jwt.SigningMethodHS256, expiredClaims)
-		expiredTokenString, _ := expiredToken.SignedString([]byte("test_secret"))
+		expiredTokenString, _ := keys.Default.Sign(expiredClaims)
 
 		// Prepare
 		req := httptest.NewRequest("GET", "/protected", nil)
@@ -90,4 +94,45 @@ func TestAuthRequired(t *testing.T) {
 		// Verify
 		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
 	})
+
+	t.Run("hmac token", func(t *testing.T) {
+		// A token signed with a shared secret must not be accepted
+		hmacToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		hmacTokenString, _ := hmacToken.SignedString([]byte("test_secret"))
+
+		// Prepare
+		req := httptest.NewRequest("GET", "/protected", nil)
+		req.AddCookie(&http.Cookie{
+			Name:  "jwt",
+			Value: hmacTokenString,
+		})
+
+		// Execute
+		resp, _ := app.Test(req)
+
+		// Verify
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("pre-auth token", func(t *testing.T) {
+		// The token handed out before the 2FA step is not a session
+		preAuthToken, _ := keys.Default.Sign(jwt.MapClaims{
+			"sub": "123",
+			"aud": PreAuthAudience,
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+
+		// Prepare
+		req := httptest.NewRequest("GET", "/protected", nil)
+		req.AddCookie(&http.Cookie{
+			Name:  "jwt",
+			Value: preAuthToken,
+		})
+
+		// Execute
+		resp, _ := app.Test(req)
+
+		// Verify
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	})
 }
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Talfaza/authentification/keys"
+	"github.com/gofiber/fiber/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClaimsUserID(t *testing.T) {
+	tests := []struct {
+		name    string
+		subject string
+		want    uint
+		wantErr error
+	}{
+		{"numeric", "42", 42, nil},
+		{"missing", "", 0, ErrMissingSubject},
+		{"non-numeric", "alice", 0, ErrInvalidSubject},
+		{"negative", "-1", 0, ErrInvalidSubject},
+		{"zero", "0", 0, ErrInvalidSubject},
+		{"float", "42.5", 0, ErrInvalidSubject},
+		{"overflow", "184467440737095516160", 0, ErrInvalidSubject},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := &Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: tt.subject}}
+			got, err := claims.UserID()
+			assert.Equal(t, tt.want, got)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUserID(t *testing.T) {
+	// Setup
+	_, private, _ := ed25519.GenerateKey(rand.Reader)
+	key, _ := keys.NewKey("test", private)
+	keys.Default = keys.NewKeySet(key)
+
+	app := fiber.New()
+	app.Get("/public", func(c fiber.Ctx) error {
+		_, err := UserID(c)
+		assert.ErrorIs(t, err, ErrNoClaims)
+		return c.SendStatus(fiber.StatusOK)
+	})
+	app.Group("/protected", AuthRequired).Get("", func(c fiber.Ctx) error {
+		id, err := UserID(c)
+		if err != nil {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+		return c.SendString(strconv.FormatUint(uint64(id), 10))
+	})
+
+	request := func(path string, claims jwt.Claims) *http.Response {
+		req := httptest.NewRequest("GET", path, nil)
+		if claims != nil {
+			token, _ := keys.Default.Sign(claims)
+			req.AddCookie(&http.Cookie{Name: "jwt", Value: token})
+		}
+		resp, _ := app.Test(req)
+		return resp
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+
+	t.Run("no middleware", func(t *testing.T) {
+		resp := request("/public", nil)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("string subject", func(t *testing.T) {
+		resp := request("/protected", jwt.MapClaims{"sub": "7", "email": "a@b.c", "aud": SessionAudience, "exp": exp})
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("numeric subject", func(t *testing.T) {
+		resp := request("/protected", jwt.MapClaims{"sub": 7, "aud": SessionAudience, "exp": exp})
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("missing subject", func(t *testing.T) {
+		resp := request("/protected", jwt.MapClaims{"aud": SessionAudience, "exp": exp})
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("non-numeric subject", func(t *testing.T) {
+		resp := request("/protected", jwt.MapClaims{"sub": "alice", "aud": SessionAudience, "exp": exp})
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("malformed roles", func(t *testing.T) {
+		resp := request("/protected", jwt.MapClaims{"sub": "7", "roles": "admin", "aud": SessionAudience, "exp": exp})
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	})
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/Talfaza/authentification/database"
+	"github.com/Talfaza/authentification/models"
+)
+
+// SessionRevoked reports whether the session with jti was revoked, e.g.
+// by logging out or changing the password. Sessions without a row or
+// jti, like those started before sessions were recorded, are not revoked.
+func SessionRevoked(ctx context.Context, jti string) bool {
+	if jti == "" {
+		return false
+	}
+
+	var count int64
+	database.DB.WithContext(ctx).Model(&models.Session{}).Where("jti = ? AND revoked_at IS NOT NULL", jti).Count(&count)
+	return count > 0
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Scopes a personal access token can be limited to.
+const (
+	ScopeRead    = "read"
+	ScopeWrite   = "write"
+	ScopeExecute = "execute"
+)
+
+var Scopes = []string{ScopeRead, ScopeWrite, ScopeExecute}
+
+// APITokenPrefix starts every personal access token so services can tell
+// them apart from JWTs.
+const APITokenPrefix = "nuc_"
+
+// APIToken is a personal access token for CLI and CI use. Only a hash of
+// the token is stored; Prefix keeps enough of it to recognise in a list.
+type APIToken struct {
+	gorm.Model
+	UserID     uint       `json:"user_id" gorm:"index"`
+	Name       string     `json:"name" gorm:"size:64"`
+	Prefix     string     `json:"prefix" gorm:"size:16"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex;size:64"`
+	Scopes     []string   `json:"scopes" gorm:"serializer:json"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Scopes of a LockoutEvent.
+const (
+	LockoutIP      = "ip"
+	LockoutAccount = "account"
+)
+
+// LockoutEvent records an IP address or account being locked out after
+// repeated failed logins, for admins to review.
+type LockoutEvent struct {
+	gorm.Model
+	Scope       string    `json:"scope" gorm:"size:16"`
+	IP          string    `json:"ip" gorm:"size:64"`
+	Email       string    `json:"email" gorm:"index"`
+	Failures    int       `json:"failures"`
+	LockedUntil time.Time `json:"locked_until"`
+}
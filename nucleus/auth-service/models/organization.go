@@ -0,0 +1,72 @@
+package models
+
+import (
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Permissions a member can hold in an organization, weakest first. Each
+// one includes the ones before it.
+const (
+	PermissionRead  = "read"
+	PermissionWrite = "write"
+	PermissionAdmin = "admin"
+)
+
+var Permissions = []string{PermissionRead, PermissionWrite, PermissionAdmin}
+
+// PermissionAtLeast reports whether have grants want.
+func PermissionAtLeast(have, want string) bool {
+	rank := map[string]int{PermissionRead: 1, PermissionWrite: 2, PermissionAdmin: 3}
+	return rank[have] > 0 && rank[have] >= rank[want]
+}
+
+// ValidPermission reports whether p is a known permission.
+func ValidPermission(p string) bool {
+	return PermissionAtLeast(p, PermissionRead)
+}
+
+type Organization struct {
+	gorm.Model
+	Name string `json:"name" gorm:"uniqueIndex;size:64"`
+}
+
+// Membership grants a user access to the servers and containers shared
+// with an organization.
+type Membership struct {
+	gorm.Model
+	OrganizationID uint         `json:"org_id" gorm:"uniqueIndex:idx_membership"`
+	UserID         uint         `json:"user_id" gorm:"uniqueIndex:idx_membership"`
+	Permission     string       `json:"permission" gorm:"size:16"`
+	Organization   Organization `json:"organization"`
+	User           User         `json:"-"`
+}
+
+// Invitation lets the owner of Email join an organization. Only a hash of
+// the token is stored.
+type Invitation struct {
+	gorm.Model
+	OrganizationID uint         `json:"org_id"`
+	Email          string       `json:"email"`
+	Permission     string       `json:"permission" gorm:"size:16"`
+	TokenHash      string       `json:"-" gorm:"uniqueIndex;size:64"`
+	InvitedByID    uint         `json:"invited_by_id"`
+	ExpiresAt      time.Time    `json:"expires_at"`
+	AcceptedAt     *time.Time   `json:"accepted_at"`
+	Organization   Organization `json:"organization"`
+}
+
+// OrgPermissions maps organization IDs to the user's permission in each,
+// in the form embedded in the JWT. Memberships must be loaded.
+func (u User) OrgPermissions() map[string]string {
+	if len(u.Memberships) == 0 {
+		return nil
+	}
+	orgs := make(map[string]string, len(u.Memberships))
+	for _, m := range u.Memberships {
+		orgs[strconv.FormatUint(uint64(m.OrganizationID), 10)] = m.Permission
+	}
+	return orgs
+}
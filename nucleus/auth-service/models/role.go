@@ -0,0 +1,24 @@
+package models
+
+import "gorm.io/gorm"
+
+const (
+	RoleAdmin    = "admin"
+	RoleOperator = "operator"
+	RoleViewer   = "viewer"
+)
+
+// Roles lists the roles seeded at startup. New users get RoleOperator,
+// except the very first account which becomes RoleAdmin.
+var Roles = []string{RoleAdmin, RoleOperator, RoleViewer}
+
+type Role struct {
+	gorm.Model
+	Name string `json:"name" gorm:"uniqueIndex;size:32"`
+}
+
+// UserRole is the join table between users and roles.
+type UserRole struct {
+	UserID uint `json:"user_id" gorm:"primaryKey"`
+	RoleID uint `json:"role_id" gorm:"primaryKey"`
+}
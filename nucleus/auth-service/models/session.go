@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Session is a login session, identified by the jti of its JWT. Tokens
+// are verified statelessly; a session only needs a row so it can be
+// revoked before its token expires.
+type Session struct {
+	gorm.Model
+	UserID    uint       `json:"user_id" gorm:"index"`
+	JTI       string     `json:"-" gorm:"uniqueIndex;size:64"`
+	IP        string     `json:"ip" gorm:"size:64"`
+	UserAgent string     `json:"user_agent" gorm:"size:255"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"index"`
+	RevokedAt *time.Time `json:"revoked_at" gorm:"index"`
+}
@@ -1,10 +1,70 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The sources of accounts.
+const (
+	SourceLocal = "local"
+	SourceLDAP  = "ldap"
+	SourceSSO   = "sso"
+)
 
 type User struct {
 	gorm.Model
 	Username string `json:"username" gorm:"unique"`
 	Email    string `json:"email" gorm:"unique"`
-	Password string `json:"password"`
+	Password string `json:"-"`
+	Roles    []Role `json:"roles" gorm:"many2many:user_roles"`
+
+	Memberships []Membership `json:"-"`
+
+	// EmailVerifiedAt is set once the user opened the link mailed to them
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+
+	// TOTPSecret is set by 2FA setup and only trusted once TOTPEnabled.
+	// TOTPLastStep is the last time step a code was accepted for, so a
+	// code cannot be replayed. TwoFactorRequired is set by admins.
+	TOTPSecret        string `json:"-"`
+	TOTPEnabled       bool   `json:"totp_enabled"`
+	TOTPLastStep      int64  `json:"-"`
+	TwoFactorRequired bool   `json:"two_factor_required"`
+
+	// DisabledAt blocks the account from logging in. PasswordResetRequired
+	// makes the user go through a password reset before their next login.
+	DisabledAt            *time.Time `json:"disabled_at"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
+	LastLoginAt           *time.Time `json:"last_login_at"`
+
+	// Source is where the account comes from. Only local accounts have a
+	// password; the others are vouched for by a directory or an SSO
+	// provider and must log in through it.
+	Source string `json:"source" gorm:"size:16;not null;default:local"`
+}
+
+// External tells whether the account comes from a directory or an SSO
+// provider rather than registration.
+func (u User) External() bool {
+	return u.Source != "" && u.Source != SourceLocal
+}
+
+// RoleNames returns the names of the user's loaded roles.
+func (u User) RoleNames() []string {
+	names := make([]string, 0, len(u.Roles))
+	for _, role := range u.Roles {
+		names = append(names, role.Name)
+	}
+	return names
+}
+
+// WithEmail finds users by email regardless of case, as MySQL does by
+// default but PostgreSQL and SQLite do not.
+func WithEmail(email string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("LOWER(email) = ?", strings.ToLower(strings.TrimSpace(email)))
+	}
 }
@@ -0,0 +1,13 @@
+package models
+
+import "gorm.io/gorm"
+
+// UserIdentity links a user to an account at an OpenID Connect provider,
+// identified by the provider's issuer and subject.
+type UserIdentity struct {
+	gorm.Model
+	UserID  uint   `json:"user_id" gorm:"index"`
+	Issuer  string `json:"issuer" gorm:"uniqueIndex:idx_identity;size:255"`
+	Subject string `json:"subject" gorm:"uniqueIndex:idx_identity;size:255"`
+	Email   string `json:"email"`
+}
@@ -0,0 +1,143 @@
+package policy
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Talfaza/authentification/logging"
+)
+
+const (
+	// MinPasswordLength follows NIST SP 800-63B for user-chosen passwords.
+	MinPasswordLength = 8
+	// MaxPasswordLength is what bcrypt can hash; longer input is an error.
+	MaxPasswordLength = 72
+)
+
+var (
+	ErrInvalidEmail      = errors.New("Invalid email address")
+	ErrInvalidUsername   = errors.New("Username must be 3 to 32 letters, digits, '.', '_' or '-', starting with a letter or digit")
+	ErrPasswordTooShort  = fmt.Errorf("Password must be at least %d characters", MinPasswordLength)
+	ErrPasswordTooLong   = fmt.Errorf("Password must be at most %d bytes", MaxPasswordLength)
+	ErrPasswordPersonal  = errors.New("Password must not contain your username or email")
+	ErrPasswordBreached  = errors.New("This password appeared in a data breach, choose another one")
+	ErrPasswordUnchanged = errors.New("New password must differ from the current one")
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{2,31}$`)
+
+// Email checks an address and returns it trimmed. Display names
+// ("Alice <alice@example.com>") are rejected.
+func Email(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || len(email) > 254 {
+		return "", ErrInvalidEmail
+	}
+	return email, nil
+}
+
+// Username checks a username's length and charset.
+func Username(username string) error {
+	if !usernamePattern.MatchString(username) {
+		return ErrInvalidUsername
+	}
+	return nil
+}
+
+// Password checks a new password for a user with the given username and
+// email. It deliberately has no composition rules; length and the breached
+// password list do more for strength.
+func Password(password, username, email string) error {
+	if utf8.RuneCountInString(password) < MinPasswordLength {
+		return ErrPasswordTooShort
+	}
+	if len(password) > MaxPasswordLength {
+		return ErrPasswordTooLong
+	}
+
+	lower := strings.ToLower(password)
+	local, _, _ := strings.Cut(strings.ToLower(email), "@")
+	for _, personal := range []string{strings.ToLower(username), local} {
+		if len(personal) >= 3 && strings.Contains(lower, personal) {
+			return ErrPasswordPersonal
+		}
+	}
+
+	if Breached.Contains(password) {
+		return ErrPasswordBreached
+	}
+	return nil
+}
+
+// BreachedList is a set of SHA-1 hashes of known breached passwords.
+type BreachedList map[string]struct{}
+
+// Breached is the list Password checks against, set up by Load.
+var Breached = BreachedList{}
+
+// Contains reports whether password is on the list.
+func (b BreachedList) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	_, ok := b[strings.ToUpper(hex.EncodeToString(sum[:]))]
+	return ok
+}
+
+// Load reads the breached password list from path, if set.
+func Load(path string) {
+	if path == "" {
+		return
+	}
+
+	list, err := LoadBreached(path)
+	if err != nil {
+		logging.Fatal("Failed to load breached passwords", "error", err)
+	}
+	Breached = list
+	slog.Info("Loaded breached passwords", "count", len(list))
+}
+
+// LoadBreached reads a breached password list with one entry per line,
+// either a password in clear or its SHA-1 hash in hex. Have I Been Pwned
+// dumps ("HASH:count") can be used as is.
+func LoadBreached(path string) (BreachedList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	list := BreachedList{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+
+		if hash, _, _ := strings.Cut(line, ":"); isSHA1(hash) {
+			list[strings.ToUpper(hash)] = struct{}{}
+			continue
+		}
+		sum := sha1.Sum([]byte(line))
+		list[strings.ToUpper(hex.EncodeToString(sum[:]))] = struct{}{}
+	}
+	return list, scanner.Err()
+}
+
+func isSHA1(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,72 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmail(t *testing.T) {
+	tests := []struct {
+		email string
+		want  string
+		err   error
+	}{
+		{"alice@example.com", "alice@example.com", nil},
+		{"  alice@example.com ", "alice@example.com", nil},
+		{"", "", ErrInvalidEmail},
+		{"alice", "", ErrInvalidEmail},
+		{"alice@", "", ErrInvalidEmail},
+		{"Alice <alice@example.com>", "", ErrInvalidEmail},
+		{"alice@example.com, bob@example.com", "", ErrInvalidEmail},
+	}
+	for _, tt := range tests {
+		got, err := Email(tt.email)
+		assert.Equal(t, tt.want, got, tt.email)
+		assert.ErrorIs(t, err, tt.err, tt.email)
+	}
+}
+
+func TestUsername(t *testing.T) {
+	for _, ok := range []string{"alice", "a.b-c_d", "abc", strings.Repeat("a", 32)} {
+		assert.NoError(t, Username(ok), ok)
+	}
+	for _, bad := range []string{"", "ab", "_alice", "alice bob", "alice<script>", strings.Repeat("a", 33), "élodie"} {
+		assert.ErrorIs(t, Username(bad), ErrInvalidUsername, bad)
+	}
+}
+
+func TestPassword(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "breached.txt")
+	// "password1" in clear, "qwertyuiop" as a HIBP line
+	content := "password1\r\nB0399D2029F64D445BD131FFAA399A42D2F8E7DC:3\n\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	list, err := LoadBreached(path)
+	require.NoError(t, err)
+	Breached = list
+	defer func() { Breached = BreachedList{} }()
+
+	tests := []struct {
+		password string
+		err      error
+	}{
+		{"correct horse battery", nil},
+		{"short", ErrPasswordTooShort},
+		{strings.Repeat("x", 73), ErrPasswordTooLong},
+		{"alice-rocks-2025", ErrPasswordPersonal},
+		{"password1", ErrPasswordBreached},
+		{"qwertyuiop", ErrPasswordBreached},
+	}
+	for _, tt := range tests {
+		assert.ErrorIs(t, Password(tt.password, "alice", "alice@example.com"), tt.err, tt.password)
+	}
+
+	_, err = LoadBreached(filepath.Join(dir, "missing.txt"))
+	assert.Error(t, err)
+}
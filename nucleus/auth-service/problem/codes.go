@@ -0,0 +1,15 @@
+package problem
+
+// The codes of the problems only auth-service answers with, more precise
+// than their status.
+const (
+	CodeInvalidCredentials    = "invalid_credentials"
+	CodeAccountDisabled       = "account_disabled"
+	CodePasswordResetRequired = "password_reset_required"
+	CodeLoginExpired          = "login_expired"
+	CodeInvalidCode           = "invalid_code"
+	CodeInvalidToken          = "invalid_token"
+	CodeTwoFactorRequired     = "two_factor_required"
+	CodeSessionRequired       = "session_required"
+	CodeExternalAccount       = "external_account"
+)
@@ -0,0 +1,60 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Talfaza/authentification/logging"
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: Handler})
+	logging.Register(app)
+	app.Get("/users/:id", func(c fiber.Ctx) error {
+		return New(fiber.StatusNotFound, "User not found")
+	})
+	app.Post("/login", func(c fiber.Ctx) error {
+		return New(fiber.StatusUnauthorized, "Invalid email or password").WithCode("invalid_credentials")
+	})
+	app.Post("/users", func(c fiber.Ctx) error {
+		return Invalid(FieldError{Field: "email", Message: "email is required"})
+	})
+	app.Get("/crash", func(c fiber.Ctx) error {
+		return errors.New("dial tcp 10.0.0.5:3306: connection refused")
+	})
+
+	tests := []struct {
+		name, method, path string
+		status             int
+		code, detail       string
+	}{
+		{"problem", "GET", "/users/7", fiber.StatusNotFound, CodeNotFound, "User not found"},
+		{"precise code", "POST", "/login", fiber.StatusUnauthorized, "invalid_credentials", "Invalid email or password"},
+		{"invalid", "POST", "/users", fiber.StatusUnprocessableEntity, CodeInvalid, "The request has invalid fields"},
+		{"unknown route", "GET", "/nowhere", fiber.StatusNotFound, CodeNotFound, "Cannot GET /nowhere"},
+		// The cause is logged, not answered
+		{"other error", "GET", "/crash", fiber.StatusInternalServerError, CodeInternal, "Internal server error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest(tt.method, tt.path, nil))
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, resp.StatusCode)
+			assert.Equal(t, ContentType, resp.Header.Get(fiber.HeaderContentType))
+
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, "about:blank", body["type"])
+			assert.Equal(t, float64(tt.status), body["status"])
+			assert.Equal(t, tt.code, body["code"])
+			assert.Equal(t, tt.detail, body["detail"])
+			assert.Equal(t, tt.path, body["instance"])
+			assert.Equal(t, resp.Header.Get(fiber.HeaderXRequestID), body["request_id"])
+		})
+	}
+}
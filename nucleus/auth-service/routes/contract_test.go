@@ -0,0 +1,297 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Talfaza/authentification/config"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gofiber/fiber/v3"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// contract makes requests to the app, failing the test when an answer does
+// not match the OpenAPI document the app serves, or a request the document
+// rejects is not rejected by the app too.
+type contract struct {
+	t      *testing.T
+	app    *fiber.App
+	doc    *openapi3.T
+	router routers.Router
+}
+
+func newContract(t *testing.T, app *fiber.App) *contract {
+	t.Helper()
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/openapi.json", nil), noTimeout)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	spec, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	doc, err := openapi3.NewLoader().LoadFromData(spec)
+	require.NoError(t, err)
+	require.NoError(t, doc.Validate(context.Background()))
+	router, err := gorillamux.NewRouter(doc)
+	require.NoError(t, err)
+	return &contract{t: t, app: app, doc: doc, router: router}
+}
+
+// call makes a JSON request with the session cookie, if any, and decodes
+// the JSON answer into out if it is not nil.
+func (c *contract) call(session *http.Cookie, method, path, body string, out interface{}) *http.Response {
+	c.t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if session != nil {
+		req.AddCookie(session)
+	}
+
+	route, params, err := c.router.FindRoute(req)
+	require.NoError(c.t, err, "%s %s is not documented", method, path)
+	input := &openapi3filter.RequestValidationInput{
+		Request:    req,
+		PathParams: params,
+		Route:      route,
+		Options:    &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+	}
+	invalid := openapi3filter.ValidateRequest(context.Background(), input)
+
+	// The validation consumed the body, and filled in defaults
+	req.Body = io.NopCloser(strings.NewReader(body))
+	req.ContentLength = int64(len(body))
+	resp, err := c.app.Test(req, noTimeout)
+	require.NoError(c.t, err)
+	answer, err := io.ReadAll(resp.Body)
+	require.NoError(c.t, err)
+	assert.NoError(c.t, openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 resp.StatusCode,
+		Header:                 resp.Header,
+		Body:                   io.NopCloser(bytes.NewReader(answer)),
+	}), "%s %s answered %d %s", method, path, resp.StatusCode, answer)
+	if invalid != nil {
+		assert.GreaterOrEqual(c.t, resp.StatusCode, fiber.StatusBadRequest, "%s %s accepted an invalid request: %v", method, path, invalid)
+		assert.Less(c.t, resp.StatusCode, fiber.StatusInternalServerError, "%s %s failed on an invalid request: %v", method, path, invalid)
+	}
+	if out != nil {
+		require.NoError(c.t, json.Unmarshal(answer, out))
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(answer))
+	return resp
+}
+
+var routeParam = regexp.MustCompile(`:(\w+)`)
+
+// TestRoutesDocumented checks every route is documented and every
+// documented operation is routed.
+func TestRoutesDocumented(t *testing.T) {
+	app := newTestApp(t)
+	c := newContract(t, app)
+
+	var routed []string
+	for _, route := range app.GetRoutes(true) {
+		if route.Method == fiber.MethodHead || route.Path == "/openapi.json" {
+			continue
+		}
+		path := routeParam.ReplaceAllString(route.Path, "{$1}")
+		if path != "/" {
+			path = strings.TrimSuffix(path, "/")
+		}
+		routed = append(routed, route.Method+" "+path)
+	}
+	var documented []string
+	for path, item := range c.doc.Paths.Map() {
+		for method := range item.Operations() {
+			documented = append(documented, method+" "+path)
+		}
+	}
+	sort.Strings(routed)
+	sort.Strings(documented)
+	assert.Equal(t, documented, routed)
+}
+
+func TestContract(t *testing.T) {
+	c := newContract(t, newTestApp(t))
+
+	// Deleting a user deletes their data in prox-service and lxc-service
+	// first, through the generated client
+	var deletions []string
+	services := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deletions = append(deletions, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"deleted":0}`))
+	}))
+	defer services.Close()
+	config.Default.ProxServiceURL = services.URL
+	config.Default.LXCServiceURL = services.URL
+
+	resp := c.call(nil, "GET", "/.well-known/jwks.json", "", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(nil, "GET", "/auth/oidc", "", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(nil, "GET", "/auth/oidc/login", "", nil)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	resp = c.call(nil, "GET", "/auth/sessions/revoked", "", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	// Accounts and sessions
+	resp = c.call(nil, "POST", "/auth/register", `{"username":"alice","email":"alice@lab.local","password":"correct horse battery"}`, nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(nil, "POST", "/auth/register", `{"username":"bob","email":"bob@lab.local","password":"correct horse battery"}`, nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(nil, "POST", "/auth/register", `{"username":"alice","email":"alice@lab.local","password":"correct horse battery"}`, nil)
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+	resp = c.call(nil, "POST", "/auth/register", `{"username":"carol"}`, nil)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+	resp = c.call(nil, "GET", "/auth/mailcheck?email=bob@lab.local", "", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	resp = c.call(nil, "POST", "/auth/login", `{"email":"alice@lab.local","password":"wrong horse battery"}`, nil)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	resp = c.call(nil, "POST", "/auth/login", `{"email":"alice@lab.local","password":"correct horse battery"}`, nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	alice := sessionCookie(resp)
+	require.NotNil(t, alice)
+	resp = c.call(nil, "POST", "/auth/login", `{"email":"bob@lab.local","password":"correct horse battery"}`, nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	bob := sessionCookie(resp)
+	require.NotNil(t, bob)
+
+	resp = c.call(alice, "GET", "/auth/verify", "", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(nil, "GET", "/auth/verify", "", nil)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	resp = c.call(alice, "POST", "/auth/email/resend", "", nil)
+	assert.Equal(t, fiber.StatusAccepted, resp.StatusCode)
+	resp = c.call(nil, "POST", "/auth/email/verify", `{"token":"nope"}`, nil)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	resp = c.call(nil, "POST", "/auth/password/forgot", `{"email":"bob@lab.local"}`, nil)
+	assert.Equal(t, fiber.StatusAccepted, resp.StatusCode)
+	resp = c.call(nil, "POST", "/auth/password/reset", `{"token":"nope","password":"correct horse battery staple"}`, nil)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	resp = c.call(alice, "POST", "/auth/password/change", `{"current_password":"correct horse battery","new_password":"correct horse battery staple"}`, nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	// Personal access tokens
+	var created struct {
+		Token string `json:"token"`
+	}
+	resp = c.call(alice, "POST", "/auth/tokens", `{"name":"ci","scopes":["read"]}`, &created)
+	require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	resp = c.call(alice, "POST", "/auth/tokens", `{"name":"ci","scopes":[]}`, nil)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+	resp = c.call(alice, "GET", "/auth/tokens", "", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(nil, "POST", "/auth/tokens/introspect", `{"token":"`+created.Token+`"}`, nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(nil, "POST", "/auth/tokens/introspect", `{"token":"nuc_nope"}`, nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(alice, "DELETE", "/auth/tokens/1", "", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	// Organizations
+	resp = c.call(alice, "POST", "/auth/orgs", `{"name":"lab"}`, nil)
+	require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	resp = c.call(alice, "GET", "/auth/orgs", "", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var invited struct {
+		Token string `json:"token"`
+	}
+	resp = c.call(alice, "POST", "/auth/orgs/1/invitations", `{"email":"bob@lab.local","permission":"read"}`, &invited)
+	require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	resp = c.call(bob, "POST", "/auth/orgs/1/invitations", `{"email":"carol@lab.local"}`, nil)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	resp = c.call(bob, "POST", "/auth/invitations/accept", `{"token":"`+invited.Token+`"}`, nil)
+	require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	resp = c.call(alice, "GET", "/auth/orgs/1/members", "", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(alice, "PUT", "/auth/orgs/1/members/2", `{"permission":"write"}`, nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(alice, "PUT", "/auth/orgs/1/members/2", `{"permission":"owner"}`, nil)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+	resp = c.call(alice, "DELETE", "/auth/orgs/1/members/2", "", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	// Removing bob ended his sessions
+	resp = c.call(nil, "POST", "/auth/login", `{"email":"bob@lab.local","password":"correct horse battery"}`, nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	bob = sessionCookie(resp)
+
+	// Administration
+	resp = c.call(alice, "GET", "/auth/admin/users?q=bob", "", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(bob, "GET", "/auth/admin/users", "", nil)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	resp = c.call(alice, "GET", "/auth/admin/users/2", "", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(alice, "GET", "/auth/admin/users/99", "", nil)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	resp = c.call(alice, "PUT", "/auth/admin/users/2/roles", `{"roles":["viewer"]}`, nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(alice, "POST", "/auth/admin/users/2/disable", "", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(alice, "POST", "/auth/admin/users/2/enable", "", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(alice, "POST", "/auth/admin/users/2/password-reset", "", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(alice, "PUT", "/auth/admin/users/2/2fa", "", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(alice, "DELETE", "/auth/admin/users/2/2fa", "", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(alice, "POST", "/auth/admin/users/2/unlock", "", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(alice, "GET", "/auth/admin/lockouts", "", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(alice, "DELETE", "/auth/admin/users/2", "", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"DELETE /admin/users/2", "DELETE /admin/users/2"}, deletions)
+
+	// Two-factor authentication
+	var setup struct {
+		Secret string `json:"secret"`
+	}
+	resp = c.call(alice, "POST", "/auth/2fa/setup", "", &setup)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	code, err := totp.GenerateCode(setup.Secret, time.Now())
+	require.NoError(t, err)
+	var enabled struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	resp = c.call(alice, "POST", "/auth/2fa/verify", `{"code":"`+code+`"}`, &enabled)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	require.NotEmpty(t, enabled.RecoveryCodes)
+	resp = c.call(alice, "POST", "/auth/2fa/disable", `{"code":"not a code"}`, nil)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	resp = c.call(alice, "GET", "/auth/logout", "", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var login struct {
+		MFAToken string `json:"mfa_token"`
+	}
+	resp = c.call(nil, "POST", "/auth/login", `{"email":"alice@lab.local","password":"correct horse battery staple"}`, &login)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(nil, "POST", "/auth/2fa/login", `{"mfa_token":"`+login.MFAToken+`","code":"not a code"}`, nil)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	// The code used to enable two-factor authentication cannot be replayed
+	resp = c.call(nil, "POST", "/auth/2fa/login", `{"mfa_token":"`+login.MFAToken+`","code":"`+code+`"}`, nil)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	resp = c.call(nil, "POST", "/auth/2fa/login", `{"mfa_token":"`+login.MFAToken+`","recovery_code":"`+enabled.RecoveryCodes[0]+`"}`, nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
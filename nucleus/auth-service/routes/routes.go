@@ -1,16 +1,93 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/Talfaza/authentification/controller"
+	"github.com/Talfaza/authentification/middleware"
+	"github.com/Talfaza/authentification/models"
+	"github.com/Talfaza/authentification/problem"
 	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/limiter"
 )
 
+// Setup registers the API, as documented in api/openapi.yaml.
 func Setup(app *fiber.App) {
+	app.Get("/.well-known/jwks.json", controller.JWKS)
+
 	auth := app.Group("/auth")
 
-	auth.Post("/register", controller.Register)
+	// Endpoints that reveal whether an account exists or send it mail are
+	// limited per IP. Failed logins are throttled by the login handlers.
+	lookupLimit := limiter.New(limiter.Config{
+		Max:        10,
+		Expiration: time.Minute,
+		LimitReached: func(c fiber.Ctx) error {
+			return problem.New(fiber.StatusTooManyRequests, "Too many requests, try again later")
+		},
+	})
+
+	auth.Post("/register", controller.Register, lookupLimit)
 	auth.Post("/login", controller.Login)
 	auth.Get("/logout", controller.Logout)
 	auth.Get("/verify", controller.Verify)
-	auth.Get("/mailcheck", controller.MailCheck)
+	auth.Get("/mailcheck", controller.MailCheck, lookupLimit)
+
+	auth.Post("/email/verify", controller.VerifyEmail)
+	auth.Post("/email/resend", controller.ResendVerification, middleware.AuthRequired, middleware.RequireSession)
+	auth.Post("/password/forgot", controller.ForgotPassword, lookupLimit)
+	auth.Post("/password/reset", controller.ResetPassword)
+	auth.Post("/password/change", controller.ChangePassword, middleware.AuthRequired, middleware.RequireSession)
+
+	// Polled by the other services to reject revoked sessions
+	auth.Get("/sessions/revoked", controller.RevokedSessions)
+
+	// Single sign-on through an OpenID Connect provider
+	auth.Get("/oidc", controller.OIDCConfig)
+	auth.Get("/oidc/login", controller.OIDCLogin)
+	auth.Get("/oidc/callback", controller.OIDCCallback)
+
+	// Second login step. Setup and verify also accept the enrollment token
+	// handed out at login when 2FA is required but not set up yet.
+	auth.Post("/2fa/login", controller.TwoFactorLogin)
+	auth.Post("/2fa/setup", controller.TwoFactorSetup, middleware.AuthOrEnrollment, middleware.RequireSession)
+	auth.Post("/2fa/verify", controller.TwoFactorVerify, middleware.AuthOrEnrollment, middleware.RequireSession)
+	auth.Post("/2fa/disable", controller.TwoFactorDisable, middleware.AuthRequired, middleware.RequireSession)
+
+	// Personal access tokens can only be managed from a login session.
+	// Introspection is authenticated by the token being introspected.
+	auth.Post("/tokens/introspect", controller.IntrospectAPIToken)
+	tokens := auth.Group("/tokens", middleware.AuthRequired, middleware.RequireSession)
+	tokens.Post("/", controller.CreateAPIToken)
+	tokens.Get("/", controller.ListAPITokens)
+	tokens.Delete("/:id", controller.RevokeAPIToken)
+
+	read := middleware.RequireScope(models.ScopeRead)
+	write := middleware.RequireScope(models.ScopeWrite)
+
+	// Creating, leaving and joining an organization refresh the session,
+	// which a personal access token must not be turned into.
+	orgs := auth.Group("/orgs", middleware.AuthRequired)
+	orgs.Post("/", controller.CreateOrganization, middleware.RequireSession)
+	orgs.Get("/", controller.ListOrganizations, read)
+	orgs.Get("/:id/members", controller.ListMembers, read)
+	orgs.Put("/:id/members/:userID", controller.UpdateMember, write)
+	orgs.Delete("/:id/members/:userID", controller.RemoveMember, middleware.RequireSession)
+	orgs.Post("/:id/invitations", controller.CreateInvitation, write)
+
+	invitations := auth.Group("/invitations", middleware.AuthRequired, middleware.RequireSession)
+	invitations.Post("/accept", controller.AcceptInvitation)
+
+	admin := auth.Group("/admin", middleware.AuthRequired, middleware.RequireSession, middleware.RequireRole(models.RoleAdmin))
+	admin.Get("/users", controller.ListUsers)
+	admin.Get("/users/:id", controller.GetUser)
+	admin.Delete("/users/:id", controller.DeleteUser)
+	admin.Post("/users/:id/disable", controller.DisableUser)
+	admin.Post("/users/:id/enable", controller.EnableUser)
+	admin.Post("/users/:id/password-reset", controller.ForcePasswordReset)
+	admin.Put("/users/:id/roles", controller.SetUserRoles)
+	admin.Put("/users/:id/2fa", controller.RequireTwoFactor)
+	admin.Delete("/users/:id/2fa", controller.ResetTwoFactor)
+	admin.Post("/users/:id/unlock", controller.UnlockUser)
+	admin.Get("/lockouts", controller.ListLockouts)
 }
@@ -0,0 +1,451 @@
+package routes
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Talfaza/authentification/api"
+	"github.com/Talfaza/authentification/authn"
+	"github.com/Talfaza/authentification/config"
+	"github.com/Talfaza/authentification/database"
+	"github.com/Talfaza/authentification/keys"
+	"github.com/Talfaza/authentification/metrics"
+	"github.com/Talfaza/authentification/models"
+	"github.com/Talfaza/authentification/problem"
+	"github.com/Talfaza/authentification/tracing"
+	"github.com/gofiber/fiber/v3"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newTestApp serves the API and its document from an in-memory SQLite
+// database.
+func newTestApp(t *testing.T) *fiber.App {
+	t.Helper()
+
+	db, err := database.Open("sqlite://:memory:")
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(db))
+	database.DB = db
+
+	_, private, _ := ed25519.GenerateKey(rand.Reader)
+	key, _ := keys.NewKey("test", private)
+	keys.Default = keys.NewKeySet(key)
+	authn.Default = &authn.Local{DB: db}
+
+	cfg := config.Defaults()
+	// The cheapest cost allowed keeps the tests fast
+	cfg.BcryptCost = 10
+	config.Default = cfg
+
+	app := fiber.New(fiber.Config{ErrorHandler: problem.Handler})
+	api.Register(app)
+	Setup(app)
+	return app
+}
+
+// noTimeout lets app.Test wait for answers as long as they take: hashing
+// passwords takes longer than its default second under the race detector.
+var noTimeout = fiber.TestConfig{Timeout: 0}
+
+// call makes a JSON request and decodes the JSON answer into out if it is
+// not nil.
+func call(t *testing.T, app *fiber.App, method, path, body string, cookie *http.Cookie, out interface{}) *http.Response {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	resp, err := app.Test(req, noTimeout)
+	require.NoError(t, err)
+	if out != nil {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+	}
+	return resp
+}
+
+func sessionCookie(resp *http.Response) *http.Cookie {
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "jwt" && cookie.Value != "" {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func TestSessionLifecycle(t *testing.T) {
+	app := newTestApp(t)
+
+	resp := call(t, app, "POST", "/auth/register", `{"username":"alice","email":"alice@lab.local","password":"correct horse battery"}`, nil, nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = call(t, app, "POST", "/auth/register", `{"username":"alice2","email":"Alice@lab.local","password":"correct horse battery"}`, nil, nil)
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+
+	failures := metrics.Logins.WithLabelValues(metrics.LoginPassword, metrics.LoginFailure)
+	successes := metrics.Logins.WithLabelValues(metrics.LoginPassword, metrics.LoginSuccess)
+	failed, succeeded := testutil.ToFloat64(failures), testutil.ToFloat64(successes)
+
+	resp = call(t, app, "POST", "/auth/login", `{"email":"alice@lab.local","password":"wrong horse battery"}`, nil, nil)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+
+	resp = call(t, app, "POST", "/auth/login", `{"email":"alice@lab.local","password":"correct horse battery"}`, nil, nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, failed+1, testutil.ToFloat64(failures))
+	assert.Equal(t, succeeded+1, testutil.ToFloat64(successes))
+	session := sessionCookie(resp)
+	require.NotNil(t, session)
+
+	var verified struct {
+		User struct {
+			Username string   `json:"username"`
+			Roles    []string `json:"roles"`
+		} `json:"user"`
+	}
+	resp = call(t, app, "GET", "/auth/verify", "", session, &verified)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "alice", verified.User.Username)
+	// The first account administers the instance
+	assert.Equal(t, []string{"admin"}, verified.User.Roles)
+
+	// Logging out revokes the session, not just the cookie
+	call(t, app, "GET", "/auth/logout", "", session, nil)
+	resp = call(t, app, "GET", "/auth/verify", "", session, nil)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestListUsers(t *testing.T) {
+	app := newTestApp(t)
+
+	for _, name := range []string{"alice", "bob", "bobby_tables"} {
+		resp := call(t, app, "POST", "/auth/register", `{"username":"`+name+`","email":"`+name+`@lab.local","password":"correct horse battery"}`, nil, nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	}
+	resp := call(t, app, "POST", "/auth/login", `{"email":"alice@lab.local","password":"correct horse battery"}`, nil, nil)
+	admin := sessionCookie(resp)
+	require.NotNil(t, admin)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"alice", "bob", "bobby_tables"}},
+		{"?q=BOB", []string{"bob", "bobby_tables"}},
+		// _ is not a wildcard
+		{"?q=b_b", nil},
+		{"?q=y_t", []string{"bobby_tables"}},
+		{"?per_page=1&page=2", []string{"bob"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			var page struct {
+				Users []struct {
+					Username string `json:"username"`
+				} `json:"users"`
+			}
+			resp := call(t, app, "GET", "/auth/admin/users"+tt.query, "", admin, &page)
+			require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+			var got []string
+			for _, user := range page.Users {
+				got = append(got, user.Username)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	// Operators are not allowed in
+	resp = call(t, app, "POST", "/auth/login", `{"email":"bob@lab.local","password":"correct horse battery"}`, nil, nil)
+	resp = call(t, app, "GET", "/auth/admin/users", "", sessionCookie(resp), nil)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+}
+
+func TestValidation(t *testing.T) {
+	app := newTestApp(t)
+
+	type fieldErrors struct {
+		Errors []struct {
+			Field   string `json:"field"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	messages := func(body fieldErrors) map[string]string {
+		got := map[string]string{}
+		for _, fe := range body.Errors {
+			got[fe.Field] = fe.Message
+		}
+		return got
+	}
+
+	var invalid fieldErrors
+	resp := call(t, app, "POST", "/auth/register", `{"username":"alice"}`, nil, &invalid)
+	require.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, map[string]string{"email": "email is required", "password": "password is required"}, messages(invalid))
+
+	// The password policy answers for its field too
+	invalid = fieldErrors{}
+	resp = call(t, app, "POST", "/auth/register", `{"username":"alice","email":"alice@lab.local","password":"short"}`, nil, &invalid)
+	require.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Contains(t, messages(invalid), "password")
+
+	resp = call(t, app, "POST", "/auth/register", `{"username":"alice","email":"alice@lab.local","password":"correct horse battery"}`, nil, nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = call(t, app, "POST", "/auth/login", `{"email":"alice@lab.local","password":"correct horse battery"}`, nil, nil)
+	session := sessionCookie(resp)
+	require.NotNil(t, session)
+
+	invalid = fieldErrors{}
+	resp = call(t, app, "POST", "/auth/tokens/", `{"name":"ci","scopes":["read","everything"],"expires_in_days":400}`, session, &invalid)
+	require.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+	got := messages(invalid)
+	assert.Contains(t, got["scopes[1]"], "scopes[1] must be one of")
+	assert.Equal(t, "expires_in_days must be at most 365", got["expires_in_days"])
+
+	invalid = fieldErrors{}
+	resp = call(t, app, "POST", "/auth/tokens/", `{"name":"ci","scopes":[]}`, session, &invalid)
+	require.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, map[string]string{"scopes": "scopes must not be empty"}, messages(invalid))
+}
+
+// A personal access token cannot be traded for a session through the
+// organization endpoints that refresh it.
+func TestOrganizationsRequireSession(t *testing.T) {
+	app := newTestApp(t)
+
+	resp := call(t, app, "POST", "/auth/register", `{"username":"alice","email":"alice@lab.local","password":"correct horse battery"}`, nil, nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = call(t, app, "POST", "/auth/login", `{"email":"alice@lab.local","password":"correct horse battery"}`, nil, nil)
+	session := sessionCookie(resp)
+	require.NotNil(t, session)
+
+	var created struct {
+		Token string `json:"token"`
+	}
+	resp = call(t, app, "POST", "/auth/tokens/", `{"name":"ci","scopes":["read","write"]}`, session, &created)
+	require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	resp = call(t, app, "POST", "/auth/orgs/", `{"name":"lab"}`, session, nil)
+	require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	for _, tt := range []struct{ method, path, body string }{
+		{"POST", "/auth/orgs/", `{"name":"other"}`},
+		{"DELETE", "/auth/orgs/1/members/1", ""},
+		{"POST", "/auth/invitations/accept", `{"token":"nope"}`},
+	} {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+created.Token)
+		resp, err := app.Test(req, noTimeout)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode, "%s %s", tt.method, tt.path)
+		assert.Nil(t, sessionCookie(resp), "%s %s", tt.method, tt.path)
+	}
+
+	// The token still reads and administers the organization
+	req := httptest.NewRequest("GET", "/auth/orgs/", nil)
+	req.Header.Set("Authorization", "Bearer "+created.Token)
+	resp, err := app.Test(req, noTimeout)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
+
+// Only one of the accounts registering at once on a new instance becomes
+// its admin.
+func TestFirstAccountAdmin(t *testing.T) {
+	app := newTestApp(t)
+
+	var wg sync.WaitGroup
+	for i := range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name := "user" + strconv.Itoa(i)
+			req := httptest.NewRequest("POST", "/auth/register", strings.NewReader(`{"username":"`+name+`","email":"`+name+`@lab.local","password":"correct horse battery"}`))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req, noTimeout)
+			if assert.NoError(t, err) {
+				assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var admins int64
+	err := database.DB.Table("user_roles").
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Where("roles.name = ?", "admin").Count(&admins).Error
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), admins)
+}
+
+// Members who lose permissions in an organization lose the sessions that
+// carry them.
+func TestMemberSessionsRevoked(t *testing.T) {
+	app := newTestApp(t)
+
+	login := func(name string) *http.Cookie {
+		t.Helper()
+		resp := call(t, app, "POST", "/auth/login", `{"email":"`+name+`@lab.local","password":"correct horse battery"}`, nil, nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		return sessionCookie(resp)
+	}
+	for _, name := range []string{"alice", "bob"} {
+		resp := call(t, app, "POST", "/auth/register", `{"username":"`+name+`","email":"`+name+`@lab.local","password":"correct horse battery"}`, nil, nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	}
+	alice, bob := login("alice"), login("bob")
+
+	resp := call(t, app, "POST", "/auth/orgs/", `{"name":"lab"}`, alice, nil)
+	require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	alice = sessionCookie(resp)
+	var invited struct {
+		Token string `json:"token"`
+	}
+	resp = call(t, app, "POST", "/auth/orgs/1/invitations", `{"email":"bob@lab.local","permission":"read"}`, alice, &invited)
+	require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	resp = call(t, app, "POST", "/auth/invitations/accept", `{"token":"`+invited.Token+`"}`, bob, nil)
+	require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	bob = sessionCookie(resp)
+
+	// An upgrade shows on the next login
+	resp = call(t, app, "PUT", "/auth/orgs/1/members/2", `{"permission":"write"}`, alice, nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = call(t, app, "GET", "/auth/verify", "", bob, nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	resp = call(t, app, "PUT", "/auth/orgs/1/members/2", `{"permission":"read"}`, alice, nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = call(t, app, "GET", "/auth/verify", "", bob, nil)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+
+	bob = login("bob")
+	resp = call(t, app, "DELETE", "/auth/orgs/1/members/2", "", alice, nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = call(t, app, "GET", "/auth/verify", "", bob, nil)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	resp = call(t, app, "GET", "/auth/verify", "", alice, nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
+
+// Accounts from a directory or an SSO provider cannot get a local password,
+// which would let them in after being removed there.
+func TestExternalAccounts(t *testing.T) {
+	app := newTestApp(t)
+
+	resp := call(t, app, "POST", "/auth/register", `{"username":"alice","email":"alice@lab.local","password":"correct horse battery"}`, nil, nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = call(t, app, "POST", "/auth/login", `{"email":"alice@lab.local","password":"correct horse battery"}`, nil, nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	alice := sessionCookie(resp)
+	dave, err := authn.ProvisionUser(database.DB, models.SourceLDAP, "dave", "dave@lab.local")
+	require.NoError(t, err)
+
+	// Only the local account is mailed a link
+	tokens := func(userID uint) int64 {
+		var count int64
+		database.DB.Model(&models.UserToken{}).Where("user_id = ? AND purpose = ?", userID, models.PurposeResetPassword).Count(&count)
+		return count
+	}
+	for _, email := range []string{"alice@lab.local", "dave@lab.local"} {
+		resp = call(t, app, "POST", "/auth/password/forgot", `{"email":"`+email+`"}`, nil, nil)
+		assert.Equal(t, fiber.StatusAccepted, resp.StatusCode)
+	}
+	assert.Equal(t, int64(1), tokens(1))
+	assert.Equal(t, int64(0), tokens(dave.ID))
+
+	// A link mailed before the account was known to be external is refused
+	sum := sha256.Sum256([]byte("old-link"))
+	require.NoError(t, database.DB.Create(&models.UserToken{
+		UserID:    dave.ID,
+		Purpose:   models.PurposeResetPassword,
+		TokenHash: hex.EncodeToString(sum[:]),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}).Error)
+	var problem map[string]interface{}
+	resp = call(t, app, "POST", "/auth/password/reset", `{"token":"old-link","password":"another horse battery"}`, nil, &problem)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, "external_account", problem["code"])
+	resp = call(t, app, "POST", "/auth/admin/users/"+strconv.Itoa(int(dave.ID))+"/password-reset", "", alice, nil)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+
+	// A password set before is not accepted either
+	hash, err := bcrypt.GenerateFromPassword([]byte("another horse battery"), config.Default.BcryptCost)
+	require.NoError(t, err)
+	require.NoError(t, database.DB.Model(dave).Update("password", string(hash)).Error)
+	resp = call(t, app, "POST", "/auth/login", `{"email":"dave@lab.local","password":"another horse battery"}`, nil, nil)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+}
+
+// recorded keeps the spans of the tests in memory. The tracer of the
+// tracing package only follows the first provider set, so it is set once.
+var recorded = struct {
+	once     sync.Once
+	exporter *tracetest.InMemoryExporter
+}{exporter: tracetest.NewInMemoryExporter()}
+
+// The queries made for a request are traced under its span.
+func TestQuerySpans(t *testing.T) {
+	recorded.once.Do(func() {
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(recorded.exporter)))
+	})
+	newTestApp(t)
+	app := fiber.New(fiber.Config{ErrorHandler: problem.Handler})
+	tracing.Register(app)
+	Setup(app)
+
+	resp := call(t, app, "POST", "/auth/register", `{"username":"alice","email":"alice@lab.local","password":"correct horse battery"}`, nil, nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	recorded.exporter.Reset()
+	resp = call(t, app, "POST", "/auth/login", `{"email":"alice@lab.local","password":"correct horse battery"}`, nil, nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	spans := recorded.exporter.GetSpans()
+	var request tracetest.SpanStub
+	for _, span := range spans {
+		if span.Name == "POST /auth/login" {
+			request = span
+		}
+	}
+	require.True(t, request.SpanContext.IsValid(), "no span for the request")
+	// Preloads are traced under the query they complete
+	parents := map[trace.SpanID]trace.SpanID{}
+	for _, span := range spans {
+		parents[span.SpanContext.SpanID()] = span.Parent.SpanID()
+	}
+	under := func(id trace.SpanID) bool {
+		for ; id.IsValid(); id = parents[id] {
+			if id == request.SpanContext.SpanID() {
+				return true
+			}
+		}
+		return false
+	}
+	var queries []string
+	for _, span := range spans {
+		if strings.HasPrefix(span.Name, "gorm.") {
+			assert.True(t, under(span.Parent.SpanID()), "%s is not under the request span", span.Name)
+			queries = append(queries, span.Name)
+		}
+	}
+	// Looking the user up, starting the session and recording the login
+	assert.Contains(t, queries, "gorm.query")
+	assert.Contains(t, queries, "gorm.create")
+	assert.Contains(t, queries, "gorm.update")
+}
@@ -0,0 +1,227 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Talfaza/authentification/config"
+	"github.com/Talfaza/authentification/logging"
+	"github.com/Talfaza/authentification/models"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+var ErrNonceMismatch = errors.New("ID token nonce does not match")
+
+// Config describes an OpenID Connect provider and how its users map to
+// Nucleus roles.
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+
+	// GroupsClaim names the ID token claim listing the user's groups.
+	GroupsClaim string
+	// GroupRoles maps provider groups to Nucleus roles. When it is set,
+	// roles are synced from groups on every login and users in no mapped
+	// group get DefaultRole.
+	GroupRoles  map[string]string
+	DefaultRole string
+}
+
+// Provider runs the authorization code flow with PKCE against an OpenID
+// Connect provider.
+type Provider struct {
+	config   Config
+	oauth2   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// Identity is what the provider asserts about a user.
+type Identity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string
+	Groups        []string
+}
+
+// Default is the configured provider, or nil when SSO is disabled.
+var Default *Provider
+
+// SuccessURL is where the browser is sent after an SSO login.
+var SuccessURL = "http://localhost:3000/create-server"
+
+// Load sets up Default from the oidc_* settings of cfg. SSO is disabled
+// unless an issuer is set.
+func Load(cfg *config.Config) {
+	if cfg.OIDCSuccessURL != "" {
+		SuccessURL = cfg.OIDCSuccessURL
+	} else {
+		SuccessURL = strings.TrimRight(cfg.AppURL, "/") + "/create-server"
+	}
+
+	if cfg.OIDCIssuer == "" {
+		return
+	}
+
+	groupRoles, err := ParseGroupRoles(cfg.OIDCGroupRoles)
+	if err != nil {
+		logging.Fatal("Invalid OIDC group roles", "error", err)
+	}
+
+	config := Config{
+		Issuer:       cfg.OIDCIssuer,
+		ClientID:     cfg.OIDCClientID,
+		ClientSecret: cfg.OIDCClientSecret,
+		RedirectURL:  cfg.OIDCRedirectURL,
+		Scopes:       cfg.OIDCScopes,
+		GroupsClaim:  cfg.OIDCGroupsClaim,
+		GroupRoles:   groupRoles,
+		DefaultRole:  cfg.OIDCDefaultRole,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	provider, err := New(ctx, config)
+	if err != nil {
+		logging.Fatal("Failed to set up OIDC provider", "error", err)
+	}
+	Default = provider
+}
+
+// New discovers the provider at config.Issuer.
+func New(ctx context.Context, config Config) (*Provider, error) {
+	if config.ClientID == "" {
+		return nil, errors.New("client ID is required")
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+	}
+	if !slices.Contains(config.Scopes, oidc.ScopeOpenID) {
+		config.Scopes = append([]string{oidc.ScopeOpenID}, config.Scopes...)
+	}
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = "groups"
+	}
+	if config.DefaultRole == "" {
+		config.DefaultRole = models.RoleViewer
+	}
+	if !slices.Contains(models.Roles, config.DefaultRole) {
+		return nil, fmt.Errorf("unknown default role %q", config.DefaultRole)
+	}
+
+	provider, err := oidc.NewProvider(ctx, config.Issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Provider{
+		config: config,
+		oauth2: oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			RedirectURL:  config.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       config.Scopes,
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: config.ClientID}),
+	}, nil
+}
+
+// ParseGroupRoles parses "group=role" pairs separated by commas.
+func ParseGroupRoles(s string) (map[string]string, error) {
+	groupRoles := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		group, role, ok := strings.Cut(pair, "=")
+		group, role = strings.TrimSpace(group), strings.TrimSpace(role)
+		if !ok || group == "" {
+			return nil, fmt.Errorf("expected group=role, got %q", pair)
+		}
+		if !slices.Contains(models.Roles, role) {
+			return nil, fmt.Errorf("unknown role %q for group %q", role, group)
+		}
+		groupRoles[group] = role
+	}
+	return groupRoles, nil
+}
+
+// AuthCodeURL returns the provider URL to send the browser to. state,
+// nonce and verifier must be random and kept by the client until the
+// callback.
+func (p *Provider) AuthCodeURL(state, nonce, verifier string) string {
+	return p.oauth2.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+}
+
+// Exchange redeems the authorization code and verifies the ID token it
+// returns.
+func (p *Provider) Exchange(ctx context.Context, code, nonce, verifier string) (*Identity, error) {
+	token, err := p.oauth2.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code: %w", err)
+	}
+
+	raw, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("no ID token in token response")
+	}
+	idToken, err := p.verifier.Verify(ctx, raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, ErrNonceMismatch
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("invalid ID token claims: %w", err)
+	}
+
+	identity := &Identity{Issuer: idToken.Issuer, Subject: idToken.Subject}
+	identity.Email, _ = claims["email"].(string)
+	identity.EmailVerified, _ = claims["email_verified"].(bool)
+	identity.Username, _ = claims["preferred_username"].(string)
+	switch groups := claims[p.config.GroupsClaim].(type) {
+	case []interface{}:
+		for _, group := range groups {
+			if name, ok := group.(string); ok {
+				identity.Groups = append(identity.Groups, name)
+			}
+		}
+	case string:
+		identity.Groups = []string{groups}
+	}
+	return identity, nil
+}
+
+// SyncsRoles reports whether roles are managed by the provider.
+func (p *Provider) SyncsRoles() bool {
+	return len(p.config.GroupRoles) > 0
+}
+
+// Roles maps groups to Nucleus roles, falling back to the default role.
+func (p *Provider) Roles(groups []string) []string {
+	var roles []string
+	for _, group := range groups {
+		if role, ok := p.config.GroupRoles[group]; ok && !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+	if len(roles) == 0 {
+		roles = []string{p.config.DefaultRole}
+	}
+	slices.Sort(roles)
+	return roles
+}
@@ -0,0 +1,186 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockProvider is a minimal OpenID Connect provider. Codes are registered
+// directly instead of going through a login page.
+type mockProvider struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]mockCode
+}
+
+type mockCode struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	m := &mockProvider{key: key, codes: map[string]mockCode{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                m.URL,
+			"authorization_endpoint":                m.URL + "/authorize",
+			"token_endpoint":                        m.URL + "/token",
+			"jwks_uri":                              m.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "mock",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		m.mu.Lock()
+		code, ok := m.codes[r.PostForm.Get("code")]
+		delete(m.codes, r.PostForm.Get("code"))
+		m.mu.Unlock()
+
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != code.challenge {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, code.claims)
+		token.Header["kid"] = "mock"
+		idToken, _ := token.SignedString(key)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     idToken,
+		})
+	})
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+// authorize plays the provider's login page: it issues a code for the
+// auth URL with the given user claims.
+func (m *mockProvider) authorize(t *testing.T, authURL string, claims jwt.MapClaims) (code, state string) {
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	q := u.Query()
+	require.Equal(t, "S256", q.Get("code_challenge_method"))
+
+	now := time.Now()
+	full := jwt.MapClaims{
+		"iss":   m.URL,
+		"aud":   q.Get("client_id"),
+		"nonce": q.Get("nonce"),
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}
+	for k, v := range claims {
+		full[k] = v
+	}
+
+	code = "code-" + q.Get("state")
+	m.mu.Lock()
+	m.codes[code] = mockCode{challenge: q.Get("code_challenge"), claims: full}
+	m.mu.Unlock()
+	return code, q.Get("state")
+}
+
+func TestProvider(t *testing.T) {
+	mock := newMockProvider(t)
+	ctx := context.Background()
+
+	provider, err := New(ctx, Config{
+		Issuer:      mock.URL,
+		ClientID:    "nucleus",
+		RedirectURL: "http://localhost:9872/auth/oidc/callback",
+		GroupRoles:  map[string]string{"platform": "admin", "sre": "operator"},
+	})
+	require.NoError(t, err)
+
+	alice := jwt.MapClaims{
+		"sub":                "alice-id",
+		"email":              "alice@example.com",
+		"email_verified":     true,
+		"preferred_username": "alice",
+		"groups":             []string{"sre", "everyone"},
+	}
+
+	t.Run("login", func(t *testing.T) {
+		code, state := mock.authorize(t, provider.AuthCodeURL("state1", "nonce1", "verifier-1-0123456789012345678901234567890"), alice)
+		assert.Equal(t, "state1", state)
+
+		identity, err := provider.Exchange(ctx, code, "nonce1", "verifier-1-0123456789012345678901234567890")
+		require.NoError(t, err)
+		assert.Equal(t, &Identity{
+			Issuer:        mock.URL,
+			Subject:       "alice-id",
+			Email:         "alice@example.com",
+			EmailVerified: true,
+			Username:      "alice",
+			Groups:        []string{"sre", "everyone"},
+		}, identity)
+		assert.Equal(t, []string{"operator"}, provider.Roles(identity.Groups))
+	})
+
+	t.Run("wrong verifier", func(t *testing.T) {
+		code, _ := mock.authorize(t, provider.AuthCodeURL("state2", "nonce2", "verifier-2-0123456789012345678901234567890"), alice)
+		_, err := provider.Exchange(ctx, code, "nonce2", "verifier-x-0123456789012345678901234567890")
+		assert.Error(t, err)
+	})
+
+	t.Run("wrong nonce", func(t *testing.T) {
+		code, _ := mock.authorize(t, provider.AuthCodeURL("state3", "nonce3", "verifier-3-0123456789012345678901234567890"), alice)
+		_, err := provider.Exchange(ctx, code, "other", "verifier-3-0123456789012345678901234567890")
+		assert.ErrorIs(t, err, ErrNonceMismatch)
+	})
+
+	t.Run("roles", func(t *testing.T) {
+		assert.Equal(t, []string{"admin", "operator"}, provider.Roles([]string{"sre", "platform"}))
+		assert.Equal(t, []string{"viewer"}, provider.Roles(nil))
+		assert.True(t, provider.SyncsRoles())
+	})
+}
+
+func TestParseGroupRoles(t *testing.T) {
+	roles, err := ParseGroupRoles(" platform=admin, sre = operator ,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"platform": "admin", "sre": "operator"}, roles)
+
+	_, err = ParseGroupRoles("platform")
+	assert.Error(t, err)
+	_, err = ParseGroupRoles("platform=root")
+	assert.Error(t, err)
+}
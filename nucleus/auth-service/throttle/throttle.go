@@ -0,0 +1,115 @@
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+// Throttle counts failed attempts per key (an IP address, an account) and
+// locks the key out once MaxFailures is reached within Window. Each
+// lockout doubles the previous one, from BaseLockout up to MaxLockout,
+// until the key stays clean for a full MaxLockout.
+type Throttle struct {
+	MaxFailures int
+	Window      time.Duration
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+
+	// now is replaced in tests
+	now func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastSweep time.Time
+}
+
+type entry struct {
+	failures    int
+	firstFail   time.Time
+	lockouts    int
+	lockedUntil time.Time
+}
+
+// New returns a Throttle with the given limits.
+func New(maxFailures int, window, baseLockout, maxLockout time.Duration) *Throttle {
+	return &Throttle{
+		MaxFailures: maxFailures,
+		Window:      window,
+		BaseLockout: baseLockout,
+		MaxLockout:  maxLockout,
+		now:         time.Now,
+		entries:     make(map[string]*entry),
+	}
+}
+
+// Locked returns how long key is still locked out for, or 0.
+func (t *Throttle) Locked(key string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.sweep(now)
+	if e, ok := t.entries[key]; ok && now.Before(e.lockedUntil) {
+		return e.lockedUntil.Sub(now)
+	}
+	return 0
+}
+
+// Fail records a failed attempt for key. When it locks the key out, it
+// returns the lockout duration and the number of failures that caused it.
+func (t *Throttle) Fail(key string) (lockedFor time.Duration, failures int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	e, ok := t.entries[key]
+	if !ok {
+		e = &entry{}
+		t.entries[key] = e
+	}
+	if e.lockouts > 0 && now.Sub(e.lockedUntil) > t.MaxLockout {
+		e.lockouts = 0
+	}
+	if e.failures == 0 || now.Sub(e.firstFail) > t.Window {
+		e.failures = 0
+		e.firstFail = now
+	}
+
+	e.failures++
+	if e.failures < t.MaxFailures {
+		return 0, e.failures
+	}
+
+	failures = e.failures
+	lockedFor = t.BaseLockout << e.lockouts
+	if lockedFor > t.MaxLockout || lockedFor <= 0 {
+		lockedFor = t.MaxLockout
+	}
+	e.lockouts++
+	e.failures = 0
+	e.lockedUntil = now.Add(lockedFor)
+	return lockedFor, failures
+}
+
+// Reset forgets key, e.g. after a successful login or when an admin
+// unlocks an account.
+func (t *Throttle) Reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.entries, key)
+}
+
+// sweep drops entries with nothing left to remember, at most once per
+// Window, so keys seen once do not accumulate.
+func (t *Throttle) sweep(now time.Time) {
+	if now.Sub(t.lastSweep) < t.Window {
+		return
+	}
+	t.lastSweep = now
+	for key, e := range t.entries {
+		expired := now.Sub(e.firstFail) > t.Window && now.Sub(e.lockedUntil) > t.MaxLockout
+		if expired {
+			delete(t.entries, key)
+		}
+	}
+}
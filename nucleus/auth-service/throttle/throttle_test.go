@@ -0,0 +1,71 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThrottle(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	th := New(3, 15*time.Minute, time.Minute, 10*time.Minute)
+	th.now = func() time.Time { return now }
+
+	fail := func(n int) time.Duration {
+		var lockedFor time.Duration
+		for i := 0; i < n; i++ {
+			lockedFor, _ = th.Fail("alice")
+		}
+		return lockedFor
+	}
+
+	t.Run("below the limit", func(t *testing.T) {
+		assert.Zero(t, fail(2))
+		assert.Zero(t, th.Locked("alice"))
+	})
+
+	t.Run("exponential lockout", func(t *testing.T) {
+		lockedFor, failures := th.Fail("alice")
+		assert.Equal(t, time.Minute, lockedFor)
+		assert.Equal(t, 3, failures)
+		assert.Equal(t, time.Minute, th.Locked("alice"))
+		assert.Zero(t, th.Locked("bob"))
+
+		now = now.Add(time.Minute)
+		assert.Zero(t, th.Locked("alice"))
+		assert.Equal(t, 2*time.Minute, fail(3))
+
+		now = now.Add(2 * time.Minute)
+		assert.Equal(t, 4*time.Minute, fail(3))
+
+		now = now.Add(4 * time.Minute)
+		assert.Equal(t, 8*time.Minute, fail(3))
+
+		now = now.Add(8 * time.Minute)
+		assert.Equal(t, 10*time.Minute, fail(3), "capped at MaxLockout")
+	})
+
+	t.Run("failures outside the window", func(t *testing.T) {
+		th.Reset("alice")
+		fail(2)
+		now = now.Add(16 * time.Minute)
+		assert.Zero(t, fail(2))
+	})
+
+	t.Run("lockouts decay", func(t *testing.T) {
+		th.Reset("alice")
+		assert.Equal(t, time.Minute, fail(3))
+		now = now.Add(12 * time.Minute)
+		assert.Equal(t, time.Minute, fail(3))
+	})
+
+	t.Run("sweep", func(t *testing.T) {
+		th.Fail("carol")
+		now = now.Add(time.Hour)
+		th.Locked("dave")
+		th.mu.Lock()
+		defer th.mu.Unlock()
+		assert.NotContains(t, th.entries, "carol")
+	})
+}
@@ -0,0 +1,25 @@
+package validation
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/Talfaza/authentification/models"
+	"github.com/go-playground/validator/v10"
+)
+
+// The tags naming the values auth-service knows of: organization
+// permissions, roles and token scopes.
+func init() {
+	known := map[string][]string{
+		"permission": models.Permissions,
+		"role":       models.Roles,
+		"scope":      models.Scopes,
+	}
+	for tag, values := range known {
+		_ = validate.RegisterValidation(tag, func(fl validator.FieldLevel) bool {
+			return slices.Contains(values, fl.Field().String())
+		})
+		messages[tag] = "must be one of " + strings.Join(values, ", ")
+	}
+}
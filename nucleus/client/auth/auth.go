@@ -0,0 +1,5 @@
+// Package auth is a client of auth-service, generated from its OpenAPI
+// document.
+package auth
+
+//go:generate go tool oapi-codegen -config oapi-codegen.yaml ../../auth-service/api/openapi.yaml
@@ -0,0 +1,212 @@
+// Package client talks to nucleus through clients generated from the
+// OpenAPI documents of its services, in the auth, prox, lxc and ssh
+// packages. New bundles them behind the gateway:
+//
+//	c, err := client.New("http://localhost:8080", client.WithToken(os.Getenv("NUCLEUS_TOKEN")))
+//	resp, err := c.Prox.ListServersWithResponse(ctx, nil)
+//	if err == nil {
+//		err = client.Check(resp.HTTPResponse, resp.Body)
+//	}
+//
+// A service can also be reached directly, e.g. with NewProx.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/Talfaza/client/auth"
+	"github.com/Talfaza/client/lxc"
+	"github.com/Talfaza/client/prox"
+	"github.com/Talfaza/client/ssh"
+)
+
+// ProblemContentType is the media type of the problems services answer
+// failed requests with.
+const ProblemContentType = "application/problem+json"
+
+// Client reaches every service through the gateway.
+type Client struct {
+	Auth *auth.ClientWithResponses
+	Prox *prox.ClientWithResponses
+	LXC  *lxc.ClientWithResponses
+	SSH  *ssh.ClientWithResponses
+}
+
+// Doer sends HTTP requests, e.g. an *http.Client.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// RequestEditorFn changes every request before it is sent.
+type RequestEditorFn func(ctx context.Context, req *http.Request) error
+
+type options struct {
+	doer    Doer
+	editors []RequestEditorFn
+}
+
+// Option configures a Client.
+type Option func(*options)
+
+// WithToken authenticates every request with a bearer token: a session
+// token or a personal access token.
+func WithToken(token string) Option {
+	return WithRequestEditorFn(func(_ context.Context, req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	})
+}
+
+// WithHTTPClient sends requests with doer instead of http.DefaultClient.
+func WithHTTPClient(doer Doer) Option {
+	return func(o *options) {
+		o.doer = doer
+	}
+}
+
+// WithRequestEditorFn changes every request with fn before it is sent.
+func WithRequestEditorFn(fn RequestEditorFn) Option {
+	return func(o *options) {
+		o.editors = append(o.editors, fn)
+	}
+}
+
+// New returns a client of the gateway at baseURL. Endpoints the gateway
+// does not expose, such as deleting a user's servers, need the client of
+// the service instead.
+func New(baseURL string, opts ...Option) (*Client, error) {
+	c := &Client{}
+	var err error
+	if c.Auth, err = NewAuth(baseURL, opts...); err != nil {
+		return nil, err
+	}
+	if c.Prox, err = NewProx(baseURL, opts...); err != nil {
+		return nil, err
+	}
+	if c.LXC, err = NewLXC(baseURL, opts...); err != nil {
+		return nil, err
+	}
+	if c.SSH, err = NewSSH(baseURL, opts...); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Each generated package has its own option types, with the same
+// underlying types.
+
+// NewAuth returns a client of auth-service at baseURL.
+func NewAuth(baseURL string, opts ...Option) (*auth.ClientWithResponses, error) {
+	o := newOptions(opts)
+	var clientOpts []auth.ClientOption
+	if o.doer != nil {
+		clientOpts = append(clientOpts, auth.WithHTTPClient(o.doer))
+	}
+	for _, fn := range o.editors {
+		clientOpts = append(clientOpts, auth.WithRequestEditorFn(auth.RequestEditorFn(fn)))
+	}
+	return auth.NewClientWithResponses(baseURL, clientOpts...)
+}
+
+// NewProx returns a client of prox-service at baseURL.
+func NewProx(baseURL string, opts ...Option) (*prox.ClientWithResponses, error) {
+	o := newOptions(opts)
+	var clientOpts []prox.ClientOption
+	if o.doer != nil {
+		clientOpts = append(clientOpts, prox.WithHTTPClient(o.doer))
+	}
+	for _, fn := range o.editors {
+		clientOpts = append(clientOpts, prox.WithRequestEditorFn(prox.RequestEditorFn(fn)))
+	}
+	return prox.NewClientWithResponses(baseURL, clientOpts...)
+}
+
+// NewLXC returns a client of lxc-service at baseURL.
+func NewLXC(baseURL string, opts ...Option) (*lxc.ClientWithResponses, error) {
+	o := newOptions(opts)
+	var clientOpts []lxc.ClientOption
+	if o.doer != nil {
+		clientOpts = append(clientOpts, lxc.WithHTTPClient(o.doer))
+	}
+	for _, fn := range o.editors {
+		clientOpts = append(clientOpts, lxc.WithRequestEditorFn(lxc.RequestEditorFn(fn)))
+	}
+	return lxc.NewClientWithResponses(baseURL, clientOpts...)
+}
+
+// NewSSH returns a client of ssh-service at baseURL.
+func NewSSH(baseURL string, opts ...Option) (*ssh.ClientWithResponses, error) {
+	o := newOptions(opts)
+	var clientOpts []ssh.ClientOption
+	if o.doer != nil {
+		clientOpts = append(clientOpts, ssh.WithHTTPClient(o.doer))
+	}
+	for _, fn := range o.editors {
+		clientOpts = append(clientOpts, ssh.WithRequestEditorFn(ssh.RequestEditorFn(fn)))
+	}
+	return ssh.NewClientWithResponses(baseURL, clientOpts...)
+}
+
+// Problem is an RFC 7807 problem a service answered a request with.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail"`
+	Instance string `json:"instance"`
+	// Code tells problems apart without parsing their detail, e.g.
+	// not_found or invalid_credentials
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id"`
+	Errors    []FieldError `json:"errors"`
+}
+
+// FieldError is a field of a request body that failed validation.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (p *Problem) Error() string {
+	msg := p.Detail
+	if msg == "" {
+		msg = p.Title
+	}
+	if len(p.Errors) > 0 {
+		messages := make([]string, len(p.Errors))
+		for i, e := range p.Errors {
+			messages[i] = e.Message
+		}
+		msg += ": " + strings.Join(messages, ", ")
+	}
+	return msg
+}
+
+// Check returns nil for a successful response, and a *Problem otherwise.
+// A failure answered with something else than a problem, e.g. by a proxy
+// in front of nucleus, gets a problem with the status of the response.
+func Check(resp *http.Response, body []byte) error {
+	if resp.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+
+	p := &Problem{}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != ProblemContentType || json.Unmarshal(body, p) != nil {
+		p = &Problem{Type: "about:blank", Title: http.StatusText(resp.StatusCode)}
+	}
+	p.Status = resp.StatusCode
+	return p
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Talfaza/client/prox"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer nuc_secret" {
+			w.Header().Set("Content-Type", ProblemContentType)
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"type":"about:blank","title":"Unauthorized","status":401,"detail":"Missing token","instance":"/prox","code":"unauthorized"}`))
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`[{"ID":1,"server_name":"pve","host":"pve.lab.local","port":"8006"}]`))
+		case http.MethodPost:
+			w.Header().Set("Content-Type", ProblemContentType)
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = w.Write([]byte(`{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"The request has invalid fields","instance":"/prox","code":"validation_failed","errors":[{"field":"host","message":"host must be a hostname or IP address"}]}`))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte("<html>Bad Gateway</html>"))
+		}
+	}))
+	defer gateway.Close()
+	ctx := context.Background()
+
+	c, err := New(gateway.URL, WithToken("nuc_secret"))
+	require.NoError(t, err)
+	listed, err := c.Prox.ListServersWithResponse(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, Check(listed.HTTPResponse, listed.Body))
+	require.NotNil(t, listed.JSON200)
+	require.Len(t, *listed.JSON200, 1)
+	assert.Equal(t, "pve", (*listed.JSON200)[0].ServerName)
+
+	created, err := c.Prox.CreateServerWithResponse(ctx, prox.ServerInput{ServerName: "pve", Host: "not a host"})
+	require.NoError(t, err)
+	var problem *Problem
+	require.True(t, errors.As(Check(created.HTTPResponse, created.Body), &problem))
+	assert.Equal(t, "validation_failed", problem.Code)
+	assert.Equal(t, "The request has invalid fields: host must be a hostname or IP address", problem.Error())
+
+	deleted, err := c.Prox.DeleteServerWithResponse(ctx, 1)
+	require.NoError(t, err)
+	require.True(t, errors.As(Check(deleted.HTTPResponse, deleted.Body), &problem))
+	assert.Equal(t, http.StatusBadGateway, problem.Status)
+	assert.Equal(t, "Bad Gateway", problem.Error())
+
+	anonymous, err := New(gateway.URL)
+	require.NoError(t, err)
+	listed, err = anonymous.Prox.ListServersWithResponse(ctx, nil)
+	require.NoError(t, err)
+	require.True(t, errors.As(Check(listed.HTTPResponse, listed.Body), &problem))
+	assert.Equal(t, "unauthorized", problem.Code)
+	assert.Equal(t, "Missing token", problem.Error())
+}
@@ -0,0 +1,2049 @@
+// Package lxc provides primitives to interact with the openapi HTTP API.
+//
+// Code generated by github.com/oapi-codegen/oapi-codegen/v2 version v2.5.1 DO NOT EDIT.
+package lxc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/oapi-codegen/runtime"
+)
+
+const (
+	BearerScopes  = "bearer.Scopes"
+	SessionScopes = "session.Scopes"
+)
+
+// Defines values for JobAction.
+const (
+	Destroy   JobAction = "destroy"
+	Provision JobAction = "provision"
+	Start     JobAction = "start"
+	Stop      JobAction = "stop"
+)
+
+// Defines values for JobState.
+const (
+	JobStateFailed    JobState = "failed"
+	JobStatePending   JobState = "pending"
+	JobStateRunning   JobState = "running"
+	JobStateSucceeded JobState = "succeeded"
+)
+
+// Defines values for JobStepState.
+const (
+	JobStepStateFailed    JobStepState = "failed"
+	JobStepStatePending   JobStepState = "pending"
+	JobStepStateRunning   JobStepState = "running"
+	JobStepStateSucceeded JobStepState = "succeeded"
+)
+
+// Defines values for JobSort.
+const (
+	JobSortCreated      JobSort = "created"
+	JobSortMinusCreated JobSort = "-created"
+	JobSortMinusUpdated JobSort = "-updated"
+	JobSortUpdated      JobSort = "updated"
+)
+
+// Defines values for Sort.
+const (
+	SortCreated      Sort = "created"
+	SortMinusCreated Sort = "-created"
+	SortMinusName    Sort = "-name"
+	SortMinusUpdated Sort = "-updated"
+	SortName         Sort = "name"
+	SortUpdated      Sort = "updated"
+)
+
+// Defines values for ListConfigsParamsSort.
+const (
+	ListConfigsParamsSortCreated      ListConfigsParamsSort = "created"
+	ListConfigsParamsSortMinusCreated ListConfigsParamsSort = "-created"
+	ListConfigsParamsSortMinusName    ListConfigsParamsSort = "-name"
+	ListConfigsParamsSortMinusUpdated ListConfigsParamsSort = "-updated"
+	ListConfigsParamsSortName         ListConfigsParamsSort = "name"
+	ListConfigsParamsSortUpdated      ListConfigsParamsSort = "updated"
+)
+
+// Defines values for ListJobsParamsSort.
+const (
+	ListJobsParamsSortCreated      ListJobsParamsSort = "created"
+	ListJobsParamsSortMinusCreated ListJobsParamsSort = "-created"
+	ListJobsParamsSortMinusUpdated ListJobsParamsSort = "-updated"
+	ListJobsParamsSortUpdated      ListJobsParamsSort = "updated"
+)
+
+// Config defines model for Config.
+type Config struct {
+	CreatedAt time.Time  `json:"CreatedAt"`
+	DeletedAt *time.Time `json:"DeletedAt"`
+	ID        int        `json:"ID"`
+	UpdatedAt time.Time  `json:"UpdatedAt"`
+	Name      string     `json:"name"`
+
+	// OrgID The organization the configuration is shared with
+	OrgID *int `json:"org_id"`
+
+	// Packages The packages as a JSON object of names to versions, encoded in a string
+	Packages string `json:"packages"`
+
+	// UserID The user who created the configuration
+	UserID int `json:"user_id"`
+}
+
+// ConfigInput defines model for ConfigInput.
+type ConfigInput struct {
+	Name string `json:"name"`
+
+	// OrgID Shares the configuration with the organization
+	OrgID *int `json:"org_id"`
+
+	// Packages Package names to versions, both at most 255 characters
+	Packages *map[string]string `json:"packages,omitempty"`
+}
+
+// ConfigOrg defines model for ConfigOrg.
+type ConfigOrg struct {
+	// OrgID The organization to share the configuration with, private when null or left out
+	OrgID *int `json:"org_id"`
+}
+
+// ContainerTarget defines model for ContainerTarget.
+type ContainerTarget struct {
+	// ServerID The prox-service server the container is on
+	ServerID int `json:"server_id"`
+}
+
+// Deleted defines model for Deleted.
+type Deleted struct {
+	// Deleted How many records were deleted
+	Deleted int `json:"deleted"`
+}
+
+// FieldError defines model for FieldError.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Job defines model for Job.
+type Job struct {
+	CreatedAt time.Time  `json:"CreatedAt"`
+	DeletedAt *time.Time `json:"DeletedAt"`
+	ID        int        `json:"ID"`
+	UpdatedAt time.Time  `json:"UpdatedAt"`
+	Action    JobAction  `json:"action"`
+
+	// ConfigID The configuration a provision job installs
+	ConfigID *int `json:"config_id"`
+	Ctid     int  `json:"ctid"`
+
+	// Error Why the job failed, and at which step
+	Error    string   `json:"error"`
+	ServerID int      `json:"server_id"`
+	State    JobState `json:"state"`
+
+	// Steps Left out of lists of jobs
+	Steps *[]JobStep `json:"steps,omitempty"`
+
+	// UserID The user who started the job
+	UserID int `json:"user_id"`
+}
+
+// JobAction defines model for Job.Action.
+type JobAction string
+
+// JobState defines model for Job.State.
+type JobState string
+
+// JobStep defines model for JobStep.
+type JobStep struct {
+	// Command What the step runs on the Proxmox server
+	Command    string     `json:"command"`
+	FinishedAt *time.Time `json:"finished_at"`
+	ID         int        `json:"id"`
+	JobID      int        `json:"job_id"`
+	Name       string     `json:"name"`
+
+	// Output What the command wrote, or why it failed
+	Output    string       `json:"output"`
+	StartedAt *time.Time   `json:"started_at"`
+	State     JobStepState `json:"state"`
+}
+
+// JobStepState defines model for JobStep.State.
+type JobStepState string
+
+// Message defines model for Message.
+type Message struct {
+	Message string `json:"message"`
+}
+
+// Problem An RFC 7807 problem
+type Problem struct {
+	// Code Tells problems apart without parsing their detail
+	Code   string  `json:"code"`
+	Detail *string `json:"detail,omitempty"`
+
+	// Errors The invalid fields of a validation_failed problem
+	Errors    *[]FieldError `json:"errors,omitempty"`
+	Instance  string        `json:"instance"`
+	RequestID *string       `json:"request_id,omitempty"`
+	Status    int           `json:"status"`
+	Title     string        `json:"title"`
+	Type      string        `json:"type"`
+}
+
+// ProvisionInput defines model for ProvisionInput.
+type ProvisionInput struct {
+	Cores *int `json:"cores,omitempty"`
+
+	// Ctid The ID of the container on the server, not taken yet
+	Ctid int `json:"ctid"`
+
+	// Disk The size of the root disk in GiB
+	Disk     *int   `json:"disk,omitempty"`
+	Hostname string `json:"hostname"`
+
+	// Memory In MiB
+	Memory *int `json:"memory,omitempty"`
+
+	// ServerID The prox-service server to create the container on
+	ServerID int `json:"server_id"`
+
+	// Template The volume of the OS template
+	Template string `json:"template"`
+}
+
+// CTID defines model for CTID.
+type CTID = int
+
+// ID defines model for ID.
+type ID = int
+
+// JobSearch defines model for JobSearch.
+type JobSearch = string
+
+// JobSort defines model for JobSort.
+type JobSort string
+
+// Page defines model for Page.
+type Page = int
+
+// PerPage defines model for PerPage.
+type PerPage = int
+
+// Search defines model for Search.
+type Search = string
+
+// Sort defines model for Sort.
+type Sort string
+
+// ListConfigsParams defines parameters for ListConfigs.
+type ListConfigsParams struct {
+	// Page The page to list, from 1
+	Page *Page `form:"page,omitempty" json:"page,omitempty"`
+
+	// PerPage How many rows a page holds, at most 100. Out of range values fall back to the default.
+	PerPage *PerPage `form:"per_page,omitempty" json:"per_page,omitempty"`
+
+	// Q Matched against the names of the configurations, ignoring case
+	Q *Search `form:"q,omitempty" json:"q,omitempty"`
+
+	// Sort The order of the rows, descending with a - prefix. Ties are broken by ID.
+	Sort *ListConfigsParamsSort `form:"sort,omitempty" json:"sort,omitempty"`
+}
+
+// ListConfigsParamsSort defines parameters for ListConfigs.
+type ListConfigsParamsSort string
+
+// ListJobsParams defines parameters for ListJobs.
+type ListJobsParams struct {
+	// Page The page to list, from 1
+	Page *Page `form:"page,omitempty" json:"page,omitempty"`
+
+	// PerPage How many rows a page holds, at most 100. Out of range values fall back to the default.
+	PerPage *PerPage `form:"per_page,omitempty" json:"per_page,omitempty"`
+
+	// Q Matched against the actions and states of the jobs, ignoring case
+	Q *JobSearch `form:"q,omitempty" json:"q,omitempty"`
+
+	// Sort The order of the rows, descending with a - prefix. Ties are broken by ID.
+	Sort *ListJobsParamsSort `form:"sort,omitempty" json:"sort,omitempty"`
+}
+
+// ListJobsParamsSort defines parameters for ListJobs.
+type ListJobsParamsSort string
+
+// CreateConfigJSONRequestBody defines body for CreateConfig for application/json ContentType.
+type CreateConfigJSONRequestBody = ConfigInput
+
+// DestroyContainerJSONRequestBody defines body for DestroyContainer for application/json ContentType.
+type DestroyContainerJSONRequestBody = ContainerTarget
+
+// StartContainerJSONRequestBody defines body for StartContainer for application/json ContentType.
+type StartContainerJSONRequestBody = ContainerTarget
+
+// StopContainerJSONRequestBody defines body for StopContainer for application/json ContentType.
+type StopContainerJSONRequestBody = ContainerTarget
+
+// ShareConfigJSONRequestBody defines body for ShareConfig for application/json ContentType.
+type ShareConfigJSONRequestBody = ConfigOrg
+
+// ProvisionContainerJSONRequestBody defines body for ProvisionContainer for application/json ContentType.
+type ProvisionContainerJSONRequestBody = ProvisionInput
+
+// RequestEditorFn  is the function signature for the RequestEditor callback function
+type RequestEditorFn func(ctx context.Context, req *http.Request) error
+
+// Doer performs HTTP requests.
+//
+// The standard http.Client implements this interface.
+type HttpRequestDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client which conforms to the OpenAPI3 specification for this service.
+type Client struct {
+	// The endpoint of the server conforming to this interface, with scheme,
+	// https://api.deepmap.com for example. This can contain a path relative
+	// to the server, such as https://api.deepmap.com/dev-test, and all the
+	// paths in the swagger spec will be appended to the server.
+	Server string
+
+	// Doer for performing requests, typically a *http.Client with any
+	// customized settings, such as certificate chains.
+	Client HttpRequestDoer
+
+	// A list of callbacks for modifying requests which are generated before sending over
+	// the network.
+	RequestEditors []RequestEditorFn
+}
+
+// ClientOption allows setting custom parameters during construction
+type ClientOption func(*Client) error
+
+// Creates a new Client, with reasonable defaults
+func NewClient(server string, opts ...ClientOption) (*Client, error) {
+	// create a client with sane default values
+	client := Client{
+		Server: server,
+	}
+	// mutate client and add all optional params
+	for _, o := range opts {
+		if err := o(&client); err != nil {
+			return nil, err
+		}
+	}
+	// ensure the server URL always has a trailing slash
+	if !strings.HasSuffix(client.Server, "/") {
+		client.Server += "/"
+	}
+	// create httpClient, if not already present
+	if client.Client == nil {
+		client.Client = &http.Client{}
+	}
+	return &client, nil
+}
+
+// WithHTTPClient allows overriding the default Doer, which is
+// automatically created using http.Client. This is useful for tests.
+func WithHTTPClient(doer HttpRequestDoer) ClientOption {
+	return func(c *Client) error {
+		c.Client = doer
+		return nil
+	}
+}
+
+// WithRequestEditorFn allows setting up a callback function, which will be
+// called right before sending the request. This can be used to mutate the request.
+func WithRequestEditorFn(fn RequestEditorFn) ClientOption {
+	return func(c *Client) error {
+		c.RequestEditors = append(c.RequestEditors, fn)
+		return nil
+	}
+}
+
+// The interface specification for the client above.
+type ClientInterface interface {
+	// DeleteUserConfigs request
+	DeleteUserConfigs(ctx context.Context, id ID, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// ListConfigs request
+	ListConfigs(ctx context.Context, params *ListConfigsParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// CreateConfigWithBody request with any body
+	CreateConfigWithBody(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	CreateConfig(ctx context.Context, body CreateConfigJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// DestroyContainerWithBody request with any body
+	DestroyContainerWithBody(ctx context.Context, ctid CTID, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	DestroyContainer(ctx context.Context, ctid CTID, body DestroyContainerJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// StartContainerWithBody request with any body
+	StartContainerWithBody(ctx context.Context, ctid CTID, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	StartContainer(ctx context.Context, ctid CTID, body StartContainerJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// StopContainerWithBody request with any body
+	StopContainerWithBody(ctx context.Context, ctid CTID, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	StopContainer(ctx context.Context, ctid CTID, body StopContainerJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// ListJobs request
+	ListJobs(ctx context.Context, params *ListJobsParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// GetJob request
+	GetJob(ctx context.Context, id ID, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// DeleteConfig request
+	DeleteConfig(ctx context.Context, id ID, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// ShareConfigWithBody request with any body
+	ShareConfigWithBody(ctx context.Context, id ID, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	ShareConfig(ctx context.Context, id ID, body ShareConfigJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// ProvisionContainerWithBody request with any body
+	ProvisionContainerWithBody(ctx context.Context, id ID, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	ProvisionContainer(ctx context.Context, id ID, body ProvisionContainerJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+}
+
+func (c *Client) DeleteUserConfigs(ctx context.Context, id ID, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewDeleteUserConfigsRequest(c.Server, id)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) ListConfigs(ctx context.Context, params *ListConfigsParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewListConfigsRequest(c.Server, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) CreateConfigWithBody(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewCreateConfigRequestWithBody(c.Server, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) CreateConfig(ctx context.Context, body CreateConfigJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewCreateConfigRequest(c.Server, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) DestroyContainerWithBody(ctx context.Context, ctid CTID, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewDestroyContainerRequestWithBody(c.Server, ctid, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) DestroyContainer(ctx context.Context, ctid CTID, body DestroyContainerJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewDestroyContainerRequest(c.Server, ctid, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) StartContainerWithBody(ctx context.Context, ctid CTID, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewStartContainerRequestWithBody(c.Server, ctid, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) StartContainer(ctx context.Context, ctid CTID, body StartContainerJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewStartContainerRequest(c.Server, ctid, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) StopContainerWithBody(ctx context.Context, ctid CTID, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewStopContainerRequestWithBody(c.Server, ctid, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) StopContainer(ctx context.Context, ctid CTID, body StopContainerJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewStopContainerRequest(c.Server, ctid, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) ListJobs(ctx context.Context, params *ListJobsParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewListJobsRequest(c.Server, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) GetJob(ctx context.Context, id ID, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewGetJobRequest(c.Server, id)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) DeleteConfig(ctx context.Context, id ID, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewDeleteConfigRequest(c.Server, id)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) ShareConfigWithBody(ctx context.Context, id ID, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewShareConfigRequestWithBody(c.Server, id, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) ShareConfig(ctx context.Context, id ID, body ShareConfigJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewShareConfigRequest(c.Server, id, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) ProvisionContainerWithBody(ctx context.Context, id ID, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewProvisionContainerRequestWithBody(c.Server, id, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) ProvisionContainer(ctx context.Context, id ID, body ProvisionContainerJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewProvisionContainerRequest(c.Server, id, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+// NewDeleteUserConfigsRequest generates requests for DeleteUserConfigs
+func NewDeleteUserConfigsRequest(server string, id ID) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/admin/users/%s", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("DELETE", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewListConfigsRequest generates requests for ListConfigs
+func NewListConfigsRequest(server string, params *ListConfigsParams) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/lxc")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if params.Page != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "page", runtime.ParamLocationQuery, *params.Page); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.PerPage != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "per_page", runtime.ParamLocationQuery, *params.PerPage); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.Q != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "q", runtime.ParamLocationQuery, *params.Q); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.Sort != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "sort", runtime.ParamLocationQuery, *params.Sort); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewCreateConfigRequest calls the generic CreateConfig builder with application/json body
+func NewCreateConfigRequest(server string, body CreateConfigJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewCreateConfigRequestWithBody(server, "application/json", bodyReader)
+}
+
+// NewCreateConfigRequestWithBody generates requests for CreateConfig with any type of body
+func NewCreateConfigRequestWithBody(server string, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/lxc")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+// NewDestroyContainerRequest calls the generic DestroyContainer builder with application/json body
+func NewDestroyContainerRequest(server string, ctid CTID, body DestroyContainerJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewDestroyContainerRequestWithBody(server, ctid, "application/json", bodyReader)
+}
+
+// NewDestroyContainerRequestWithBody generates requests for DestroyContainer with any type of body
+func NewDestroyContainerRequestWithBody(server string, ctid CTID, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "ctid", runtime.ParamLocationPath, ctid)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/lxc/containers/%s/destroy", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+// NewStartContainerRequest calls the generic StartContainer builder with application/json body
+func NewStartContainerRequest(server string, ctid CTID, body StartContainerJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewStartContainerRequestWithBody(server, ctid, "application/json", bodyReader)
+}
+
+// NewStartContainerRequestWithBody generates requests for StartContainer with any type of body
+func NewStartContainerRequestWithBody(server string, ctid CTID, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "ctid", runtime.ParamLocationPath, ctid)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/lxc/containers/%s/start", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+// NewStopContainerRequest calls the generic StopContainer builder with application/json body
+func NewStopContainerRequest(server string, ctid CTID, body StopContainerJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewStopContainerRequestWithBody(server, ctid, "application/json", bodyReader)
+}
+
+// NewStopContainerRequestWithBody generates requests for StopContainer with any type of body
+func NewStopContainerRequestWithBody(server string, ctid CTID, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "ctid", runtime.ParamLocationPath, ctid)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/lxc/containers/%s/stop", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+// NewListJobsRequest generates requests for ListJobs
+func NewListJobsRequest(server string, params *ListJobsParams) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/lxc/jobs")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if params.Page != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "page", runtime.ParamLocationQuery, *params.Page); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.PerPage != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "per_page", runtime.ParamLocationQuery, *params.PerPage); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.Q != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "q", runtime.ParamLocationQuery, *params.Q); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.Sort != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "sort", runtime.ParamLocationQuery, *params.Sort); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewGetJobRequest generates requests for GetJob
+func NewGetJobRequest(server string, id ID) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/lxc/jobs/%s", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewDeleteConfigRequest generates requests for DeleteConfig
+func NewDeleteConfigRequest(server string, id ID) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/lxc/%s", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("DELETE", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewShareConfigRequest calls the generic ShareConfig builder with application/json body
+func NewShareConfigRequest(server string, id ID, body ShareConfigJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewShareConfigRequestWithBody(server, id, "application/json", bodyReader)
+}
+
+// NewShareConfigRequestWithBody generates requests for ShareConfig with any type of body
+func NewShareConfigRequestWithBody(server string, id ID, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/lxc/%s/org", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("PUT", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+// NewProvisionContainerRequest calls the generic ProvisionContainer builder with application/json body
+func NewProvisionContainerRequest(server string, id ID, body ProvisionContainerJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewProvisionContainerRequestWithBody(server, id, "application/json", bodyReader)
+}
+
+// NewProvisionContainerRequestWithBody generates requests for ProvisionContainer with any type of body
+func NewProvisionContainerRequestWithBody(server string, id ID, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/lxc/%s/provision", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+func (c *Client) applyEditors(ctx context.Context, req *http.Request, additionalEditors []RequestEditorFn) error {
+	for _, r := range c.RequestEditors {
+		if err := r(ctx, req); err != nil {
+			return err
+		}
+	}
+	for _, r := range additionalEditors {
+		if err := r(ctx, req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClientWithResponses builds on ClientInterface to offer response payloads
+type ClientWithResponses struct {
+	ClientInterface
+}
+
+// NewClientWithResponses creates a new ClientWithResponses, which wraps
+// Client with return type handling
+func NewClientWithResponses(server string, opts ...ClientOption) (*ClientWithResponses, error) {
+	client, err := NewClient(server, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientWithResponses{client}, nil
+}
+
+// WithBaseURL overrides the baseURL.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *Client) error {
+		newBaseURL, err := url.Parse(baseURL)
+		if err != nil {
+			return err
+		}
+		c.Server = newBaseURL.String()
+		return nil
+	}
+}
+
+// ClientWithResponsesInterface is the interface specification for the client with responses above.
+type ClientWithResponsesInterface interface {
+	// DeleteUserConfigsWithResponse request
+	DeleteUserConfigsWithResponse(ctx context.Context, id ID, reqEditors ...RequestEditorFn) (*DeleteUserConfigsResponse, error)
+
+	// ListConfigsWithResponse request
+	ListConfigsWithResponse(ctx context.Context, params *ListConfigsParams, reqEditors ...RequestEditorFn) (*ListConfigsResponse, error)
+
+	// CreateConfigWithBodyWithResponse request with any body
+	CreateConfigWithBodyWithResponse(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*CreateConfigResponse, error)
+
+	CreateConfigWithResponse(ctx context.Context, body CreateConfigJSONRequestBody, reqEditors ...RequestEditorFn) (*CreateConfigResponse, error)
+
+	// DestroyContainerWithBodyWithResponse request with any body
+	DestroyContainerWithBodyWithResponse(ctx context.Context, ctid CTID, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*DestroyContainerResponse, error)
+
+	DestroyContainerWithResponse(ctx context.Context, ctid CTID, body DestroyContainerJSONRequestBody, reqEditors ...RequestEditorFn) (*DestroyContainerResponse, error)
+
+	// StartContainerWithBodyWithResponse request with any body
+	StartContainerWithBodyWithResponse(ctx context.Context, ctid CTID, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*StartContainerResponse, error)
+
+	StartContainerWithResponse(ctx context.Context, ctid CTID, body StartContainerJSONRequestBody, reqEditors ...RequestEditorFn) (*StartContainerResponse, error)
+
+	// StopContainerWithBodyWithResponse request with any body
+	StopContainerWithBodyWithResponse(ctx context.Context, ctid CTID, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*StopContainerResponse, error)
+
+	StopContainerWithResponse(ctx context.Context, ctid CTID, body StopContainerJSONRequestBody, reqEditors ...RequestEditorFn) (*StopContainerResponse, error)
+
+	// ListJobsWithResponse request
+	ListJobsWithResponse(ctx context.Context, params *ListJobsParams, reqEditors ...RequestEditorFn) (*ListJobsResponse, error)
+
+	// GetJobWithResponse request
+	GetJobWithResponse(ctx context.Context, id ID, reqEditors ...RequestEditorFn) (*GetJobResponse, error)
+
+	// DeleteConfigWithResponse request
+	DeleteConfigWithResponse(ctx context.Context, id ID, reqEditors ...RequestEditorFn) (*DeleteConfigResponse, error)
+
+	// ShareConfigWithBodyWithResponse request with any body
+	ShareConfigWithBodyWithResponse(ctx context.Context, id ID, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*ShareConfigResponse, error)
+
+	ShareConfigWithResponse(ctx context.Context, id ID, body ShareConfigJSONRequestBody, reqEditors ...RequestEditorFn) (*ShareConfigResponse, error)
+
+	// ProvisionContainerWithBodyWithResponse request with any body
+	ProvisionContainerWithBodyWithResponse(ctx context.Context, id ID, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*ProvisionContainerResponse, error)
+
+	ProvisionContainerWithResponse(ctx context.Context, id ID, body ProvisionContainerJSONRequestBody, reqEditors ...RequestEditorFn) (*ProvisionContainerResponse, error)
+}
+
+type DeleteUserConfigsResponse struct {
+	Body                          []byte
+	HTTPResponse                  *http.Response
+	JSON200                       *Deleted
+	ApplicationProblemJSONDefault *Problem
+}
+
+// Status returns HTTPResponse.Status
+func (r DeleteUserConfigsResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r DeleteUserConfigsResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type ListConfigsResponse struct {
+	Body                          []byte
+	HTTPResponse                  *http.Response
+	JSON200                       *[]Config
+	ApplicationProblemJSONDefault *Problem
+}
+
+// Status returns HTTPResponse.Status
+func (r ListConfigsResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r ListConfigsResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type CreateConfigResponse struct {
+	Body                          []byte
+	HTTPResponse                  *http.Response
+	JSON201                       *Config
+	ApplicationProblemJSONDefault *Problem
+}
+
+// Status returns HTTPResponse.Status
+func (r CreateConfigResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r CreateConfigResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type DestroyContainerResponse struct {
+	Body                          []byte
+	HTTPResponse                  *http.Response
+	JSON202                       *Job
+	ApplicationProblemJSONDefault *Problem
+}
+
+// Status returns HTTPResponse.Status
+func (r DestroyContainerResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r DestroyContainerResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type StartContainerResponse struct {
+	Body                          []byte
+	HTTPResponse                  *http.Response
+	JSON202                       *Job
+	ApplicationProblemJSONDefault *Problem
+}
+
+// Status returns HTTPResponse.Status
+func (r StartContainerResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r StartContainerResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type StopContainerResponse struct {
+	Body                          []byte
+	HTTPResponse                  *http.Response
+	JSON202                       *Job
+	ApplicationProblemJSONDefault *Problem
+}
+
+// Status returns HTTPResponse.Status
+func (r StopContainerResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r StopContainerResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type ListJobsResponse struct {
+	Body                          []byte
+	HTTPResponse                  *http.Response
+	JSON200                       *[]Job
+	ApplicationProblemJSONDefault *Problem
+}
+
+// Status returns HTTPResponse.Status
+func (r ListJobsResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r ListJobsResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type GetJobResponse struct {
+	Body                          []byte
+	HTTPResponse                  *http.Response
+	JSON200                       *Job
+	ApplicationProblemJSONDefault *Problem
+}
+
+// Status returns HTTPResponse.Status
+func (r GetJobResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetJobResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type DeleteConfigResponse struct {
+	Body                          []byte
+	HTTPResponse                  *http.Response
+	JSON200                       *Message
+	ApplicationProblemJSONDefault *Problem
+}
+
+// Status returns HTTPResponse.Status
+func (r DeleteConfigResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r DeleteConfigResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type ShareConfigResponse struct {
+	Body                          []byte
+	HTTPResponse                  *http.Response
+	JSON200                       *Config
+	ApplicationProblemJSONDefault *Problem
+}
+
+// Status returns HTTPResponse.Status
+func (r ShareConfigResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r ShareConfigResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type ProvisionContainerResponse struct {
+	Body                          []byte
+	HTTPResponse                  *http.Response
+	JSON202                       *Job
+	ApplicationProblemJSONDefault *Problem
+}
+
+// Status returns HTTPResponse.Status
+func (r ProvisionContainerResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r ProvisionContainerResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+// DeleteUserConfigsWithResponse request returning *DeleteUserConfigsResponse
+func (c *ClientWithResponses) DeleteUserConfigsWithResponse(ctx context.Context, id ID, reqEditors ...RequestEditorFn) (*DeleteUserConfigsResponse, error) {
+	rsp, err := c.DeleteUserConfigs(ctx, id, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDeleteUserConfigsResponse(rsp)
+}
+
+// ListConfigsWithResponse request returning *ListConfigsResponse
+func (c *ClientWithResponses) ListConfigsWithResponse(ctx context.Context, params *ListConfigsParams, reqEditors ...RequestEditorFn) (*ListConfigsResponse, error) {
+	rsp, err := c.ListConfigs(ctx, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseListConfigsResponse(rsp)
+}
+
+// CreateConfigWithBodyWithResponse request with arbitrary body returning *CreateConfigResponse
+func (c *ClientWithResponses) CreateConfigWithBodyWithResponse(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*CreateConfigResponse, error) {
+	rsp, err := c.CreateConfigWithBody(ctx, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCreateConfigResponse(rsp)
+}
+
+func (c *ClientWithResponses) CreateConfigWithResponse(ctx context.Context, body CreateConfigJSONRequestBody, reqEditors ...RequestEditorFn) (*CreateConfigResponse, error) {
+	rsp, err := c.CreateConfig(ctx, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCreateConfigResponse(rsp)
+}
+
+// DestroyContainerWithBodyWithResponse request with arbitrary body returning *DestroyContainerResponse
+func (c *ClientWithResponses) DestroyContainerWithBodyWithResponse(ctx context.Context, ctid CTID, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*DestroyContainerResponse, error) {
+	rsp, err := c.DestroyContainerWithBody(ctx, ctid, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDestroyContainerResponse(rsp)
+}
+
+func (c *ClientWithResponses) DestroyContainerWithResponse(ctx context.Context, ctid CTID, body DestroyContainerJSONRequestBody, reqEditors ...RequestEditorFn) (*DestroyContainerResponse, error) {
+	rsp, err := c.DestroyContainer(ctx, ctid, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDestroyContainerResponse(rsp)
+}
+
+// StartContainerWithBodyWithResponse request with arbitrary body returning *StartContainerResponse
+func (c *ClientWithResponses) StartContainerWithBodyWithResponse(ctx context.Context, ctid CTID, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*StartContainerResponse, error) {
+	rsp, err := c.StartContainerWithBody(ctx, ctid, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseStartContainerResponse(rsp)
+}
+
+func (c *ClientWithResponses) StartContainerWithResponse(ctx context.Context, ctid CTID, body StartContainerJSONRequestBody, reqEditors ...RequestEditorFn) (*StartContainerResponse, error) {
+	rsp, err := c.StartContainer(ctx, ctid, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseStartContainerResponse(rsp)
+}
+
+// StopContainerWithBodyWithResponse request with arbitrary body returning *StopContainerResponse
+func (c *ClientWithResponses) StopContainerWithBodyWithResponse(ctx context.Context, ctid CTID, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*StopContainerResponse, error) {
+	rsp, err := c.StopContainerWithBody(ctx, ctid, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseStopContainerResponse(rsp)
+}
+
+func (c *ClientWithResponses) StopContainerWithResponse(ctx context.Context, ctid CTID, body StopContainerJSONRequestBody, reqEditors ...RequestEditorFn) (*StopContainerResponse, error) {
+	rsp, err := c.StopContainer(ctx, ctid, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseStopContainerResponse(rsp)
+}
+
+// ListJobsWithResponse request returning *ListJobsResponse
+func (c *ClientWithResponses) ListJobsWithResponse(ctx context.Context, params *ListJobsParams, reqEditors ...RequestEditorFn) (*ListJobsResponse, error) {
+	rsp, err := c.ListJobs(ctx, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseListJobsResponse(rsp)
+}
+
+// GetJobWithResponse request returning *GetJobResponse
+func (c *ClientWithResponses) GetJobWithResponse(ctx context.Context, id ID, reqEditors ...RequestEditorFn) (*GetJobResponse, error) {
+	rsp, err := c.GetJob(ctx, id, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetJobResponse(rsp)
+}
+
+// DeleteConfigWithResponse request returning *DeleteConfigResponse
+func (c *ClientWithResponses) DeleteConfigWithResponse(ctx context.Context, id ID, reqEditors ...RequestEditorFn) (*DeleteConfigResponse, error) {
+	rsp, err := c.DeleteConfig(ctx, id, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDeleteConfigResponse(rsp)
+}
+
+// ShareConfigWithBodyWithResponse request with arbitrary body returning *ShareConfigResponse
+func (c *ClientWithResponses) ShareConfigWithBodyWithResponse(ctx context.Context, id ID, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*ShareConfigResponse, error) {
+	rsp, err := c.ShareConfigWithBody(ctx, id, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseShareConfigResponse(rsp)
+}
+
+func (c *ClientWithResponses) ShareConfigWithResponse(ctx context.Context, id ID, body ShareConfigJSONRequestBody, reqEditors ...RequestEditorFn) (*ShareConfigResponse, error) {
+	rsp, err := c.ShareConfig(ctx, id, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseShareConfigResponse(rsp)
+}
+
+// ProvisionContainerWithBodyWithResponse request with arbitrary body returning *ProvisionContainerResponse
+func (c *ClientWithResponses) ProvisionContainerWithBodyWithResponse(ctx context.Context, id ID, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*ProvisionContainerResponse, error) {
+	rsp, err := c.ProvisionContainerWithBody(ctx, id, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseProvisionContainerResponse(rsp)
+}
+
+func (c *ClientWithResponses) ProvisionContainerWithResponse(ctx context.Context, id ID, body ProvisionContainerJSONRequestBody, reqEditors ...RequestEditorFn) (*ProvisionContainerResponse, error) {
+	rsp, err := c.ProvisionContainer(ctx, id, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseProvisionContainerResponse(rsp)
+}
+
+// ParseDeleteUserConfigsResponse parses an HTTP response from a DeleteUserConfigsWithResponse call
+func ParseDeleteUserConfigsResponse(rsp *http.Response) (*DeleteUserConfigsResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DeleteUserConfigsResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest Deleted
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && true:
+		var dest Problem
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.ApplicationProblemJSONDefault = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseListConfigsResponse parses an HTTP response from a ListConfigsWithResponse call
+func ParseListConfigsResponse(rsp *http.Response) (*ListConfigsResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ListConfigsResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest []Config
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && true:
+		var dest Problem
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.ApplicationProblemJSONDefault = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseCreateConfigResponse parses an HTTP response from a CreateConfigWithResponse call
+func ParseCreateConfigResponse(rsp *http.Response) (*CreateConfigResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &CreateConfigResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 201:
+		var dest Config
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON201 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && true:
+		var dest Problem
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.ApplicationProblemJSONDefault = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseDestroyContainerResponse parses an HTTP response from a DestroyContainerWithResponse call
+func ParseDestroyContainerResponse(rsp *http.Response) (*DestroyContainerResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DestroyContainerResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 202:
+		var dest Job
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON202 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && true:
+		var dest Problem
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.ApplicationProblemJSONDefault = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseStartContainerResponse parses an HTTP response from a StartContainerWithResponse call
+func ParseStartContainerResponse(rsp *http.Response) (*StartContainerResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &StartContainerResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 202:
+		var dest Job
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON202 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && true:
+		var dest Problem
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.ApplicationProblemJSONDefault = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseStopContainerResponse parses an HTTP response from a StopContainerWithResponse call
+func ParseStopContainerResponse(rsp *http.Response) (*StopContainerResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &StopContainerResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 202:
+		var dest Job
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON202 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && true:
+		var dest Problem
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.ApplicationProblemJSONDefault = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseListJobsResponse parses an HTTP response from a ListJobsWithResponse call
+func ParseListJobsResponse(rsp *http.Response) (*ListJobsResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ListJobsResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest []Job
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && true:
+		var dest Problem
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.ApplicationProblemJSONDefault = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseGetJobResponse parses an HTTP response from a GetJobWithResponse call
+func ParseGetJobResponse(rsp *http.Response) (*GetJobResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &GetJobResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest Job
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && true:
+		var dest Problem
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.ApplicationProblemJSONDefault = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseDeleteConfigResponse parses an HTTP response from a DeleteConfigWithResponse call
+func ParseDeleteConfigResponse(rsp *http.Response) (*DeleteConfigResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DeleteConfigResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest Message
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && true:
+		var dest Problem
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.ApplicationProblemJSONDefault = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseShareConfigResponse parses an HTTP response from a ShareConfigWithResponse call
+func ParseShareConfigResponse(rsp *http.Response) (*ShareConfigResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ShareConfigResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest Config
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && true:
+		var dest Problem
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.ApplicationProblemJSONDefault = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseProvisionContainerResponse parses an HTTP response from a ProvisionContainerWithResponse call
+func ParseProvisionContainerResponse(rsp *http.Response) (*ProvisionContainerResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ProvisionContainerResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 202:
+		var dest Job
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON202 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && true:
+		var dest Problem
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.ApplicationProblemJSONDefault = &dest
+
+	}
+
+	return response, nil
+}
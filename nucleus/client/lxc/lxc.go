@@ -0,0 +1,5 @@
+// Package lxc is a client of lxc-service, generated from its OpenAPI
+// document.
+package lxc
+
+//go:generate go tool oapi-codegen -config oapi-codegen.yaml ../../lxc-service/api/openapi.yaml
@@ -0,0 +1,5 @@
+// Package prox is a client of prox-service, generated from its OpenAPI
+// document.
+package prox
+
+//go:generate go tool oapi-codegen -config oapi-codegen.yaml ../../prox-service/api/openapi.yaml
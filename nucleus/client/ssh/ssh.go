@@ -0,0 +1,5 @@
+// Package ssh is a client of ssh-service, generated from its OpenAPI
+// document.
+package ssh
+
+//go:generate go tool oapi-codegen -config oapi-codegen.yaml ../../ssh-service/api/openapi.yaml
@@ -0,0 +1,99 @@
+// Package config loads the settings of the gateway.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Config holds the settings of the gateway.
+type Config struct {
+	Addr            string   `yaml:"addr" env:"LISTEN_ADDR" flag:"addr" usage:"address to listen on"`
+	ShutdownTimeout int      `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" usage:"seconds in-flight requests get to finish on shutdown"`
+	LogLevel        string   `yaml:"log_level" env:"LOG_LEVEL" flag:"log-level" usage:"least severe level logged: debug, info, warn or error"`
+	OTLPEndpoint    string   `yaml:"otlp_endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" flag:"otlp-endpoint" usage:"OTLP/HTTP collector spans are exported to, e.g. http://localhost:4318 (default: no tracing)"`
+	CORSOrigins     []string `yaml:"cors_origins" env:"CORS_ORIGINS" flag:"cors-origins" usage:"comma-separated origins allowed to call the API from a browser"`
+	RateLimit       int      `yaml:"rate_limit" env:"RATE_LIMIT" flag:"rate-limit" usage:"requests per minute per client IP"`
+	TrustedProxies  []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES" flag:"trusted-proxies" usage:"comma-separated load balancer addresses or CIDR ranges allowed to set X-Forwarded-For"`
+	AuthURL         string   `yaml:"auth_url" env:"AUTH_URL" flag:"auth-url" usage:"auth-service base URL"`
+	ProxServiceURL  string   `yaml:"prox_service_url" env:"PROX_SERVICE_URL" flag:"prox-service-url" usage:"prox-service base URL"`
+	LXCServiceURL   string   `yaml:"lxc_service_url" env:"LXC_SERVICE_URL" flag:"lxc-service-url" usage:"lxc-service base URL"`
+	SSHServiceURL   string   `yaml:"ssh_service_url" env:"SSH_SERVICE_URL" flag:"ssh-service-url" usage:"ssh-service base URL"`
+	JWKSURL         string   `yaml:"jwks_url" env:"JWKS_URL" flag:"jwks-url" usage:"URL of the token signing keys (default: published by auth_url)"`
+
+	PrintConfig bool `yaml:"-"`
+}
+
+// Defaults returns the configuration used when nothing is set.
+func Defaults() *Config {
+	return &Config{
+		Addr:            ":8080",
+		ShutdownTimeout: 30,
+		LogLevel:        "info",
+		CORSOrigins:     []string{"http://localhost:3000"},
+		RateLimit:       300,
+		AuthURL:         "http://localhost:9872",
+		ProxServiceURL:  "http://localhost:7790",
+		LXCServiceURL:   "http://localhost:7402",
+		SSHServiceURL:   "http://localhost:7789",
+	}
+}
+
+// complete derives the settings left empty from the others.
+func (c *Config) complete() {
+	for _, url := range []*string{&c.AuthURL, &c.ProxServiceURL, &c.LXCServiceURL, &c.SSHServiceURL} {
+		*url = strings.TrimRight(*url, "/")
+	}
+	if c.JWKSURL == "" {
+		c.JWKSURL = c.AuthURL + "/.well-known/jwks.json"
+	}
+}
+
+// Validate reports every invalid setting.
+func (c *Config) Validate() error {
+	var errs []error
+	if err := validateAddr("addr", c.Addr); err != nil {
+		errs = append(errs, err)
+	}
+	if c.ShutdownTimeout < 1 {
+		errs = append(errs, fmt.Errorf("shutdown_timeout: %d is not a positive number of seconds", c.ShutdownTimeout))
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		errs = append(errs, fmt.Errorf("log_level: %q is not debug, info, warn or error", c.LogLevel))
+	}
+	if c.OTLPEndpoint != "" {
+		if err := validateURL("otlp_endpoint", c.OTLPEndpoint); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(c.CORSOrigins) == 0 {
+		errs = append(errs, errors.New("cors_origins: required"))
+	}
+	for _, origin := range c.CORSOrigins {
+		// A wildcard cannot be combined with the session cookie
+		if err := validateURL("cors_origins", origin); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.RateLimit <= 0 {
+		errs = append(errs, fmt.Errorf("rate_limit: %d is not a positive number", c.RateLimit))
+	}
+	if err := validateProxies("trusted_proxies", c.TrustedProxies); err != nil {
+		errs = append(errs, err)
+	}
+	for _, setting := range [][2]string{
+		{"auth_url", c.AuthURL},
+		{"prox_service_url", c.ProxServiceURL},
+		{"lxc_service_url", c.LXCServiceURL},
+		{"ssh_service_url", c.SSHServiceURL},
+		{"jwks_url", c.JWKSURL},
+	} {
+		if err := validateURL(setting[0], setting[1]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
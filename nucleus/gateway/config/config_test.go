@@ -0,0 +1,40 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gateway.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("auth_url: http://auth:9872/\ncors_origins:\n  - https://nucleus.lab\n  - https://admin.nucleus.lab\n"), 0o600))
+	t.Setenv("RATE_LIMIT", "60")
+
+	cfg, err := Load([]string{"-config", path, "-ssh-service-url", "http://ssh:7789"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"https://nucleus.lab", "https://admin.nucleus.lab"}, cfg.CORSOrigins)
+	assert.Equal(t, 60, cfg.RateLimit)
+	assert.Equal(t, "http://ssh:7789", cfg.SSHServiceURL)
+	// The keys are found at auth-service unless set otherwise
+	assert.Equal(t, "http://auth:9872", cfg.AuthURL)
+	assert.Equal(t, "http://auth:9872/.well-known/jwks.json", cfg.JWKSURL)
+
+	t.Setenv("CORS_ORIGINS", "*")
+	t.Setenv("RATE_LIMIT", "0")
+	_, err = Load([]string{"-config", path})
+	assert.ErrorContains(t, err, "cors_origins")
+	assert.ErrorContains(t, err, "rate_limit")
+}
+
+func TestPrint(t *testing.T) {
+	var out strings.Builder
+	require.NoError(t, Defaults().Print(&out))
+	assert.Contains(t, out.String(), "addr: :8080")
+	assert.NotContains(t, out.String(), "printconfig")
+}
@@ -0,0 +1,187 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces secrets in the output of Print.
+const redacted = "REDACTED"
+
+// Load builds the configuration from, by increasing precedence, the
+// defaults, the YAML file, the environment (including a .env file) and
+// args, the command line without the program name. The YAML file is the
+// one given with -config or CONFIG_FILE, or config.yaml if it exists.
+//
+// Each setting is described by the tags of its Config field: yaml, env,
+// flag, usage, and secret for values Print must not show.
+func Load(args []string) (*Config, error) {
+	// A missing .env file is fine, the environment may be set otherwise
+	_ = godotenv.Load()
+
+	cfg := Defaults()
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	path := flags.String("config", os.Getenv("CONFIG_FILE"), "YAML configuration file")
+	flags.BoolVar(&cfg.PrintConfig, "print-config", false, "print the effective configuration and exit")
+
+	fields := reflect.ValueOf(cfg).Elem()
+	flagged := make(map[string]int)
+	for i := 0; i < fields.NumField(); i++ {
+		field := fields.Type().Field(i)
+		if name := field.Tag.Get("flag"); name != "" {
+			flagged[name] = i
+			flags.String(name, "", field.Tag.Get("usage"))
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if err := loadFile(cfg, *path); err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < fields.NumField(); i++ {
+		field := fields.Type().Field(i)
+		env := field.Tag.Get("env")
+		if env == "" {
+			continue
+		}
+		if raw, ok := os.LookupEnv(env); ok && raw != "" {
+			if err := set(fields.Field(i), raw); err != nil {
+				return nil, fmt.Errorf("%s: %v", env, err)
+			}
+		}
+	}
+
+	var err error
+	flags.Visit(func(f *flag.Flag) {
+		i, ok := flagged[f.Name]
+		if !ok || err != nil {
+			return
+		}
+		if e := set(fields.Field(i), f.Value.String()); e != nil {
+			err = fmt.Errorf("-%s: %v", f.Name, e)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.complete()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	explicit := path != ""
+	if !explicit {
+		path = "config.yaml"
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) && !explicit {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %v", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	// Catch misspelled settings instead of silently ignoring them
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && err != io.EOF {
+		return fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return nil
+}
+
+// set parses raw into field. Lists are comma-separated.
+func set(field reflect.Value, raw string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", raw)
+		}
+		field.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", raw)
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
+	}
+	return nil
+}
+
+// Print writes the configuration as YAML with its secrets redacted.
+func (c *Config) Print(w io.Writer) error {
+	copied := *c
+	fields := reflect.ValueOf(&copied).Elem()
+	for i := 0; i < fields.NumField(); i++ {
+		field := fields.Field(i)
+		if fields.Type().Field(i).Tag.Get("secret") == "true" && field.Kind() == reflect.String && field.String() != "" {
+			field.SetString(redacted)
+		}
+	}
+
+	out, err := yaml.Marshal(&copied)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+func validateAddr(name, addr string) error {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return fmt.Errorf("%s: %q is not a host:port address", name, addr)
+	}
+	return nil
+}
+
+func validateURL(name, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s: %q is not an http(s) URL", name, raw)
+	}
+	return nil
+}
+
+func validateProxies(name string, proxies []string) error {
+	for _, proxy := range proxies {
+		if net.ParseIP(proxy) != nil {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(proxy); err != nil {
+			return fmt.Errorf("%s: %q is not an IP address or CIDR range", name, proxy)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/Talfaza/gateway/config"
+	"github.com/Talfaza/gateway/health"
+	"github.com/Talfaza/gateway/logging"
+	"github.com/Talfaza/gateway/metrics"
+	"github.com/Talfaza/gateway/middleware"
+	"github.com/Talfaza/gateway/problem"
+	"github.com/Talfaza/gateway/proxy"
+	"github.com/Talfaza/gateway/tracing"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/cors"
+	"github.com/gofiber/fiber/v3/middleware/limiter"
+)
+
+func main() {
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	if err := logging.Setup(cfg.LogLevel); err != nil {
+		logging.Fatal("Invalid log level", "error", err)
+	}
+	if cfg.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			logging.Fatal("Failed to print configuration", "error", err)
+		}
+		return
+	}
+
+	flushSpans, err := tracing.Setup("gateway", cfg.OTLPEndpoint)
+	if err != nil {
+		logging.Fatal("Failed to set up tracing", "error", err)
+	}
+
+	middleware.Keys = middleware.NewKeySet(cfg.JWKSURL)
+	middleware.Tokens = middleware.NewIntrospector(cfg.AuthURL + "/auth/tokens/introspect")
+	middleware.Revoked.URL = cfg.AuthURL + "/auth/sessions/revoked"
+
+	fiberConfig := fiber.Config{ErrorHandler: problem.Handler}
+	if len(cfg.TrustedProxies) > 0 {
+		fiberConfig.TrustProxy = true
+		fiberConfig.TrustProxyConfig = fiber.TrustProxyConfig{Proxies: cfg.TrustedProxies}
+		fiberConfig.ProxyHeader = fiber.HeaderXForwardedFor
+	}
+	app := fiber.New(fiberConfig)
+
+	auth := proxy.Backend{Name: "auth-service", URL: cfg.AuthURL}
+	prox := proxy.Backend{Name: "prox-service", URL: cfg.ProxServiceURL}
+	lxc := proxy.Backend{Name: "lxc-service", URL: cfg.LXCServiceURL}
+	ssh := proxy.Backend{Name: "ssh-service", URL: cfg.SSHServiceURL}
+
+	// Nothing can be authenticated without auth-service; the other
+	// services fail on their own
+	health.Register(app, map[string]health.Check{"auth_service": auth.Ready})
+	metrics.Register(app)
+	tracing.Register(app)
+	logging.Register(app)
+
+	app.Use(cors.New(cors.Config{
+		AllowOrigins:     cfg.CORSOrigins,
+		AllowCredentials: true,
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		ExposeHeaders:    []string{"X-Request-ID", "Retry-After", "X-Total-Count"},
+	}))
+	app.Use(limiter.New(limiter.Config{
+		Max:        cfg.RateLimit,
+		Expiration: time.Minute,
+		LimitReached: func(c fiber.Ctx) error {
+			return problem.New(fiber.StatusTooManyRequests, "Too many requests")
+		},
+	}))
+	app.Use(middleware.Forwarded)
+
+	// auth-service authenticates its own requests, most of which come
+	// before a login
+	app.All("/auth/*", auth.Forward)
+	app.Get("/.well-known/jwks.json", auth.Forward)
+
+	// The other services only see requests the gateway has authenticated
+	protected := app.Group("/", middleware.AuthRequired, middleware.ForwardIdentity)
+	protected.All("/prox", prox.Forward)
+	protected.All("/prox/*", prox.Forward)
+	protected.All("/lxc", lxc.Forward)
+	protected.All("/lxc/*", lxc.Forward)
+	protected.Post("/execute", ssh.Forward)
+
+	slog.Info("Gateway running", "addr", cfg.Addr)
+	if err := health.Serve(app, cfg.Addr, time.Duration(cfg.ShutdownTimeout)*time.Second); err != nil {
+		logging.Fatal("Server failed", "error", err)
+	}
+	if err := flushSpans(context.Background()); err != nil {
+		slog.Warn("Failed to export spans", "error", err)
+	}
+	slog.Info("Gateway stopped")
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SessionAudience is the audience of login sessions issued by
+// auth-service.
+const SessionAudience = "nucleus"
+
+var (
+	ErrNoClaims       = errors.New("request is not authenticated")
+	ErrMissingSubject = errors.New("token has no subject")
+	ErrInvalidSubject = errors.New("token subject is not a user ID")
+)
+
+// Claims is the payload of the tokens issued by auth-service. The gateway
+// does not interpret roles, organizations or scopes; it only verifies
+// them and hands them to the services.
+type Claims struct {
+	Email  string            `json:"email,omitempty"`
+	Roles  []string          `json:"roles,omitempty"`
+	Orgs   map[string]string `json:"orgs,omitempty"`
+	Scopes []string          `json:"scopes"`
+	jwt.RegisteredClaims
+}
+
+// UserID parses the subject into a user ID.
+func (c *Claims) UserID() (uint, error) {
+	if c.Subject == "" {
+		return 0, ErrMissingSubject
+	}
+	id, err := strconv.ParseUint(c.Subject, 10, strconv.IntSize)
+	if err != nil || id == 0 {
+		return 0, ErrInvalidSubject
+	}
+	return uint(id), nil
+}
+
+// ClaimsFrom returns the claims stored by AuthRequired.
+func ClaimsFrom(c fiber.Ctx) (*Claims, error) {
+	claims, ok := c.Locals("claims").(*Claims)
+	if !ok || claims == nil {
+		return nil, ErrNoClaims
+	}
+	return claims, nil
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/Talfaza/gateway/logging"
+	"github.com/Talfaza/gateway/problem"
+	"github.com/gofiber/fiber/v3"
+)
+
+// HeaderIdentity carries the verified claims of the caller to the
+// services, as base64url-encoded JSON. Services only believe it from the
+// addresses in their TRUSTED_PROXIES.
+const HeaderIdentity = "X-Nucleus-Identity"
+
+// Forwarded sets the headers the services rely on: the client address,
+// the request ID, and no identity until ForwardIdentity has verified one,
+// whatever the client sent.
+func Forwarded(c fiber.Ctx) error {
+	c.Request().Header.Del(HeaderIdentity)
+	c.Request().Header.Set(fiber.HeaderXForwardedFor, c.IP())
+	if id := logging.ID(c); id != "" {
+		c.Request().Header.Set(fiber.HeaderXRequestID, id)
+	}
+	return c.Next()
+}
+
+// ForwardIdentity passes the claims verified by AuthRequired on to the
+// service. It must run after AuthRequired.
+func ForwardIdentity(c fiber.Ctx) error {
+	claims, err := ClaimsFrom(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	identity, err := EncodeIdentity(claims)
+	if err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to forward identity")
+	}
+	c.Request().Header.Set(HeaderIdentity, identity)
+	return c.Next()
+}
+
+// EncodeIdentity encodes claims for the HeaderIdentity header.
+func EncodeIdentity(claims *Claims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload), nil
+}
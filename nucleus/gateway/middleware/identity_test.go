@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForwardIdentity(t *testing.T) {
+	// Setup: a fake auth-service publishing one Ed25519 key and no
+	// revoked sessions
+	public, private, _ := ed25519.GenerateKey(rand.Reader)
+	authServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/auth/sessions/revoked" {
+			_, _ = w.Write([]byte(`{"revoked":[]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"keys":[{"kty":"OKP","crv":"Ed25519","kid":"k1","x":"` +
+			base64.RawURLEncoding.EncodeToString(public) + `"}]}`))
+	}))
+	defer authServer.Close()
+
+	Keys = NewKeySet(authServer.URL + "/.well-known/jwks.json")
+	Revoked = &RevocationList{URL: authServer.URL + "/auth/sessions/revoked"}
+
+	// The service side: echo the identity header it received
+	app := fiber.New()
+	app.Use(Forwarded)
+	echo := func(c fiber.Ctx) error {
+		return c.SendString(c.Get(HeaderIdentity))
+	}
+	app.Get("/auth/verify", echo)
+	app.Get("/prox", AuthRequired, ForwardIdentity, echo)
+
+	request := func(path, token, forged string) (int, string) {
+		req := httptest.NewRequest("GET", path, nil)
+		if token != "" {
+			req.AddCookie(&http.Cookie{Name: "jwt", Value: token})
+		}
+		if forged != "" {
+			req.Header.Set(HeaderIdentity, forged)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		body := make([]byte, 4096)
+		n, _ := resp.Body.Read(body)
+		return resp.StatusCode, string(body[:n])
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{
+		"sub":   "7",
+		"email": "a@b.c",
+		"roles": []string{"operator"},
+		"aud":   SessionAudience,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = "k1"
+	session, _ := token.SignedString(private)
+	forged, _ := EncodeIdentity(&Claims{Roles: []string{"admin"}, RegisteredClaims: jwt.RegisteredClaims{Subject: "1"}})
+
+	t.Run("verified session", func(t *testing.T) {
+		status, identity := request("/prox", session, forged)
+		require.Equal(t, fiber.StatusOK, status)
+
+		payload, err := base64.RawURLEncoding.DecodeString(identity)
+		require.NoError(t, err)
+		var claims struct {
+			Subject string    `json:"sub"`
+			Email   string    `json:"email"`
+			Roles   []string  `json:"roles"`
+			Scopes  *[]string `json:"scopes"`
+		}
+		require.NoError(t, json.Unmarshal(payload, &claims))
+		assert.Equal(t, "7", claims.Subject)
+		assert.Equal(t, "a@b.c", claims.Email)
+		assert.Equal(t, []string{"operator"}, claims.Roles)
+		// Sessions are not limited by scope
+		assert.Nil(t, claims.Scopes)
+	})
+
+	t.Run("no credential", func(t *testing.T) {
+		status, _ := request("/prox", "", forged)
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+	})
+
+	t.Run("forged identity is dropped", func(t *testing.T) {
+		status, identity := request("/auth/verify", "", forged)
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Empty(t, identity)
+	})
+}
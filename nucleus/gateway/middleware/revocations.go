@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// revocationsTTL bounds how long a revoked session keeps working here.
+const revocationsTTL = 30 * time.Second
+
+var ErrSessionRevoked = errors.New("session was revoked")
+
+// RevocationList mirrors the list of revoked sessions auth-service
+// publishes, so a logout or password change takes effect here without a
+// call to auth-service on every request.
+type RevocationList struct {
+	URL    string
+	Client *http.Client
+
+	mu         sync.Mutex
+	revoked    map[string]struct{}
+	fetchedAt  time.Time
+	refreshing bool
+}
+
+// Revoked is the list used by AuthRequired. main sets its URL from the
+// configuration.
+var Revoked = &RevocationList{}
+
+// Contains reports whether the session with jti was revoked. If
+// auth-service cannot be reached the last known list is used.
+func (r *RevocationList) Contains(jti string) bool {
+	if jti == "" {
+		return false
+	}
+
+	// One request at a time refreshes a stale list, without the lock so
+	// the others keep checking against the previous one meanwhile
+	r.mu.Lock()
+	stale := !r.refreshing && time.Since(r.fetchedAt) >= revocationsTTL
+	if stale {
+		r.refreshing = true
+	}
+	r.mu.Unlock()
+	if stale {
+		revoked, err := r.fetch()
+		r.mu.Lock()
+		r.refreshing = false
+		r.fetchedAt = time.Now()
+		if err != nil {
+			slog.Warn("Keeping the previous revoked sessions", "error", err)
+		} else {
+			r.revoked = revoked
+		}
+		r.mu.Unlock()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.revoked[jti]
+	return ok
+}
+
+func (r *RevocationList) fetch() (map[string]struct{}, error) {
+	if r.URL == "" {
+		return nil, errors.New("no URL to fetch revoked sessions from")
+	}
+	client := r.Client
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+
+	resp, err := client.Get(r.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch revoked sessions: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch revoked sessions: status %d", resp.StatusCode)
+	}
+
+	var list struct {
+		Revoked []string `json:"revoked"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode revoked sessions: %v", err)
+	}
+
+	revoked := make(map[string]struct{}, len(list.Revoked))
+	for _, jti := range list.Revoked {
+		revoked[jti] = struct{}{}
+	}
+	return revoked, nil
+}
@@ -0,0 +1,63 @@
+// Package proxy relays requests from the gateway to the Nucleus services.
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Talfaza/gateway/logging"
+	"github.com/Talfaza/gateway/problem"
+	"github.com/Talfaza/gateway/tracing"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/proxy"
+)
+
+// Backend is a service the gateway routes to.
+type Backend struct {
+	Name string
+	// URL is the base URL, without a trailing slash
+	URL string
+}
+
+// Forward relays the request to the backend under the same path and
+// query, and relays its answer back.
+func (b Backend) Forward(c fiber.Ctx) error {
+	// The response is replaced by the backend's, so keep the headers set
+	// by the gateway's own middleware (CORS, request ID, rate limit)
+	var kept [][2]string
+	c.Response().Header.VisitAll(func(key, value []byte) {
+		kept = append(kept, [2]string{string(key), string(value)})
+	})
+
+	tracing.Inject(c)
+	if err := proxy.Do(c, b.URL+c.OriginalURL()); err != nil {
+		logging.Logger(c).Error("Failed to reach backend", "backend", b.Name, "error", err)
+		return problem.New(fiber.StatusBadGateway, b.Name+" is unavailable")
+	}
+
+	for _, header := range kept {
+		if c.Response().Header.Peek(header[0]) == nil {
+			c.Response().Header.Set(header[0], header[1])
+		}
+	}
+	return nil
+}
+
+// Ready checks that the backend answers its liveness probe.
+func (b Backend) Ready(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.URL+"/healthz", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
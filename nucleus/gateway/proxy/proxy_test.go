@@ -0,0 +1,56 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Talfaza/gateway/logging"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/cors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForward(t *testing.T) {
+	// Setup: a service echoing what it received
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.RequestURI() + " " + r.Header.Get("X-Request-ID")))
+	}))
+	defer service.Close()
+
+	app := fiber.New()
+	logging.Register(app)
+	app.Use(cors.New(cors.Config{AllowOrigins: []string{"http://localhost:3000"}}))
+	app.Use(func(c fiber.Ctx) error {
+		c.Request().Header.Set(fiber.HeaderXRequestID, logging.ID(c))
+		return c.Next()
+	})
+	app.All("/prox/*", Backend{Name: "prox-service", URL: service.URL}.Forward)
+	app.All("/down", Backend{Name: "lxc-service", URL: "http://127.0.0.1:1"}.Forward)
+
+	t.Run("relays path, query and answer", func(t *testing.T) {
+		req := httptest.NewRequest("PUT", "/prox/3?force=true", nil)
+		req.Header.Set("Origin", "http://localhost:3000")
+		req.Header.Set("X-Request-ID", "abc")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "PUT /prox/3?force=true abc", string(body))
+		assert.Equal(t, "text/plain", resp.Header.Get("Content-Type"))
+		// Headers set by the gateway survive the service's answer
+		assert.Equal(t, "abc", resp.Header.Get("X-Request-ID"))
+		assert.Equal(t, "http://localhost:3000", resp.Header.Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("service down", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/down", nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadGateway, resp.StatusCode)
+	})
+}
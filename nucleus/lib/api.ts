@@ -0,0 +1,12 @@
+// Base URL of the API gateway, which routes to every backend service
+export const API_URL = process.env.NEXT_PUBLIC_API_URL || "http://localhost:8080";
+
+// Message to show for a failed request: the fields that failed validation,
+// or the detail of the problem the service answered with.
+export function apiError(err: any, fallback: string): string {
+  const data = err?.response?.data;
+  if (Array.isArray(data?.errors) && data.errors.length > 0) {
+    return data.errors.map((e: { message: string }) => e.message).join(". ");
+  }
+  return data?.detail ?? fallback;
+}
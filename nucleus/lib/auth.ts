@@ -1,4 +1,5 @@
 import axios from 'axios';
+import { API_URL } from './api';
 
 export interface User {
   id: number;
@@ -9,7 +10,7 @@ export interface User {
 // Check if user is authenticated by verifying JWT cookie
 export async function checkAuth(): Promise<User | null> {
   try {
-    const response = await axios.get('http://localhost:9872/auth/verify', {
+    const response = await axios.get(`${API_URL}/auth/verify`, {
       withCredentials: true, // Include cookies
       validateStatus: (status) => status >= 200 && status < 300,
     });
@@ -22,7 +23,7 @@ export async function checkAuth(): Promise<User | null> {
 // Logout user
 export async function logout(): Promise<void> {
   try {
-    await axios.get('http://localhost:9872/auth/logout', {
+    await axios.get(`${API_URL}/auth/logout`, {
       withCredentials: true,
     });
     // Redirect to auth page
@@ -0,0 +1,90 @@
+// Package config loads the settings of lxc-service.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Config holds the settings of lxc-service.
+type Config struct {
+	Addr            string   `yaml:"addr" env:"LISTEN_ADDR" flag:"addr" usage:"address to listen on"`
+	ShutdownTimeout int      `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" usage:"seconds in-flight requests get to finish on shutdown"`
+	LogLevel        string   `yaml:"log_level" env:"LOG_LEVEL" flag:"log-level" usage:"least severe level logged: debug, info, warn or error"`
+	OTLPEndpoint    string   `yaml:"otlp_endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" flag:"otlp-endpoint" usage:"OTLP/HTTP collector spans are exported to, e.g. http://localhost:4318 (default: no tracing)"`
+	DSN             string   `yaml:"dsn" env:"DSN" flag:"dsn" usage:"database DSN, mysql://, postgres:// or sqlite://" secret:"true"`
+	AutoMigrate     bool     `yaml:"auto_migrate" env:"AUTO_MIGRATE" flag:"auto-migrate" usage:"apply pending database migrations at startup"`
+	TrustedProxies  []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES" flag:"trusted-proxies" usage:"comma-separated gateway addresses or CIDR ranges whose identity headers are trusted"`
+	AuthURL         string   `yaml:"auth_url" env:"AUTH_URL" flag:"auth-url" usage:"auth-service base URL, for token introspection and revoked sessions"`
+	JWKSURL         string   `yaml:"jwks_url" env:"JWKS_URL" flag:"jwks-url" usage:"URL of the token signing keys (default: published by auth_url)"`
+	ProxServiceURL  string   `yaml:"prox_service_url" env:"PROX_SERVICE_URL" flag:"prox-service-url" usage:"prox-service base URL, for the servers containers are provisioned on"`
+	SSHServiceURL   string   `yaml:"ssh_service_url" env:"SSH_SERVICE_URL" flag:"ssh-service-url" usage:"ssh-service base URL, to run the steps of provisioning jobs"`
+
+	PrintConfig bool `yaml:"-"`
+	// Args is what follows the flags on the command line, e.g. migrate up
+	Args []string `yaml:"-"`
+}
+
+// Defaults returns the configuration used when nothing is set.
+func Defaults() *Config {
+	return &Config{
+		Addr:            ":7402",
+		ShutdownTimeout: 30,
+		LogLevel:        "info",
+		AutoMigrate:     true,
+		AuthURL:         "http://localhost:9872",
+		ProxServiceURL:  "http://localhost:7790",
+		SSHServiceURL:   "http://localhost:7789",
+	}
+}
+
+// complete derives the settings left empty from the others.
+func (c *Config) complete() {
+	c.AuthURL = strings.TrimRight(c.AuthURL, "/")
+	c.ProxServiceURL = strings.TrimRight(c.ProxServiceURL, "/")
+	c.SSHServiceURL = strings.TrimRight(c.SSHServiceURL, "/")
+	if c.JWKSURL == "" {
+		c.JWKSURL = c.AuthURL + "/.well-known/jwks.json"
+	}
+}
+
+// Validate reports every invalid setting.
+func (c *Config) Validate() error {
+	var errs []error
+	if err := validateAddr("addr", c.Addr); err != nil {
+		errs = append(errs, err)
+	}
+	if c.ShutdownTimeout < 1 {
+		errs = append(errs, fmt.Errorf("shutdown_timeout: %d is not a positive number of seconds", c.ShutdownTimeout))
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		errs = append(errs, fmt.Errorf("log_level: %q is not debug, info, warn or error", c.LogLevel))
+	}
+	if c.OTLPEndpoint != "" {
+		if err := validateURL("otlp_endpoint", c.OTLPEndpoint); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if strings.TrimSpace(c.DSN) == "" {
+		errs = append(errs, errors.New("dsn: required"))
+	}
+	if err := validateProxies("trusted_proxies", c.TrustedProxies); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateURL("auth_url", c.AuthURL); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateURL("jwks_url", c.JWKSURL); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateURL("prox_service_url", c.ProxServiceURL); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateURL("ssh_service_url", c.SSHServiceURL); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
@@ -1,39 +1,39 @@
 package database
 
 import (
-    "fmt"
-    "log"
-    "os"
+    "log/slog"
 
-    "github.com/Talfaza/lxc-service/models"
-    "github.com/joho/godotenv"
-    "gorm.io/driver/mysql"
+    "github.com/Talfaza/lxc-service/logging"
     "gorm.io/gorm"
 )
 
 var DB *gorm.DB
 
-func Connect() {
-    _ = godotenv.Load()
-
-    dsn := os.Getenv("DSN")
-    if dsn == "" {
-        log.Fatal("DSN not found in .env")
-    }
-
-    database, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+// Connect opens the database described by dsn, see Open. With migrate it
+// applies the pending migrations, otherwise it refuses to run against an
+// outdated schema.
+func Connect(dsn string, migrate bool) {
+    database, err := Open(dsn)
     if err != nil {
-        log.Fatal("Failed to connect to database:", err)
+        logging.Fatal("Failed to connect to database", "error", err)
     }
 
-    fmt.Println("Database connected (lxc-service)")
+    slog.Info("Database connected")
 
-    DB = database
-    if err := DB.AutoMigrate(&models.LXCConfig{}); err != nil {
-        log.Fatalf("Failed to migrate database: %v", err)
+    if migrate {
+        if err := Migrate(database); err != nil {
+            logging.Fatal("Failed to migrate database", "error", err)
+        }
+    } else if pending, err := Pending(database, migrations); err != nil {
+        logging.Fatal("Failed to check database migrations", "error", err)
+    } else if pending > 0 {
+        logging.Fatal("Database migrations pending, run migrate up", "pending", pending)
     }
+    DB = database
 }
 
-
-
-
+// Migrate applies the pending migrations.
+func Migrate(database *gorm.DB) error {
+    _, err := Up(database, migrations)
+    return err
+}
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/Talfaza/lxc-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// schema returns the SQL SQLite keeps for every table and index, except
+// the migration bookkeeping.
+func schema(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+
+	var statements []string
+	err := db.Raw("SELECT sql FROM sqlite_master WHERE sql IS NOT NULL AND name NOT LIKE 'schema_migrations%' ORDER BY name").
+		Scan(&statements).Error
+	require.NoError(t, err)
+	return statements
+}
+
+func TestMigrationsMatchModels(t *testing.T) {
+	migrated, err := Open("sqlite://:memory:")
+	require.NoError(t, err)
+	require.NoError(t, Migrate(migrated))
+
+	legacy, err := Open("sqlite://:memory:")
+	require.NoError(t, err)
+	require.NoError(t, legacy.AutoMigrate(&models.LXCConfig{}, &models.Job{}, &models.JobStep{}))
+	assert.Equal(t, schema(t, legacy), schema(t, migrated), "a model changed without a migration")
+
+	// A database created before jobs existed gets them
+	legacy, err = Open("sqlite://:memory:")
+	require.NoError(t, err)
+	require.NoError(t, legacy.AutoMigrate(&models.LXCConfig{}))
+	require.NoError(t, Migrate(legacy))
+	assert.Equal(t, schema(t, migrated), schema(t, legacy))
+
+	_, err = Down(legacy, migrations, 1)
+	require.NoError(t, err)
+	assert.False(t, legacy.Migrator().HasTable(&models.Job{}))
+	assert.True(t, legacy.Migrator().HasTable(&models.LXCConfig{}))
+}
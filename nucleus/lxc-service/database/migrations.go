@@ -0,0 +1,72 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrations is the schema of lxc-service, oldest first. Each one works on
+// its own copy of the models as they were when it was written, so later
+// changes to package models cannot alter what it does.
+var migrations = []Migration{
+	{Version: 1, Name: "baseline", Up: baselineUp, Down: baselineDown},
+	{Version: 2, Name: "provisioning jobs", Up: jobsUp, Down: jobsDown},
+}
+
+// The baseline is the schema the service used to AutoMigrate at startup.
+// Up adopts a database created that way: AutoMigrate only adds what is
+// missing.
+type baselineLXCConfig struct {
+	gorm.Model
+	UserID   uint
+	OrgID    *uint `gorm:"index"`
+	Name     string
+	Packages string `gorm:"type:JSON"`
+}
+
+func (baselineLXCConfig) TableName() string { return "lxc_configs" }
+
+func baselineUp(tx *gorm.DB) error {
+	return tx.AutoMigrate(&baselineLXCConfig{})
+}
+
+func baselineDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&baselineLXCConfig{})
+}
+
+// Jobs run actions on the containers of Proxmox servers, one step at a time.
+type jobsJob struct {
+	gorm.Model
+	UserID   uint `gorm:"index"`
+	ConfigID *uint
+	ServerID uint
+	CTID     int
+	Action   string `gorm:"size:16"`
+	State    string `gorm:"size:16;index"`
+	Error    string
+	Steps    []jobsJobStep `gorm:"foreignKey:JobID"`
+}
+
+func (jobsJob) TableName() string { return "jobs" }
+
+type jobsJobStep struct {
+	ID         uint
+	JobID      uint   `gorm:"index"`
+	Name       string `gorm:"size:32"`
+	Command    string
+	State      string `gorm:"size:16"`
+	Output     string
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+}
+
+func (jobsJobStep) TableName() string { return "job_steps" }
+
+func jobsUp(tx *gorm.DB) error {
+	return tx.Migrator().CreateTable(&jobsJob{}, &jobsJobStep{})
+}
+
+func jobsDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&jobsJobStep{}, &jobsJob{})
+}
@@ -0,0 +1,271 @@
+// Package jobs runs provisioning jobs in the background. Each step of a
+// job is a command run on its Proxmox server through ssh-service, with
+// the credentials prox-service keeps for the server, on behalf of the
+// user who started the job.
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Talfaza/client"
+	"github.com/Talfaza/client/prox"
+	"github.com/Talfaza/client/ssh"
+	"github.com/Talfaza/lxc-service/database"
+	"github.com/Talfaza/lxc-service/logging"
+	"github.com/Talfaza/lxc-service/metrics"
+	"github.com/Talfaza/lxc-service/models"
+	"github.com/Talfaza/lxc-service/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// sshPort is where Proxmox servers take SSH connections; the port kept
+// with a server is the one of its web interface.
+const sshPort = "22"
+
+// lookupTimeout bounds getting the server from prox-service, and
+// stepTimeout a step, e.g. installing packages.
+const (
+	lookupTimeout = 10 * time.Second
+	stepTimeout   = 15 * time.Minute
+)
+
+// Runner runs jobs, one goroutine each.
+type Runner struct {
+	// ProxURL and SSHURL are the base URLs of prox-service and ssh-service
+	ProxURL string
+	SSHURL  string
+	// Client makes the requests to them, http.DefaultClient if nil
+	Client client.Doer
+
+	running sync.WaitGroup
+}
+
+// Default runs the jobs started through the API. main points it at the
+// services.
+var Default = &Runner{}
+
+// NewRunner returns a runner of jobs calling prox-service at proxURL and
+// ssh-service at sshURL.
+func NewRunner(proxURL, sshURL string) *Runner {
+	return &Runner{
+		ProxURL: proxURL,
+		SSHURL:  sshURL,
+		Client:  &http.Client{Transport: logging.Transport{Base: tracing.Transport{}}},
+	}
+}
+
+// Provision describes the container a provision job creates.
+type Provision struct {
+	// Template is the volume of the OS template, e.g.
+	// local:vztmpl/debian-12-standard_12.7-1_amd64.tar.zst
+	Template string
+	Hostname string
+	Cores    int
+	// Memory is in MiB, Disk in GiB
+	Memory int
+	Disk   int
+	// Packages maps names to versions, latest or empty for the candidate
+	Packages map[string]string
+}
+
+// ProvisionSteps creates container ctid as described by p, starts it and
+// installs its packages.
+func ProvisionSteps(ctid int, p Provision) []models.JobStep {
+	id := strconv.Itoa(ctid)
+	steps := []models.JobStep{
+		{Name: "create", Command: strings.Join([]string{
+			"pct create", id, quote(p.Template),
+			"--hostname", quote(p.Hostname),
+			"--cores", strconv.Itoa(p.Cores),
+			"--memory", strconv.Itoa(p.Memory),
+			"--rootfs", "local-lvm:" + strconv.Itoa(p.Disk),
+			"--unprivileged 1",
+			"--net0 name=eth0,bridge=vmbr0,ip=dhcp",
+		}, " ")},
+		{Name: "start", Command: "pct start " + id},
+	}
+	if len(p.Packages) == 0 {
+		return steps
+	}
+
+	names := make([]string, 0, len(p.Packages))
+	for name := range p.Packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	install := []string{"pct exec", id, "-- apt-get install -y"}
+	for _, name := range names {
+		if version := p.Packages[name]; version != "" && version != "latest" {
+			name += "=" + version
+		}
+		install = append(install, quote(name))
+	}
+	return append(steps,
+		models.JobStep{Name: "update", Command: "pct exec " + id + " -- apt-get update"},
+		models.JobStep{Name: "install", Command: strings.Join(install, " ")},
+	)
+}
+
+// ControlSteps takes action, start, stop or destroy, on container ctid.
+func ControlSteps(action string, ctid int) []models.JobStep {
+	id := strconv.Itoa(ctid)
+	switch action {
+	case models.ActionStart:
+		return []models.JobStep{{Name: "start", Command: "pct start " + id}}
+	case models.ActionStop:
+		return []models.JobStep{{Name: "stop", Command: "pct stop " + id}}
+	case models.ActionDestroy:
+		return []models.JobStep{{Name: "destroy", Command: "pct destroy " + id + " --force --purge"}}
+	}
+	panic("jobs: unknown action " + action)
+}
+
+// quote makes s a single word of a shell command.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Start runs a copy of job, saved as pending with its steps, in the
+// background. Its requests are authenticated with credential, the token or
+// session of the caller, and carry the request ID and span in ctx, see
+// logging.Detach.
+func (r *Runner) Start(ctx context.Context, job models.Job, credential string) {
+	metrics.JobState(job.Action, "", job.State)
+	job.Steps = append([]models.JobStep(nil), job.Steps...)
+	r.running.Add(1)
+	go func() {
+		defer r.running.Done()
+		r.run(ctx, &job, credential)
+	}()
+}
+
+// Wait waits for the jobs started to finish.
+func (r *Runner) Wait() {
+	r.running.Wait()
+}
+
+// run runs job under a span of its own, child of the span of the request
+// that started it, and each step under a span of the job's.
+func (r *Runner) run(ctx context.Context, job *models.Job, credential string) {
+	opts := []client.Option{client.WithToken(credential)}
+	if r.Client != nil {
+		opts = append(opts, client.WithHTTPClient(r.Client))
+	}
+
+	ctx, span := tracing.Start(ctx, "job."+job.Action,
+		attribute.Int("job.id", int(job.ID)),
+		attribute.Int("job.server_id", int(job.ServerID)),
+		attribute.Int("job.ctid", job.CTID))
+	err := r.runSteps(ctx, job, opts)
+	tracing.End(span, err)
+}
+
+func (r *Runner) runSteps(ctx context.Context, job *models.Job, opts []client.Option) error {
+	setState(ctx, job, models.JobRunning, "")
+	server, err := r.server(ctx, job.ServerID, opts)
+	if err != nil {
+		setState(ctx, job, models.JobFailed, "prox-service: "+err.Error())
+		return err
+	}
+	for i := range job.Steps {
+		step := &job.Steps[i]
+		// The command is left out of the span, like ssh-service does
+		stepCtx, span := tracing.Start(ctx, "job.step", attribute.String("job.step", step.Name))
+		err := r.runStep(stepCtx, server, step, opts)
+		tracing.End(span, err)
+		if err != nil {
+			setState(ctx, job, models.JobFailed, step.Name+": "+err.Error())
+			return err
+		}
+	}
+	setState(ctx, job, models.JobSucceeded, "")
+	return nil
+}
+
+// server gets the server of the job from prox-service, which checks the
+// user can see it.
+func (r *Runner) server(ctx context.Context, id uint, opts []client.Option) (*prox.Server, error) {
+	ctx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	defer cancel()
+
+	proxClient, err := client.NewProx(r.ProxURL, opts...)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := proxClient.GetServerWithResponse(ctx, prox.ID(id))
+	if err == nil {
+		err = client.Check(resp.HTTPResponse, resp.Body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp.JSON200, nil
+}
+
+// runStep runs the command of step on server, saving its output.
+func (r *Runner) runStep(ctx context.Context, server *prox.Server, step *models.JobStep, opts []client.Option) error {
+	started := time.Now()
+	step.State = models.JobRunning
+	step.StartedAt = &started
+	saveStep(ctx, step)
+
+	err := func() error {
+		ctx, cancel := context.WithTimeout(ctx, stepTimeout)
+		defer cancel()
+
+		sshClient, err := client.NewSSH(r.SSHURL, opts...)
+		if err != nil {
+			return err
+		}
+		resp, err := sshClient.ExecuteWithResponse(ctx, ssh.Command{
+			Host:     server.Host,
+			Port:     sshPort,
+			Username: server.Username,
+			Password: server.Password,
+			Command:  step.Command,
+		})
+		if err == nil {
+			err = client.Check(resp.HTTPResponse, resp.Body)
+		}
+		if err != nil {
+			return err
+		}
+		step.Output = resp.JSON200.Output
+		return nil
+	}()
+
+	finished := time.Now()
+	step.FinishedAt = &finished
+	step.State = models.JobSucceeded
+	if err != nil {
+		step.State = models.JobFailed
+		step.Output = err.Error()
+	}
+	saveStep(ctx, step)
+	return err
+}
+
+// setState moves job to state, failed with reason, and counts it.
+func setState(ctx context.Context, job *models.Job, state, reason string) {
+	from := job.State
+	job.State = state
+	job.Error = reason
+	err := database.DB.WithContext(ctx).Model(job).Select("State", "Error").Updates(job).Error
+	if err != nil {
+		slog.Error("Failed to save job", "job", job.ID, "state", state, "error", err)
+	}
+	metrics.JobState(job.Action, from, state)
+}
+
+func saveStep(ctx context.Context, step *models.JobStep) {
+	if err := database.DB.WithContext(ctx).Save(step).Error; err != nil {
+		slog.Error("Failed to save job step", "job", step.JobID, "step", step.Name, "error", err)
+	}
+}
@@ -1,34 +1,87 @@
 package main
 
 import (
-    "log"
+    "context"
+    "log/slog"
+    "os"
+    "time"
 
+    "github.com/Talfaza/lxc-service/api"
+    "github.com/Talfaza/lxc-service/config"
     "github.com/Talfaza/lxc-service/database"
+    "github.com/Talfaza/lxc-service/health"
+    "github.com/Talfaza/lxc-service/jobs"
+    "github.com/Talfaza/lxc-service/logging"
+    "github.com/Talfaza/lxc-service/metrics"
     "github.com/Talfaza/lxc-service/middleware"
-    "github.com/Talfaza/lxc-service/service"
+    "github.com/Talfaza/lxc-service/problem"
+    "github.com/Talfaza/lxc-service/routes"
+    "github.com/Talfaza/lxc-service/tracing"
     "github.com/gofiber/fiber/v3"
-    "github.com/gofiber/fiber/v3/middleware/cors"
 )
 
 func main() {
-    database.Connect()
+    cfg, err := config.Load(os.Args[1:])
+    if err != nil {
+        logging.Fatal("Invalid configuration", "error", err)
+    }
+    if err := logging.Setup(cfg.LogLevel); err != nil {
+        logging.Fatal("Invalid log level", "error", err)
+    }
+    if cfg.PrintConfig {
+        if err := cfg.Print(os.Stdout); err != nil {
+            logging.Fatal("Failed to print configuration", "error", err)
+        }
+        return
+    }
+    if len(cfg.Args) > 0 {
+        if cfg.Args[0] != "migrate" {
+            logging.Fatal("Unknown command", "command", cfg.Args[0])
+        }
+        if err := database.Command(cfg.DSN, cfg.Args[1:], os.Stdout); err != nil {
+            logging.Fatal("Migration failed", "error", err)
+        }
+        return
+    }
 
-    app := fiber.New()
+    flushSpans, err := tracing.Setup("lxc-service", cfg.OTLPEndpoint)
+    if err != nil {
+        logging.Fatal("Failed to set up tracing", "error", err)
+    }
 
-    app.Use(cors.New(cors.Config{
-        AllowOrigins:     []string{"http://localhost:3000"},
-        AllowCredentials: true,
-        AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-        AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-    }))
+    database.Connect(cfg.DSN, cfg.AutoMigrate)
+    middleware.Keys = middleware.NewKeySet(cfg.JWKSURL)
+    middleware.Tokens = middleware.NewIntrospector(cfg.AuthURL + "/auth/tokens/introspect")
+    middleware.Revoked.URL = cfg.AuthURL + "/auth/sessions/revoked"
+    jobs.Default = jobs.NewRunner(cfg.ProxServiceURL, cfg.SSHServiceURL)
 
-    protected := app.Group("/", middleware.AuthRequired)
-    protected.Post("/lxc", service.CreateConfig)
-    protected.Get("/lxc", service.ListConfigs)
-    protected.Delete("/lxc/:id", service.DeleteConfig)
+    fiberConfig := fiber.Config{ErrorHandler: problem.Handler}
+    if len(cfg.TrustedProxies) > 0 {
+        fiberConfig.TrustProxy = true
+        fiberConfig.TrustProxyConfig = fiber.TrustProxyConfig{Proxies: cfg.TrustedProxies}
+        fiberConfig.ProxyHeader = fiber.HeaderXForwardedFor
+    }
+    app := fiber.New(fiberConfig)
+    health.Register(app, map[string]health.Check{"database": database.Ping})
+    metrics.Register(app)
+    tracing.Register(app)
+    logging.Register(app)
+    api.Register(app)
 
-    log.Println("LXC service running on port 7402")
-    log.Fatal(app.Listen(":7402"))
+    routes.Setup(app)
+
+    slog.Info("LXC service running", "addr", cfg.Addr)
+    if err := health.Serve(app, cfg.Addr, time.Duration(cfg.ShutdownTimeout)*time.Second); err != nil {
+        logging.Fatal("Server failed", "error", err)
+    }
+    // Jobs in progress would otherwise be left running forever
+    slog.Info("Waiting for jobs to finish")
+    jobs.Default.Wait()
+    _ = database.Close()
+    if err := flushSpans(context.Background()); err != nil {
+        slog.Warn("Failed to export spans", "error", err)
+    }
+    slog.Info("Server stopped")
 }
 
 
@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Jobs counts the provisioning jobs this process started by action
+// (provision, start, stop, destroy) and the state they are in (pending,
+// running, succeeded, failed). A job moves from one state to the next, so
+// pending and running hold the jobs in progress and succeeded and failed
+// keep growing.
+var Jobs = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "nucleus_lxc_jobs",
+	Help: "Provisioning jobs by action and state.",
+}, []string{"action", "state"})
+
+// JobState moves a job of action from state from to state to, from ""
+// when the job was just created.
+func JobState(action, from, to string) {
+	if from != "" {
+		Jobs.WithLabelValues(action, from).Dec()
+	}
+	Jobs.WithLabelValues(action, to).Inc()
+}
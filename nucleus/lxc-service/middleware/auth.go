@@ -1,27 +1,72 @@
 package middleware
 
 import (
+    "strings"
+
+    "github.com/Talfaza/lxc-service/logging"
+    "github.com/Talfaza/lxc-service/problem"
     "github.com/gofiber/fiber/v3"
     "github.com/golang-jwt/jwt/v5"
-    "os"
 )
 
+// AuthRequired accepts the identity forwarded by a trusted gateway, the
+// jwt session cookie, or an Authorization: Bearer header carrying either a
+// JWT or a personal access token.
 func AuthRequired(c fiber.Ctx) error {
-    cookie := c.Cookies("jwt")
+    claims, forwarded, err := gatewayClaims(c)
+    if !forwarded {
+        raw := Credential(c)
+        if strings.HasPrefix(raw, apiTokenPrefix) {
+            claims, err = Tokens.Claims(logging.Context(c), raw)
+        } else {
+            claims, err = parseJWT(raw)
+            if err == nil && Revoked.Contains(claims.ID) {
+                err = ErrSessionRevoked
+            }
+        }
+    }
 
-    token, err := jwt.Parse(cookie, func(token *jwt.Token) (interface{}, error) {
-        return []byte(os.Getenv("JWT_SECRET")), nil
-    })
+    if err != nil {
+        return problem.New(fiber.StatusUnauthorized, "Unauthorized")
+    }
 
-    if err != nil || !token.Valid {
-        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+    if _, err := claims.UserID(); err != nil {
+        return problem.New(fiber.StatusUnauthorized, "Unauthorized")
     }
 
-    claims := token.Claims.(jwt.MapClaims)
-    c.Locals("userID", claims["sub"])
+    c.Locals("claims", claims)
     return c.Next()
 }
 
+// Credential returns the token or session cookie the caller sent, to call
+// the other services on their behalf.
+func Credential(c fiber.Ctx) string {
+    if raw := bearerToken(c); raw != "" {
+        return raw
+    }
+    return c.Cookies("jwt")
+}
 
+func bearerToken(c fiber.Ctx) string {
+    header := c.Get(fiber.HeaderAuthorization)
+    if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+        return strings.TrimSpace(header[7:])
+    }
+    return ""
+}
 
-
+func parseJWT(raw string) (*Claims, error) {
+    claims := new(Claims)
+    token, err := jwt.ParseWithClaims(raw, claims, Keys.Keyfunc,
+        jwt.WithValidMethods(Keys.Methods()),
+        // Only full sessions; auth-service also signs short-lived 2FA tokens
+        jwt.WithAudience(SessionAudience),
+    )
+    if err != nil {
+        return nil, err
+    }
+    if !token.Valid {
+        return nil, jwt.ErrTokenUnverifiable
+    }
+    return claims, nil
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SessionAudience is the audience of login sessions issued by
+// auth-service.
+const SessionAudience = "nucleus"
+
+var (
+	ErrNoClaims       = errors.New("request is not authenticated")
+	ErrMissingSubject = errors.New("token has no subject")
+	ErrInvalidSubject = errors.New("token subject is not a user ID")
+)
+
+// Claims is the payload of the tokens issued by auth-service. The subject
+// is the user ID as a decimal string, as RFC 7519 requires. Orgs maps
+// organization IDs to the user's permission in each. Scopes is only set
+// for personal access tokens; sessions are not limited by scope.
+type Claims struct {
+	Email  string            `json:"email,omitempty"`
+	Roles  []string          `json:"roles,omitempty"`
+	Orgs   map[string]string `json:"orgs,omitempty"`
+	Scopes []string          `json:"scopes,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// HasScope reports whether the credential may be used for scope.
+func (c *Claims) HasScope(scope string) bool {
+	return c.Scopes == nil || slices.Contains(c.Scopes, scope)
+}
+
+// UserID parses the subject into a user ID.
+func (c *Claims) UserID() (uint, error) {
+	if c.Subject == "" {
+		return 0, ErrMissingSubject
+	}
+	id, err := strconv.ParseUint(c.Subject, 10, strconv.IntSize)
+	if err != nil || id == 0 {
+		return 0, ErrInvalidSubject
+	}
+	return uint(id), nil
+}
+
+// ClaimsFrom returns the claims stored by AuthRequired.
+func ClaimsFrom(c fiber.Ctx) (*Claims, error) {
+	claims, ok := c.Locals("claims").(*Claims)
+	if !ok || claims == nil {
+		return nil, ErrNoClaims
+	}
+	return claims, nil
+}
+
+// UserID returns the authenticated user's ID.
+func UserID(c fiber.Ctx) (uint, error) {
+	claims, err := ClaimsFrom(c)
+	if err != nil {
+		return 0, err
+	}
+	return claims.UserID()
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The states of jobs and of their steps, in the order they go through them.
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// The actions a job takes on a container.
+const (
+	ActionProvision = "provision"
+	ActionStart     = "start"
+	ActionStop      = "stop"
+	ActionDestroy   = "destroy"
+)
+
+// Job is an action on a container of a Proxmox server, run in the
+// background as a series of steps.
+type Job struct {
+	gorm.Model
+	UserID uint `json:"user_id" gorm:"index"`
+	// ConfigID is the configuration a provision job installs, nil for the
+	// other actions
+	ConfigID *uint  `json:"config_id"`
+	ServerID uint   `json:"server_id"`
+	CTID     int    `json:"ctid"`
+	Action   string `json:"action" gorm:"size:16"`
+	State    string `json:"state" gorm:"size:16;index"`
+	Error    string `json:"error"`
+	// Steps are left out of lists of jobs
+	Steps []JobStep `json:"steps,omitempty"`
+}
+
+// JobStep is a command a job runs on the Proxmox server.
+type JobStep struct {
+	ID         uint       `json:"id"`
+	JobID      uint       `json:"job_id" gorm:"index"`
+	Name       string     `json:"name" gorm:"size:32"`
+	Command    string     `json:"command"`
+	State      string     `json:"state" gorm:"size:16"`
+	Output     string     `json:"output"`
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
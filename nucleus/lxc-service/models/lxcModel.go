@@ -7,6 +7,7 @@ import (
 type LXCConfig struct {
     gorm.Model
     UserID   uint   `json:"user_id"`
+    OrgID    *uint  `json:"org_id" gorm:"index"`
     Name     string `json:"name"`
     // JSON string persisted in MySQL JSON/TEXT column
     Packages string `json:"packages" gorm:"type:JSON"`
@@ -0,0 +1,95 @@
+// Package pagination pages, searches and sorts list endpoints with the
+// page, per_page, q and sort query parameters, as GORM scopes.
+package pagination
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Talfaza/lxc-service/problem"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultPerPage is as large as allowed: the frontend does not page
+	// yet.
+	DefaultPerPage = 100
+	MaxPerPage     = 100
+
+	// HeaderTotalCount counts the rows of every page.
+	HeaderTotalCount = "X-Total-Count"
+)
+
+// Query is the page of a list asked for.
+type Query struct {
+	Page    int
+	PerPage int
+	// Search is matched against the searchable columns, ignoring case
+	Search string
+	order  string
+}
+
+// Parse reads the query parameters of a list request. sorts maps the
+// values sort accepts to their columns, a - prefix sorting in descending
+// order. Out of range pages are clamped like the users of auth-service.
+func Parse(c fiber.Ctx, sorts map[string]string, defaultSort string) (Query, error) {
+	q := Query{Search: strings.TrimSpace(c.Query("q"))}
+	q.Page, _ = strconv.Atoi(c.Query("page", "1"))
+	q.PerPage, _ = strconv.Atoi(c.Query("per_page", strconv.Itoa(DefaultPerPage)))
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PerPage < 1 || q.PerPage > MaxPerPage {
+		q.PerPage = DefaultPerPage
+	}
+
+	name := c.Query("sort", defaultSort)
+	direction := "ASC"
+	if strings.HasPrefix(name, "-") {
+		name, direction = name[1:], "DESC"
+	}
+	column, ok := sorts[name]
+	if !ok {
+		names := make([]string, 0, len(sorts))
+		for name := range sorts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return Query{}, problem.New(fiber.StatusBadRequest, "sort must be one of "+strings.Join(names, ", ")+", prefixed with - for descending order")
+	}
+	// The ID breaks ties so pages do not overlap
+	q.order = column + " " + direction + ", id " + direction
+	return q, nil
+}
+
+// Filter scopes a query to the rows where one of the columns contains the
+// search, ignoring case.
+func (q Query) Filter(columns ...string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if q.Search == "" {
+			return db
+		}
+		// ! escapes the wildcards, backslashes are not portable across
+		// databases
+		like := "%" + strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(strings.ToLower(q.Search)) + "%"
+		conditions := make([]string, len(columns))
+		args := make([]interface{}, len(columns))
+		for i, column := range columns {
+			conditions[i] = "LOWER(" + column + ") LIKE ? ESCAPE '!'"
+			args[i] = like
+		}
+		return db.Where(strings.Join(conditions, " OR "), args...)
+	}
+}
+
+// Paginate scopes a query to the page, sorted.
+func (q Query) Paginate(db *gorm.DB) *gorm.DB {
+	return db.Order(q.order).Offset((q.Page - 1) * q.PerPage).Limit(q.PerPage)
+}
+
+// SetTotal tells how many rows there are on every page.
+func SetTotal(c fiber.Ctx, total int64) {
+	c.Set(HeaderTotalCount, strconv.FormatInt(total, 10))
+}
@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"github.com/Talfaza/lxc-service/middleware"
+	"github.com/Talfaza/lxc-service/models"
+	"github.com/Talfaza/lxc-service/service"
+	"github.com/gofiber/fiber/v3"
+)
+
+// Setup registers the API, as documented in api/openapi.yaml. Viewers can
+// only read, operators manage their own configurations and admins manage
+// everyone's. Jobs run commands on the caller's servers, so tokens
+// starting them also need the execute scope.
+func Setup(app *fiber.App) {
+	protected := app.Group("/", middleware.AuthRequired)
+	read := middleware.RequireRole(middleware.RoleAdmin, middleware.RoleOperator, middleware.RoleViewer)
+	write := middleware.RequireRole(middleware.RoleAdmin, middleware.RoleOperator)
+	readScope := middleware.RequireScope(middleware.ScopeRead)
+	writeScope := middleware.RequireScope(middleware.ScopeWrite)
+	executeScope := middleware.RequireScope(middleware.ScopeExecute)
+	admin := middleware.RequireRole(middleware.RoleAdmin)
+	protected.Post("/lxc", write, writeScope, service.CreateConfig)
+	protected.Get("/lxc", read, readScope, service.ListConfigs)
+	protected.Put("/lxc/:id/org", write, writeScope, service.ShareConfig)
+	protected.Delete("/lxc/:id", write, writeScope, service.DeleteConfig)
+	protected.Post("/lxc/:id/provision", write, writeScope, executeScope, service.ProvisionContainer)
+	protected.Post("/lxc/containers/:ctid/start", write, writeScope, executeScope, service.ControlContainer(models.ActionStart))
+	protected.Post("/lxc/containers/:ctid/stop", write, writeScope, executeScope, service.ControlContainer(models.ActionStop))
+	protected.Post("/lxc/containers/:ctid/destroy", write, writeScope, executeScope, service.ControlContainer(models.ActionDestroy))
+	protected.Get("/lxc/jobs", read, readScope, service.ListJobs)
+	protected.Get("/lxc/jobs/:id", read, readScope, service.GetJob)
+	protected.Delete("/admin/users/:id", admin, writeScope, service.DeleteUserConfigs)
+}
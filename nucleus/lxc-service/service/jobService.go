@@ -0,0 +1,184 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/Talfaza/lxc-service/database"
+	"github.com/Talfaza/lxc-service/jobs"
+	"github.com/Talfaza/lxc-service/logging"
+	"github.com/Talfaza/lxc-service/middleware"
+	"github.com/Talfaza/lxc-service/models"
+	"github.com/Talfaza/lxc-service/pagination"
+	"github.com/Talfaza/lxc-service/problem"
+	"github.com/Talfaza/lxc-service/tracing"
+	"github.com/Talfaza/lxc-service/validation"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+)
+
+// ProvisionContainer starts a job creating a container from a config the
+// caller can see on one of their Proxmox servers, starting it and
+// installing the packages of the config. It answers with the job while
+// still pending; GetJob follows its progress.
+func ProvisionContainer(c fiber.Ctx) error {
+	userID, err := middleware.UserID(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	// Left out, the container gets a core, 512 MiB of memory and 8 GiB of
+	// disk
+	body := struct {
+		ServerID uint   `json:"server_id" validate:"required"`
+		CTID     int    `json:"ctid" validate:"min=100,max=999999999"`
+		Template string `json:"template" validate:"required,max=255"`
+		Hostname string `json:"hostname" validate:"required,hostname_rfc1123,max=63"`
+		Cores    int    `json:"cores" validate:"min=1,max=512"`
+		Memory   int    `json:"memory" validate:"min=16"`
+		Disk     int    `json:"disk" validate:"min=1"`
+	}{Cores: 1, Memory: 512, Disk: 8}
+	if err := c.Bind().Body(&body); err != nil {
+		return problem.New(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if errs := validation.Struct(&body); errs != nil {
+		return problem.Invalid(errs...)
+	}
+
+	var cfg models.LXCConfig
+	if err := database.DB.WithContext(tracing.Context(c)).Scopes(readable(c, userID)).Where("id = ?", c.Params("id")).First(&cfg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem.New(fiber.StatusNotFound, "Configuration not found")
+		}
+		return problem.New(fiber.StatusInternalServerError, "Failed to load config")
+	}
+	var packages map[string]string
+	if cfg.Packages != "" {
+		if err := json.Unmarshal([]byte(cfg.Packages), &packages); err != nil {
+			return problem.New(fiber.StatusInternalServerError, "Failed to read the packages of the config")
+		}
+	}
+
+	job := models.Job{
+		UserID:   userID,
+		ConfigID: &cfg.ID,
+		ServerID: body.ServerID,
+		CTID:     body.CTID,
+		Action:   models.ActionProvision,
+		Steps: jobs.ProvisionSteps(body.CTID, jobs.Provision{
+			Template: body.Template,
+			Hostname: body.Hostname,
+			Cores:    body.Cores,
+			Memory:   body.Memory,
+			Disk:     body.Disk,
+			Packages: packages,
+		}),
+	}
+	return startJob(c, &job)
+}
+
+// ControlContainer returns the handler starting a job that takes action,
+// start, stop or destroy, on a container of one of the caller's Proxmox
+// servers.
+func ControlContainer(action string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID, err := middleware.UserID(c)
+		if err != nil {
+			return problem.New(fiber.StatusUnauthorized, "User not authenticated")
+		}
+
+		ctid, err := strconv.Atoi(c.Params("ctid"))
+		if err != nil || ctid < 100 {
+			return problem.New(fiber.StatusBadRequest, "Container ID must be a number from 100")
+		}
+		var body struct {
+			ServerID uint `json:"server_id" validate:"required"`
+		}
+		if err := c.Bind().Body(&body); err != nil {
+			return problem.New(fiber.StatusBadRequest, "Invalid request body")
+		}
+		if errs := validation.Struct(&body); errs != nil {
+			return problem.Invalid(errs...)
+		}
+
+		job := models.Job{
+			UserID:   userID,
+			ServerID: body.ServerID,
+			CTID:     ctid,
+			Action:   action,
+			Steps:    jobs.ControlSteps(action, ctid),
+		}
+		return startJob(c, &job)
+	}
+}
+
+// startJob saves job, pending, and runs it in the background with the
+// caller's credential.
+func startJob(c fiber.Ctx, job *models.Job) error {
+	job.State = models.JobPending
+	for i := range job.Steps {
+		job.Steps[i].State = models.JobPending
+	}
+	if err := database.DB.WithContext(tracing.Context(c)).Create(job).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to save job")
+	}
+
+	jobs.Default.Start(logging.Detach(c), *job, middleware.Credential(c))
+	return c.Status(fiber.StatusAccepted).JSON(job)
+}
+
+// jobSorts are the orders jobs can be listed in
+var jobSorts = map[string]string{"created": "created_at", "updated": "updated_at"}
+
+// ListJobs lists the jobs the authenticated user started, or every job for
+// an admin, newest first unless sorted otherwise, a page at a time. q
+// searches their actions and states. Their steps are left out.
+func ListJobs(c fiber.Ctx) error {
+	userID, err := middleware.UserID(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	page, err := pagination.Parse(c, jobSorts, "-created")
+	if err != nil {
+		return err
+	}
+	query := database.DB.WithContext(tracing.Context(c)).Model(&models.Job{}).
+		Scopes(own(c, userID), page.Filter("action", "state"))
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to count jobs")
+	}
+
+	var found []models.Job
+	if err := query.Scopes(page.Paginate).Find(&found).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to retrieve jobs")
+	}
+
+	pagination.SetTotal(c, total)
+	return c.JSON(found)
+}
+
+// GetJob answers a job the authenticated user started, or any job for an
+// admin, with its steps and their output so far.
+func GetJob(c fiber.Ctx) error {
+	userID, err := middleware.UserID(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	var job models.Job
+	err = database.DB.WithContext(tracing.Context(c)).Scopes(own(c, userID)).
+		Preload("Steps", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Where("id = ?", c.Params("id")).First(&job).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem.New(fiber.StatusNotFound, "Job not found")
+		}
+		return problem.New(fiber.StatusInternalServerError, "Failed to load job")
+	}
+
+	return c.JSON(job)
+}
@@ -0,0 +1,298 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Talfaza/lxc-service/apptest"
+	"github.com/Talfaza/lxc-service/jobs"
+	"github.com/Talfaza/lxc-service/metrics"
+	"github.com/Talfaza/lxc-service/middleware"
+	"github.com/Talfaza/lxc-service/models"
+	"github.com/Talfaza/lxc-service/problem"
+	"github.com/Talfaza/lxc-service/tracing"
+	"github.com/gofiber/fiber/v3"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// proxmox stands in for prox-service, which keeps server 3, and for
+// ssh-service running commands on it, failing those containing fail.
+type proxmox struct {
+	mu   sync.Mutex
+	ran  []string
+	prox *httptest.Server
+	ssh  *httptest.Server
+}
+
+func newProxmox(t *testing.T) *proxmox {
+	p := &proxmox{}
+	p.prox = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer nuc_alice", r.Header.Get("Authorization"))
+		if r.URL.Path != "/prox/3" {
+			w.Header().Set("Content-Type", problem.ContentType)
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"type":"about:blank","title":"Not Found","status":404,"detail":"Configuration not found","instance":"` + r.URL.Path + `","code":"not_found"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ID":3,"server_name":"pve","username":"root","host":"10.0.0.2","port":"8006","password":"secret"}`))
+	}))
+	p.ssh = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer nuc_alice", r.Header.Get("Authorization"))
+		var command struct {
+			Username, Password, Host, Port, Command string
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&command))
+		assert.Equal(t, "root", command.Username)
+		assert.Equal(t, "secret", command.Password)
+		assert.Equal(t, "10.0.0.2:22", command.Host+":"+command.Port)
+		p.mu.Lock()
+		p.ran = append(p.ran, command.Command)
+		p.mu.Unlock()
+
+		if strings.Contains(command.Command, "fail") {
+			w.Header().Set("Content-Type", problem.ContentType)
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte(`{"type":"about:blank","title":"Bad Gateway","status":502,"detail":"Error executing command: Process exited with status 100","instance":"/execute","code":"bad_gateway"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"output":"done"}`))
+	}))
+	t.Cleanup(p.prox.Close)
+	t.Cleanup(p.ssh.Close)
+
+	runner := jobs.Default
+	jobs.Default = &jobs.Runner{ProxURL: p.prox.URL, SSHURL: p.ssh.URL}
+	t.Cleanup(func() { jobs.Default = runner })
+	return p
+}
+
+// start makes the request starting a job as alice, with her token, and
+// waits for the job to finish.
+func start(t *testing.T, app *fiber.App, path, body string, job *models.Job) int {
+	t.Helper()
+
+	req := apptest.Request(`{"sub":"1"}`, "POST", path, body)
+	req.Header.Set("Authorization", "Bearer nuc_alice")
+	resp := apptest.Do(t, app, req)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(job))
+	jobs.Default.Wait()
+	return resp.StatusCode
+}
+
+func TestJobs(t *testing.T) {
+	app := newTestApp(t)
+	p := newProxmox(t)
+	alice := `{"sub":"1"}`
+	bob := `{"sub":"2"}`
+	admin := `{"sub":"3","roles":["admin"]}`
+	counted := func(action, state string) float64 {
+		return testutil.ToFloat64(metrics.Jobs.WithLabelValues(action, state))
+	}
+	succeeded, failed := counted(models.ActionProvision, models.JobSucceeded), counted(models.ActionStart, models.JobFailed)
+
+	status := apptest.Call(t, app, alice, "POST", "/lxc", `{"name":"web","packages":{"nginx":"1.24","curl":"latest"}}`, nil)
+	require.Equal(t, fiber.StatusCreated, status)
+
+	var job models.Job
+	status = start(t, app, "/lxc/1/provision", `{"server_id":3,"ctid":101,"template":"local:vztmpl/debian-12-standard_12.7-1_amd64.tar.zst","hostname":"web"}`, &job)
+	require.Equal(t, fiber.StatusAccepted, status)
+	assert.Equal(t, models.JobPending, job.State)
+	require.Len(t, job.Steps, 4)
+
+	status = apptest.Call(t, app, alice, "GET", "/lxc/jobs/1", "", &job)
+	require.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, models.JobSucceeded, job.State)
+	assert.Empty(t, job.Error)
+	want := []string{
+		"pct create 101 'local:vztmpl/debian-12-standard_12.7-1_amd64.tar.zst' --hostname 'web' --cores 1 --memory 512 --rootfs local-lvm:8 --unprivileged 1 --net0 name=eth0,bridge=vmbr0,ip=dhcp",
+		"pct start 101",
+		"pct exec 101 -- apt-get update",
+		"pct exec 101 -- apt-get install -y 'curl' 'nginx=1.24'",
+	}
+	assert.Equal(t, want, p.ran)
+	for i, step := range job.Steps {
+		assert.Equal(t, want[i], step.Command)
+		assert.Equal(t, models.JobSucceeded, step.State)
+		assert.Equal(t, "done", step.Output)
+		assert.NotNil(t, step.FinishedAt)
+	}
+	assert.Equal(t, succeeded+1, counted(models.ActionProvision, models.JobSucceeded))
+	assert.Zero(t, counted(models.ActionProvision, models.JobPending))
+	assert.Zero(t, counted(models.ActionProvision, models.JobRunning))
+
+	// A server prox-service does not show the user fails the job before
+	// anything runs
+	p.ran = nil
+	status = start(t, app, "/lxc/containers/101/start", `{"server_id":4}`, &job)
+	require.Equal(t, fiber.StatusAccepted, status)
+	status = apptest.Call(t, app, alice, "GET", "/lxc/jobs/2", "", &job)
+	require.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, models.JobFailed, job.State)
+	assert.Equal(t, "prox-service: Configuration not found", job.Error)
+	assert.Equal(t, models.JobPending, job.Steps[0].State)
+	assert.Empty(t, p.ran)
+	assert.Equal(t, failed+1, counted(models.ActionStart, models.JobFailed))
+
+	// So does a failing command, with what went wrong in the step
+	p.ran = nil
+	status = apptest.Call(t, app, alice, "POST", "/lxc", `{"name":"broken","packages":{"fail":"1"}}`, nil)
+	require.Equal(t, fiber.StatusCreated, status)
+	status = start(t, app, "/lxc/2/provision", `{"server_id":3,"ctid":102,"template":"local:vztmpl/debian.tar.zst","hostname":"broken","cores":2,"memory":2048,"disk":20}`, &job)
+	require.Equal(t, fiber.StatusAccepted, status)
+	status = apptest.Call(t, app, alice, "GET", "/lxc/jobs/3", "", &job)
+	require.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, models.JobFailed, job.State)
+	assert.Equal(t, "install: Error executing command: Process exited with status 100", job.Error)
+	assert.Contains(t, p.ran[0], "--cores 2 --memory 2048 --rootfs local-lvm:20")
+	assert.Equal(t, models.JobFailed, job.Steps[3].State)
+	assert.Equal(t, "Error executing command: Process exited with status 100", job.Steps[3].Output)
+
+	// Jobs are private to the user who started them, and listed newest
+	// first without their steps
+	var listed []models.Job
+	status = apptest.Call(t, app, alice, "GET", "/lxc/jobs?per_page=2", "", &listed)
+	require.Equal(t, fiber.StatusOK, status)
+	require.Len(t, listed, 2)
+	assert.Equal(t, uint(3), listed[0].ID)
+	assert.Empty(t, listed[0].Steps)
+	status = apptest.Call(t, app, alice, "GET", "/lxc/jobs?q=fail", "", &listed)
+	require.Equal(t, fiber.StatusOK, status)
+	assert.Len(t, listed, 2)
+	status = apptest.Call(t, app, bob, "GET", "/lxc/jobs", "", &listed)
+	require.Equal(t, fiber.StatusOK, status)
+	assert.Empty(t, listed)
+	assert.Equal(t, fiber.StatusNotFound, apptest.Call(t, app, bob, "GET", "/lxc/jobs/1", "", nil))
+	assert.Equal(t, fiber.StatusOK, apptest.Call(t, app, admin, "GET", "/lxc/jobs/1", "", nil))
+
+	// They go with the user
+	assert.Equal(t, fiber.StatusOK, apptest.Call(t, app, admin, "DELETE", "/admin/users/1", "", nil))
+	assert.Equal(t, fiber.StatusNotFound, apptest.Call(t, app, admin, "GET", "/lxc/jobs/1", "", nil))
+}
+
+func TestJobValidation(t *testing.T) {
+	app := newTestApp(t)
+	newProxmox(t)
+	alice := `{"sub":"1"}`
+	bob := `{"sub":"2"}`
+
+	status := apptest.Call(t, app, alice, "POST", "/lxc", `{"name":"web"}`, nil)
+	require.Equal(t, fiber.StatusCreated, status)
+
+	var invalid struct {
+		Errors []map[string]string `json:"errors"`
+	}
+	status = apptest.Call(t, app, alice, "POST", "/lxc/1/provision", `{"ctid":5,"template":"local:vztmpl/debian.tar.zst","hostname":"not a host","cores":0}`, &invalid)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, status)
+	assert.Equal(t, []map[string]string{
+		{"field": "server_id", "message": "server_id is required"},
+		{"field": "ctid", "message": "ctid must be at least 100"},
+		{"field": "hostname", "message": "hostname must be a hostname"},
+		{"field": "cores", "message": "cores must be at least 1"},
+	}, invalid.Errors)
+
+	// Another user's config is not found
+	status = apptest.Call(t, app, bob, "POST", "/lxc/1/provision", `{"server_id":3,"ctid":101,"template":"local:vztmpl/debian.tar.zst","hostname":"web"}`, nil)
+	assert.Equal(t, fiber.StatusNotFound, status)
+
+	status = apptest.Call(t, app, alice, "POST", "/lxc/containers/abc/start", `{"server_id":3}`, nil)
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	status = apptest.Call(t, app, alice, "POST", "/lxc/containers/101/start", `{}`, &invalid)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, status)
+	assert.Equal(t, []map[string]string{{"field": "server_id", "message": "server_id is required"}}, invalid.Errors)
+
+	var listed []models.Job
+	status = apptest.Call(t, app, alice, "GET", "/lxc/jobs", "", &listed)
+	require.Equal(t, fiber.StatusOK, status)
+	assert.Empty(t, listed)
+}
+
+// recorded keeps the spans of the tests in memory. The tracer of the
+// tracing package only follows the first provider set, so it is set once.
+var recorded = struct {
+	once     sync.Once
+	exporter *tracetest.InMemoryExporter
+}{exporter: tracetest.NewInMemoryExporter()}
+
+// A job is traced under the request that started it, and its steps under
+// the job.
+func TestJobSpans(t *testing.T) {
+	recorded.once.Do(func() {
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(recorded.exporter)))
+	})
+	app := apptest.New(t)
+	tracing.Register(app)
+	protected := app.Group("/", middleware.AuthRequired)
+	protected.Post("/lxc", CreateConfig)
+	protected.Post("/lxc/:id/provision", ProvisionContainer)
+	protected.Post("/lxc/containers/:ctid/start", ControlContainer(models.ActionStart))
+	newProxmox(t)
+	jobs.Default.Client = &http.Client{Transport: tracing.Transport{}}
+
+	status := apptest.Call(t, app, `{"sub":"1"}`, "POST", "/lxc", `{"name":"web","packages":{"nginx":"1.24"}}`, nil)
+	require.Equal(t, fiber.StatusCreated, status)
+	recorded.exporter.Reset()
+	var job models.Job
+	status = start(t, app, "/lxc/1/provision", `{"server_id":3,"ctid":101,"template":"local:vztmpl/debian.tar.zst","hostname":"web"}`, &job)
+	require.Equal(t, fiber.StatusAccepted, status)
+
+	spans := recorded.exporter.GetSpans()
+	named := func(name string) []tracetest.SpanStub {
+		var found []tracetest.SpanStub
+		for _, span := range spans {
+			if span.Name == name {
+				found = append(found, span)
+			}
+		}
+		return found
+	}
+	children := func(parent tracetest.SpanStub, name string) []tracetest.SpanStub {
+		var found []tracetest.SpanStub
+		for _, span := range named(name) {
+			if span.Parent.SpanID() == parent.SpanContext.SpanID() {
+				found = append(found, span)
+			}
+		}
+		return found
+	}
+	requests := named("POST /lxc/:id/provision")
+	require.Len(t, requests, 1)
+	provisions := children(requests[0], "job.provision")
+	require.Len(t, provisions, 1)
+	run := provisions[0]
+	assert.Equal(t, requests[0].SpanContext.TraceID(), run.SpanContext.TraceID())
+	assert.Equal(t, codes.Unset, run.Status.Code)
+	assert.Contains(t, run.Attributes, attribute.Int("job.ctid", 101))
+	// The server is looked up for the job, and the command of each step run
+	assert.Len(t, children(run, "GET"), 1)
+	steps := children(run, "job.step")
+	var names []string
+	for _, step := range steps {
+		names = append(names, step.Attributes[0].Value.AsString())
+		assert.Len(t, children(step, "POST"), 1, "the ssh-service call of %s", step.Attributes[0].Value.AsString())
+		assert.NotEmpty(t, children(step, "gorm.update"), "the step is saved")
+	}
+	assert.Equal(t, []string{"create", "start", "update", "install"}, names)
+
+	// A job failing is an error
+	recorded.exporter.Reset()
+	status = start(t, app, "/lxc/containers/101/start", `{"server_id":4}`, &job)
+	require.Equal(t, fiber.StatusAccepted, status)
+	spans = recorded.exporter.GetSpans()
+	starts := named("job.start")
+	require.Len(t, starts, 1)
+	assert.Equal(t, codes.Error, starts[0].Status.Code)
+	assert.Empty(t, children(starts[0], "job.step"))
+}
@@ -2,73 +2,180 @@ package service
 
 import (
     "encoding/json"
+    "errors"
     "github.com/Talfaza/lxc-service/database"
+    "github.com/Talfaza/lxc-service/middleware"
     "github.com/Talfaza/lxc-service/models"
+    "github.com/Talfaza/lxc-service/pagination"
+    "github.com/Talfaza/lxc-service/problem"
+    "github.com/Talfaza/lxc-service/tracing"
+    "github.com/Talfaza/lxc-service/validation"
     "github.com/gofiber/fiber/v3"
+    "gorm.io/gorm"
 )
 
 func CreateConfig(c fiber.Ctx) error {
     var body struct {
-        Name     string            `json:"name"`
-        Packages map[string]string `json:"packages"`
+        Name     string            `json:"name" validate:"required,max=255"`
+        Packages map[string]string `json:"packages" validate:"dive,keys,required,max=255,endkeys,max=255"`
+        OrgID    *uint             `json:"org_id"`
     }
     if err := c.Bind().Body(&body); err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+        return problem.New(fiber.StatusBadRequest, "Invalid request body")
+    }
+    if errs := validation.Struct(&body); errs != nil {
+        return problem.Invalid(errs...)
     }
 
-    userID := c.Locals("userID")
-    if userID == nil {
-        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not authenticated"})
+    userID, err := middleware.UserID(c)
+    if err != nil {
+        return problem.New(fiber.StatusUnauthorized, "User not authenticated")
+    }
+    // Sharing with an organization requires write access to it
+    if !canShareWith(c, body.OrgID) {
+        return problem.New(fiber.StatusForbidden, "Not allowed to share with this organization")
     }
     packagesJSON, _ := json.Marshal(body.Packages)
     cfg := models.LXCConfig{
-        UserID:   uint(userID.(float64)),
+        UserID:   userID,
+        OrgID:    body.OrgID,
         Name:     body.Name,
         Packages: string(packagesJSON),
     }
 
-    if err := database.DB.Create(&cfg).Error; err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save config"})
+    if err := database.DB.WithContext(tracing.Context(c)).Create(&cfg).Error; err != nil {
+        return problem.New(fiber.StatusInternalServerError, "Failed to save config")
     }
 
     return c.Status(fiber.StatusCreated).JSON(cfg)
 }
 
+// configSorts are the orders configs can be listed in
+var configSorts = map[string]string{"created": "created_at", "updated": "updated_at", "name": "name"}
+
+// ListConfigs lists the LXC configs of the authenticated user and those
+// shared with their organizations, or every config for an admin, a page
+// at a time. q searches their names
 func ListConfigs(c fiber.Ctx) error {
-    userID := c.Locals("userID")
-    if userID == nil {
-        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not authenticated"})
+    userID, err := middleware.UserID(c)
+    if err != nil {
+        return problem.New(fiber.StatusUnauthorized, "User not authenticated")
+    }
+
+    page, err := pagination.Parse(c, configSorts, "created")
+    if err != nil {
+        return err
+    }
+    query := database.DB.WithContext(tracing.Context(c)).Model(&models.LXCConfig{}).
+        Scopes(readable(c, userID), page.Filter("name"))
+
+    var total int64
+    if err := query.Count(&total).Error; err != nil {
+        return problem.New(fiber.StatusInternalServerError, "Failed to count configs")
     }
 
     var cfgs []models.LXCConfig
-    if err := database.DB.Where("user_id = ?", uint(userID.(float64))).Find(&cfgs).Error; err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve configs"})
+    if err := query.Scopes(page.Paginate).Find(&cfgs).Error; err != nil {
+        return problem.New(fiber.StatusInternalServerError, "Failed to retrieve configs")
     }
+
+    pagination.SetTotal(c, total)
     return c.JSON(cfgs)
 }
 
-// DeleteConfig deletes a specific LXC config for the authenticated user
+// DeleteConfig deletes a specific LXC config owned by the authenticated user
+// or shared with an organization they can write to, or any user's config
+// when called by an admin
 func DeleteConfig(c fiber.Ctx) error {
-    userID := c.Locals("userID")
-    if userID == nil {
-        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not authenticated"})
+    userID, err := middleware.UserID(c)
+    if err != nil {
+        return problem.New(fiber.StatusUnauthorized, "User not authenticated")
     }
 
     id := c.Params("id")
     if id == "" {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Config ID is required"})
+        return problem.New(fiber.StatusBadRequest, "Config ID is required")
     }
 
-    // Ensure the config belongs to the user before deleting
-    result := database.DB.Where("id = ? AND user_id = ?", id, uint(userID.(float64))).Delete(&models.LXCConfig{})
+    // Ensure the user can write the config before deleting
+    result := database.DB.WithContext(tracing.Context(c)).Scopes(writable(c, userID)).Where("id = ?", id).Delete(&models.LXCConfig{})
     if result.Error != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete config"})
+        return problem.New(fiber.StatusInternalServerError, "Failed to delete config")
     }
     if result.RowsAffected == 0 {
-        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Configuration not found"})
+        return problem.New(fiber.StatusNotFound, "Configuration not found")
     }
 
     return c.JSON(fiber.Map{"message": "Configuration deleted successfully"})
 }
 
+// ShareConfig moves a config the authenticated user owns, or any config
+// for an admin, to an organization they can write to, or makes it private
+// again when org_id is null or left out
+func ShareConfig(c fiber.Ctx) error {
+    userID, err := middleware.UserID(c)
+    if err != nil {
+        return problem.New(fiber.StatusUnauthorized, "User not authenticated")
+    }
+
+    var body struct {
+        OrgID *uint `json:"org_id"`
+    }
+    if err := c.Bind().Body(&body); err != nil {
+        return problem.New(fiber.StatusBadRequest, "Invalid request body")
+    }
+
+    var cfg models.LXCConfig
+    if err := database.DB.WithContext(tracing.Context(c)).Scopes(writable(c, userID)).Where("id = ?", c.Params("id")).First(&cfg).Error; err != nil {
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            return problem.New(fiber.StatusNotFound, "Configuration not found")
+        }
+        return problem.New(fiber.StatusInternalServerError, "Failed to load config")
+    }
+    if sameOrg(cfg.OrgID, body.OrgID) {
+        return c.JSON(cfg)
+    }
+    // Writers of the organization can change the config, not take it away
+    if !canMove(c, userID, cfg.UserID) {
+        return problem.New(fiber.StatusForbidden, "Only the owner can move this config")
+    }
+    if !canShareWith(c, body.OrgID) {
+        return problem.New(fiber.StatusForbidden, "Not allowed to share with this organization")
+    }
+
+    cfg.OrgID = body.OrgID
+    if err := database.DB.WithContext(tracing.Context(c)).Save(&cfg).Error; err != nil {
+        return problem.New(fiber.StatusInternalServerError, "Failed to save config")
+    }
+
+    return c.JSON(cfg)
+}
+
+// DeleteUserConfigs permanently deletes every config owned by a user,
+// and the jobs they started. auth-service calls it on behalf of an admin
+// deleting the user.
+func DeleteUserConfigs(c fiber.Ctx) error {
+    ownerID := c.Params("id")
+    if ownerID == "" {
+        return problem.New(fiber.StatusBadRequest, "User ID is required")
+    }
 
+    var deleted int64
+    err := database.DB.WithContext(tracing.Context(c)).Transaction(func(tx *gorm.DB) error {
+        owned := tx.Unscoped().Model(&models.Job{}).Select("id").Where("user_id = ?", ownerID)
+        if err := tx.Where("job_id IN (?)", owned).Delete(&models.JobStep{}).Error; err != nil {
+            return err
+        }
+        if err := tx.Unscoped().Where("user_id = ?", ownerID).Delete(&models.Job{}).Error; err != nil {
+            return err
+        }
+        result := tx.Unscoped().Where("user_id = ?", ownerID).Delete(&models.LXCConfig{})
+        deleted = result.RowsAffected
+        return result.Error
+    })
+    if err != nil {
+        return problem.New(fiber.StatusInternalServerError, "Failed to delete configs")
+    }
+
+    return c.JSON(fiber.Map{"deleted": deleted})
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Talfaza/lxc-service/apptest"
+	"github.com/Talfaza/lxc-service/middleware"
+	"github.com/Talfaza/lxc-service/models"
+	"github.com/Talfaza/lxc-service/problem"
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestApp serves the API from an in-memory SQLite database.
+func newTestApp(t *testing.T) *fiber.App {
+	t.Helper()
+
+	app := apptest.New(t)
+	protected := app.Group("/", middleware.AuthRequired)
+	protected.Post("/lxc", CreateConfig)
+	protected.Get("/lxc", ListConfigs)
+	protected.Put("/lxc/:id/org", ShareConfig)
+	protected.Delete("/lxc/:id", DeleteConfig)
+	protected.Post("/lxc/:id/provision", ProvisionContainer)
+	protected.Post("/lxc/containers/:ctid/start", ControlContainer(models.ActionStart))
+	protected.Get("/lxc/jobs", ListJobs)
+	protected.Get("/lxc/jobs/:id", GetJob)
+	protected.Delete("/admin/users/:id", DeleteUserConfigs)
+	return app
+}
+
+// list returns the config names identity can see.
+func list(t *testing.T, app *fiber.App, identity string) []string {
+	t.Helper()
+
+	var cfgs []models.LXCConfig
+	apptest.Call(t, app, identity, "GET", "/lxc", "", &cfgs)
+	names := []string{}
+	for _, cfg := range cfgs {
+		names = append(names, cfg.Name)
+	}
+	return names
+}
+
+func TestShareConfig(t *testing.T) {
+	app := newTestApp(t)
+	alice := `{"sub":"1","orgs":{"7":"write","8":"read"}}`
+	bob := `{"sub":"2","orgs":{"7":"read"}}`
+
+	status := apptest.Call(t, app, alice, "POST", "/lxc", `{"name":"web"}`, nil)
+	require.Equal(t, fiber.StatusCreated, status)
+	assert.Empty(t, list(t, app, bob))
+
+	var shared models.LXCConfig
+	status = apptest.Call(t, app, alice, "PUT", "/lxc/1/org", `{"org_id":7}`, &shared)
+	require.Equal(t, fiber.StatusOK, status)
+	require.NotNil(t, shared.OrgID)
+	assert.Equal(t, uint(7), *shared.OrgID)
+	assert.Equal(t, []string{"web"}, list(t, app, bob))
+
+	// Reading an organization is not enough to share with it, nor reading
+	// the config to unshare it
+	status = apptest.Call(t, app, alice, "PUT", "/lxc/1/org", `{"org_id":8}`, nil)
+	assert.Equal(t, fiber.StatusForbidden, status)
+	status = apptest.Call(t, app, bob, "PUT", "/lxc/1/org", `{"org_id":null}`, nil)
+	assert.Equal(t, fiber.StatusNotFound, status)
+
+	// Other writers of the organization cannot take the config away from it
+	carol := `{"sub":"3","orgs":{"7":"write","9":"write"}}`
+	status = apptest.Call(t, app, carol, "PUT", "/lxc/1/org", `{"org_id":null}`, nil)
+	assert.Equal(t, fiber.StatusForbidden, status)
+	status = apptest.Call(t, app, carol, "PUT", "/lxc/1/org", `{"org_id":9}`, nil)
+	assert.Equal(t, fiber.StatusForbidden, status)
+	status = apptest.Call(t, app, carol, "PUT", "/lxc/1/org", `{"org_id":7}`, nil)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, []string{"web"}, list(t, app, bob))
+
+	var private models.LXCConfig
+	status = apptest.Call(t, app, alice, "PUT", "/lxc/1/org", `{"org_id":null}`, &private)
+	require.Equal(t, fiber.StatusOK, status)
+	assert.Nil(t, private.OrgID)
+	assert.Empty(t, list(t, app, bob))
+	assert.Equal(t, []string{"web"}, list(t, app, alice))
+}
+
+func TestCreateConfigValidation(t *testing.T) {
+	app := newTestApp(t)
+	alice := `{"sub":"1"}`
+	long := strings.Repeat("x", 256)
+
+	var invalid struct {
+		Errors []map[string]string `json:"errors"`
+	}
+	status := apptest.Call(t, app, alice, "POST", "/lxc", `{"packages":{"nginx":"1.24"}}`, &invalid)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, status)
+	assert.Equal(t, []map[string]string{{"field": "name", "message": "name is required"}}, invalid.Errors)
+
+	status = apptest.Call(t, app, alice, "POST", "/lxc", `{"name":"`+long+`","packages":{"":"1.24","redis":"`+long+`"}}`, &invalid)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, status)
+	assert.ElementsMatch(t, []map[string]string{
+		{"field": "name", "message": "name must be at most 255 characters"},
+		{"field": "packages[]", "message": "packages[] is required"},
+		{"field": "packages[redis]", "message": "packages[redis] must be at most 255 characters"},
+	}, invalid.Errors)
+	assert.Empty(t, list(t, app, alice))
+
+	status = apptest.Call(t, app, alice, "POST", "/lxc", `{"name":"web","packages":{"nginx":"1.24"}}`, nil)
+	assert.Equal(t, fiber.StatusCreated, status)
+	assert.Equal(t, []string{"web"}, list(t, app, alice))
+}
+
+func TestListConfigs(t *testing.T) {
+	app := newTestApp(t)
+	alice := `{"sub":"1","orgs":{"7":"write"}}`
+	bob := `{"sub":"2","orgs":{"7":"read"}}`
+
+	for _, body := range []string{
+		`{"name":"web-b","packages":{"nginx":"1.24"}}`,
+		`{"name":"web-a","packages":{"nginx":"1.24"}}`,
+		`{"name":"cache","packages":{"redis":"7"},"org_id":7}`,
+	} {
+		require.Equal(t, fiber.StatusCreated, apptest.Call(t, app, alice, "POST", "/lxc", body, nil))
+	}
+
+	tests := []struct {
+		identity, query string
+		want            []string
+		total           string
+	}{
+		{alice, "", []string{"web-b", "web-a", "cache"}, "3"},
+		{alice, "?per_page=1", []string{"web-b"}, "3"},
+		{alice, "?sort=name", []string{"cache", "web-a", "web-b"}, "3"},
+		{alice, "?sort=-name&per_page=2&page=2", []string{"cache"}, "3"},
+		{alice, "?q=WEB", []string{"web-b", "web-a"}, "2"},
+		// % is not a wildcard
+		{alice, "?q=%25", []string{}, "0"},
+		// The search does not widen what the user can see
+		{bob, "?q=web", []string{}, "0"},
+		{bob, "?q=cache", []string{"cache"}, "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			resp := apptest.Do(t, app, apptest.Request(tt.identity, "GET", "/lxc"+tt.query, ""))
+			require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+			var cfgs []models.LXCConfig
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&cfgs))
+			names := []string{}
+			for _, cfg := range cfgs {
+				names = append(names, cfg.Name)
+			}
+			assert.Equal(t, tt.want, names)
+			assert.Equal(t, tt.total, resp.Header.Get("X-Total-Count"))
+		})
+	}
+
+	status := apptest.Call(t, app, alice, "GET", "/lxc?sort=packages", "", nil)
+	assert.Equal(t, fiber.StatusBadRequest, status)
+}
+
+func TestConfigProblems(t *testing.T) {
+	app := newTestApp(t)
+
+	resp := apptest.Do(t, app, apptest.Request(`{"sub":"1"}`, "DELETE", "/lxc/9", ""))
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, problem.ContentType, resp.Header.Get(fiber.HeaderContentType))
+
+	var answer map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&answer))
+	assert.Equal(t, "Not Found", answer["title"])
+	assert.Equal(t, 404.0, answer["status"])
+	assert.Equal(t, "/lxc/9", answer["instance"])
+	assert.Equal(t, problem.CodeNotFound, answer["code"])
+	assert.Equal(t, "Configuration not found", answer["detail"])
+
+	// Unauthenticated requests get a problem too
+	resp = apptest.Do(t, app, httptest.NewRequest("GET", "/lxc", nil))
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, problem.ContentType, resp.Header.Get(fiber.HeaderContentType))
+}
@@ -0,0 +1,189 @@
+// Package tracing records OpenTelemetry spans of the requests a service
+// handles and of the work done for them, and exports them over OTLP.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/Talfaza/nucleus")
+
+type contextKey struct{}
+
+// Setup exports the spans of service to the OTLP/HTTP collector at
+// endpoint, e.g. http://localhost:4318. Without an endpoint nothing is
+// recorded, but trace headers are still passed on. The function returned
+// flushes the spans not exported yet.
+func Setup(service, endpoint string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(),
+		otlptracehttp.WithEndpointURL(strings.TrimRight(endpoint, "/")+"/v1/traces"))
+	if err != nil {
+		return nil, err
+	}
+	// The sampler can be set with OTEL_TRACES_SAMPLER, by default spans are
+	// kept when the caller's are
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(service))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Register records a span for each request to the routes registered after
+// it, continuing the trace of the caller if it sent one.
+func Register(app *fiber.App) {
+	app.Use(handle)
+}
+
+func handle(c fiber.Ctx) error {
+	ctx := otel.GetTextMapPropagator().Extract(c, headerCarrier{c})
+	ctx, span := tracer.Start(ctx, c.Method(),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(c.Method()),
+			semconv.URLPath(c.Path()),
+			semconv.ClientAddress(c.IP()),
+		))
+	defer span.End()
+	c.Locals(contextKey{}, ctx)
+
+	err := c.Next()
+
+	// Errors are only turned into responses after the middleware returns
+	status := c.Response().StatusCode()
+	var ferr *fiber.Error
+	if errors.As(err, &ferr) {
+		status = ferr.Code
+	} else if err != nil {
+		status = fiber.StatusInternalServerError
+	}
+	// Only the router fails with a bare *fiber.Error, handlers with
+	// problems
+	if routeErr, ok := err.(*fiber.Error); !ok || routeErr.Code != fiber.StatusNotFound {
+		span.SetName(c.Method() + " " + c.Route().Path)
+		span.SetAttributes(semconv.HTTPRoute(c.Route().Path))
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+	if err != nil {
+		span.RecordError(err)
+	}
+	if status >= fiber.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+	return err
+}
+
+// Context returns the context of the span of the request, to start the
+// spans of the work done for it. It must not outlive the handler.
+func Context(c fiber.Ctx) context.Context {
+	if ctx, ok := c.Locals(contextKey{}).(context.Context); ok {
+		return ctx
+	}
+	return c
+}
+
+// Detach returns a context carrying the span of the request and nothing
+// else of it, for work that goes on after the handler returned.
+func Detach(c fiber.Ctx) context.Context {
+	return trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(Context(c)))
+}
+
+// Inject passes the span of the request on in its own headers, for the
+// service it is forwarded to.
+func Inject(c fiber.Ctx) {
+	otel.GetTextMapPropagator().Inject(Context(c), headerCarrier{c})
+}
+
+// Start starts a span of work done for the span in ctx. End it with End.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends span, marking it failed if err is not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// headerCarrier reads trace headers from the request and writes them to
+// it.
+type headerCarrier struct {
+	c fiber.Ctx
+}
+
+func (h headerCarrier) Get(key string) string {
+	return h.c.Get(key)
+}
+
+func (h headerCarrier) Set(key, value string) {
+	h.c.Request().Header.Set(key, value)
+}
+
+func (h headerCarrier) Keys() []string {
+	var keys []string
+	for key := range h.c.GetReqHeaders() {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Transport records a span for each request and passes it on to the
+// service called.
+type Transport struct {
+	// Base makes the requests, http.DefaultTransport if nil
+	Base http.RoundTripper
+}
+
+func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	ctx, span := tracer.Start(req.Context(), req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.URLFull(req.URL.Redacted()),
+			semconv.ServerAddress(req.URL.Hostname()),
+		))
+	defer span.End()
+
+	// A RoundTripper must not modify the request it was given
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+	return resp, nil
+}
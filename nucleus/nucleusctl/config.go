@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultURL is the address of a gateway run locally.
+const DefaultURL = "http://localhost:8080"
+
+// Config is what nucleusctl keeps between runs. NUCLEUS_URL and
+// NUCLEUS_TOKEN override the file, e.g. in CI.
+type Config struct {
+	URL string `yaml:"url"`
+	// Token is a session token or a personal access token
+	Token string `yaml:"token,omitempty"`
+}
+
+func defaultConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "nucleusctl", "config.yaml"), nil
+}
+
+// loadConfig reads the configuration file, which need not exist yet.
+func loadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if url := os.Getenv("NUCLEUS_URL"); url != "" {
+		cfg.URL = url
+	}
+	if token := os.Getenv("NUCLEUS_TOKEN"); token != "" {
+		cfg.Token = token
+	}
+	if cfg.URL == "" {
+		cfg.URL = DefaultURL
+	}
+	return cfg, nil
+}
+
+// save writes the configuration file, readable by its owner only since it
+// holds credentials.
+func (cfg *Config) save(path string) error {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Talfaza/client"
+	"github.com/Talfaza/client/lxc"
+)
+
+func (c *cli) container(args []string) error {
+	return c.subcommand("container", args, map[string]func([]string) error{
+		"start":   c.containerControl("start"),
+		"stop":    c.containerControl("stop"),
+		"destroy": c.containerControl("destroy"),
+	})
+}
+
+// containerControl returns the command starting a job that takes action,
+// start, stop or destroy, on a container.
+func (c *cli) containerControl(action string) func([]string) error {
+	return func(args []string) error {
+		fs := c.flags("container "+action, "<ctid>")
+		serverID := fs.Int("server", 0, "ID of the Proxmox server the container is on")
+		wait := fs.Bool("wait", false, "follow the job until it finishes")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		ctid, err := parseID(fs)
+		if err != nil {
+			return err
+		}
+
+		api, err := c.client()
+		if err != nil {
+			return err
+		}
+		target := lxc.ContainerTarget{ServerID: *serverID}
+		var job *lxc.Job
+		switch action {
+		case "start":
+			resp, err := api.LXC.StartContainerWithResponse(c.ctx, ctid, target)
+			if err != nil {
+				return err
+			}
+			if err := client.Check(resp.HTTPResponse, resp.Body); err != nil {
+				return fmt.Errorf("container %d: %w", ctid, err)
+			}
+			job = resp.JSON202
+		case "stop":
+			resp, err := api.LXC.StopContainerWithResponse(c.ctx, ctid, target)
+			if err != nil {
+				return err
+			}
+			if err := client.Check(resp.HTTPResponse, resp.Body); err != nil {
+				return fmt.Errorf("container %d: %w", ctid, err)
+			}
+			job = resp.JSON202
+		case "destroy":
+			resp, err := api.LXC.DestroyContainerWithResponse(c.ctx, ctid, target)
+			if err != nil {
+				return err
+			}
+			if err := client.Check(resp.HTTPResponse, resp.Body); err != nil {
+				return fmt.Errorf("container %d: %w", ctid, err)
+			}
+			job = resp.JSON202
+		}
+		return c.started(job, *wait)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Talfaza/client"
+	"github.com/Talfaza/client/lxc"
+)
+
+// pollInterval is how often job tail asks for the progress of a job.
+var pollInterval = 2 * time.Second
+
+func (c *cli) job(args []string) error {
+	return c.subcommand("job", args, map[string]func([]string) error{
+		"list": c.jobList,
+		"tail": c.jobTail,
+	})
+}
+
+func (c *cli) printJobs(v interface{}, jobs ...lxc.Job) error {
+	rows := make([][]string, len(jobs))
+	for i, job := range jobs {
+		reason := job.Error
+		if reason == "" {
+			reason = "-"
+		}
+		rows[i] = []string{
+			strconv.Itoa(job.ID), string(job.Action), strconv.Itoa(job.ServerID),
+			strconv.Itoa(job.Ctid), string(job.State), reason,
+		}
+	}
+	return c.print(v, []string{"ID", "ACTION", "SERVER", "CTID", "STATE", "ERROR"}, rows)
+}
+
+func (c *cli) jobList(args []string) error {
+	fs := c.flags("job list", "")
+	f := newListFlags(fs, "-created", "created or updated")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+	resp, err := api.LXC.ListJobsWithResponse(c.ctx, &lxc.ListJobsParams{
+		Page:    f.page,
+		PerPage: f.perPage,
+		Q:       f.search,
+		Sort:    (*lxc.ListJobsParamsSort)(f.sort),
+	})
+	if err != nil {
+		return err
+	}
+	if err := client.Check(resp.HTTPResponse, resp.Body); err != nil {
+		return err
+	}
+	jobs := []lxc.Job{}
+	if resp.JSON200 != nil {
+		jobs = *resp.JSON200
+	}
+	return c.printJobs(jobs, jobs...)
+}
+
+func (c *cli) jobTail(args []string) error {
+	fs := c.flags("job tail", "<id>")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	id, err := parseID(fs)
+	if err != nil {
+		return err
+	}
+	return c.tail(id)
+}
+
+// started prints a job just started, or follows it to its end with wait.
+func (c *cli) started(job *lxc.Job, wait bool) error {
+	if wait {
+		return c.tail(job.ID)
+	}
+	return c.printJobs(job, *job)
+}
+
+// tail polls job id until it succeeds or fails, printing each step as it
+// runs and what it wrote once finished. With -o json only the job is
+// printed, at the end. A failed job is an error.
+func (c *cli) tail(id int) error {
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	seen := map[int]lxc.JobStepState{}
+	for {
+		resp, err := api.LXC.GetJobWithResponse(c.ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := client.Check(resp.HTTPResponse, resp.Body); err != nil {
+			return fmt.Errorf("job %d: %w", id, err)
+		}
+		job := resp.JSON200
+
+		if c.format == "table" && job.Steps != nil {
+			for _, step := range *job.Steps {
+				if step.State == lxc.JobStepStatePending || seen[step.ID] == step.State {
+					continue
+				}
+				seen[step.ID] = step.State
+				fmt.Fprintf(c.out, "%s: %s\n", step.Name, step.State)
+				if step.State != lxc.JobStepStateRunning && step.Output != "" {
+					fmt.Fprint(c.out, step.Output)
+					if !strings.HasSuffix(step.Output, "\n") {
+						fmt.Fprintln(c.out)
+					}
+				}
+			}
+		}
+
+		if job.State == lxc.JobStateSucceeded || job.State == lxc.JobStateFailed {
+			if c.format == "json" {
+				if err := c.print(job, nil, nil); err != nil {
+					return err
+				}
+			} else if job.State == lxc.JobStateSucceeded {
+				fmt.Fprintf(c.out, "Job %d succeeded\n", id)
+			}
+			if job.State == lxc.JobStateFailed {
+				return fmt.Errorf("job %d failed: %s", id, job.Error)
+			}
+			return nil
+		}
+
+		select {
+		case <-c.ctx.Done():
+			return c.ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Talfaza/client"
+	"github.com/Talfaza/client/auth"
+)
+
+// sessionCookie is the cookie auth-service hands the session token in.
+const sessionCookie = "jwt"
+
+// login stores a session token, or a personal access token after checking
+// it is active. The password is read from NUCLEUS_PASSWORD or the first
+// line of stdin, so that it stays out of the shell history.
+func (c *cli) login(args []string) error {
+	fs := c.flags("login", "")
+	url := fs.String("url", c.config.URL, "gateway URL")
+	email := fs.String("email", "", "email of the account")
+	token := fs.String("token", "", "personal access token to log in with instead of a password")
+	code := fs.String("code", "", "TOTP or recovery code, prompted for when 2FA is enabled")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if (*email == "") == (*token == "") {
+		fs.Usage()
+		return errUsage
+	}
+
+	authClient, err := client.NewAuth(*url)
+	if err != nil {
+		return err
+	}
+	var who string
+	if *token != "" {
+		who, err = c.checkToken(authClient, *token)
+	} else {
+		who = *email
+		*token, err = c.passwordLogin(authClient, *email, *code)
+	}
+	if err != nil {
+		return err
+	}
+
+	c.config.URL = *url
+	c.config.Token = *token
+	if err := c.config.save(c.configPath); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "Logged in to %s as %s\n", *url, who)
+	return nil
+}
+
+// checkToken returns the email of the user a personal access token
+// belongs to.
+func (c *cli) checkToken(authClient *auth.ClientWithResponses, token string) (string, error) {
+	resp, err := authClient.IntrospectAPITokenWithResponse(c.ctx, auth.Token{Token: token})
+	if err != nil {
+		return "", err
+	}
+	if err := client.Check(resp.HTTPResponse, resp.Body); err != nil {
+		return "", err
+	}
+	if resp.JSON200 == nil || !resp.JSON200.Active || resp.JSON200.Email == nil {
+		return "", errors.New("the token is invalid, expired or revoked")
+	}
+	return *resp.JSON200.Email, nil
+}
+
+// passwordLogin returns a session token, going through the second step of
+// the login when the account has 2FA enabled.
+func (c *cli) passwordLogin(authClient *auth.ClientWithResponses, email, code string) (string, error) {
+	password := os.Getenv("NUCLEUS_PASSWORD")
+	if password == "" {
+		var err error
+		if password, err = c.prompt("Password: "); err != nil {
+			return "", err
+		}
+	}
+
+	resp, err := authClient.LoginWithResponse(c.ctx, auth.Credentials{Email: email, Password: password})
+	if err != nil {
+		return "", err
+	}
+	if err := client.Check(resp.HTTPResponse, resp.Body); err != nil {
+		return "", err
+	}
+	login := resp.JSON200
+	if login != nil && login.MfaEnrollmentRequired != nil && *login.MfaEnrollmentRequired {
+		return "", errors.New("the account must set up two-factor authentication in the web app first")
+	}
+	if login == nil || login.MfaRequired == nil || !*login.MfaRequired {
+		return tokenOf(resp.HTTPResponse)
+	}
+
+	if code == "" {
+		if code, err = c.prompt("Two-factor code: "); err != nil {
+			return "", err
+		}
+	}
+	body := auth.TwoFactorLoginJSONRequestBody{MfaToken: login.MfaToken}
+	// Recovery codes are longer than the 6 digits of a TOTP code
+	if len(code) > 6 {
+		body.RecoveryCode = &code
+	} else {
+		body.Code = &code
+	}
+	second, err := authClient.TwoFactorLoginWithResponse(c.ctx, body)
+	if err != nil {
+		return "", err
+	}
+	if err := client.Check(second.HTTPResponse, second.Body); err != nil {
+		return "", err
+	}
+	return tokenOf(second.HTTPResponse)
+}
+
+func tokenOf(resp *http.Response) (string, error) {
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == sessionCookie && cookie.Value != "" {
+			return cookie.Value, nil
+		}
+	}
+	return "", errors.New("auth-service did not start a session")
+}
+
+// logout forgets the stored token. A session token is revoked first; a
+// personal access token stays valid until revoked in the web app.
+func (c *cli) logout(args []string) error {
+	fs := c.flags("logout", "")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if c.config.Token == "" {
+		return errors.New("not logged in")
+	}
+
+	if !strings.HasPrefix(c.config.Token, "nuc_") {
+		authClient, err := client.NewAuth(c.config.URL)
+		if err != nil {
+			return err
+		}
+		// Logging out only looks at the session cookie
+		resp, err := authClient.LogoutWithResponse(c.ctx, func(_ context.Context, req *http.Request) error {
+			req.AddCookie(&http.Cookie{Name: sessionCookie, Value: c.config.Token})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if err := client.Check(resp.HTTPResponse, resp.Body); err != nil {
+			return err
+		}
+	}
+
+	c.config.Token = ""
+	if err := c.config.save(c.configPath); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.out, "Logged out")
+	return nil
+}
+
+// prompt asks for a line of stdin.
+func (c *cli) prompt(question string) (string, error) {
+	fmt.Fprint(c.errOut, question)
+	line, err := c.in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("reading stdin: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
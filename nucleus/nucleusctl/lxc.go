@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Talfaza/client"
+	"github.com/Talfaza/client/lxc"
+)
+
+func (c *cli) lxc(args []string) error {
+	return c.subcommand("lxc", args, map[string]func([]string) error{
+		"list":      c.lxcList,
+		"create":    c.lxcCreate,
+		"share":     c.lxcShare,
+		"rm":        c.lxcRemove,
+		"provision": c.lxcProvision,
+	})
+}
+
+// config is an LXC configuration with its packages decoded: lxc-service
+// answers them as a JSON object encoded in a string.
+type config struct {
+	ID        int               `json:"id"`
+	Name      string            `json:"name"`
+	Packages  map[string]string `json:"packages"`
+	UserID    int               `json:"user_id"`
+	OrgID     *int              `json:"org_id"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+func decodeConfig(c lxc.Config) (config, error) {
+	decoded := config{
+		ID:        c.ID,
+		Name:      c.Name,
+		Packages:  map[string]string{},
+		UserID:    c.UserID,
+		OrgID:     c.OrgID,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+	if c.Packages != "" {
+		if err := json.Unmarshal([]byte(c.Packages), &decoded.Packages); err != nil {
+			return config{}, fmt.Errorf("configuration %d: invalid packages: %w", c.ID, err)
+		}
+	}
+	return decoded, nil
+}
+
+func (c *cli) printConfigs(v interface{}, configs ...config) error {
+	rows := make([][]string, len(configs))
+	for i, cfg := range configs {
+		names := make([]string, 0, len(cfg.Packages))
+		for name := range cfg.Packages {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		packages := make([]string, len(names))
+		for j, name := range names {
+			packages[j] = name + "=" + cfg.Packages[name]
+		}
+		rows[i] = []string{strconv.Itoa(cfg.ID), cfg.Name, strings.Join(packages, ","), orgName(cfg.OrgID)}
+	}
+	return c.print(v, []string{"ID", "NAME", "PACKAGES", "ORG"}, rows)
+}
+
+func (c *cli) lxcList(args []string) error {
+	fs := c.flags("lxc list", "")
+	f := newListFlags(fs, "created", "created, updated or name")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+	resp, err := api.LXC.ListConfigsWithResponse(c.ctx, &lxc.ListConfigsParams{
+		Page:    f.page,
+		PerPage: f.perPage,
+		Q:       f.search,
+		Sort:    (*lxc.ListConfigsParamsSort)(f.sort),
+	})
+	if err != nil {
+		return err
+	}
+	if err := client.Check(resp.HTTPResponse, resp.Body); err != nil {
+		return err
+	}
+	configs := []config{}
+	if resp.JSON200 != nil {
+		for _, cfg := range *resp.JSON200 {
+			decoded, err := decodeConfig(cfg)
+			if err != nil {
+				return err
+			}
+			configs = append(configs, decoded)
+		}
+	}
+	return c.printConfigs(configs, configs...)
+}
+
+// packageFlags collects repeated -package name=version flags.
+type packageFlags map[string]string
+
+func (p packageFlags) String() string {
+	return ""
+}
+
+func (p packageFlags) Set(value string) error {
+	name, version, ok := strings.Cut(value, "=")
+	if !ok || name == "" || version == "" {
+		return fmt.Errorf("%q is not name=version", value)
+	}
+	p[name] = version
+	return nil
+}
+
+func (c *cli) lxcCreate(args []string) error {
+	fs := c.flags("lxc create", "")
+	name := fs.String("name", "", "name of the configuration")
+	packages := packageFlags{}
+	fs.Var(packages, "package", "package to install as name=version, repeatable")
+	org := fs.Int("org", 0, "ID of the organization to share the configuration with")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	input := lxc.ConfigInput{Name: *name}
+	if len(packages) > 0 {
+		p := map[string]string(packages)
+		input.Packages = &p
+	}
+	if *org != 0 {
+		input.OrgID = org
+	}
+
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+	resp, err := api.LXC.CreateConfigWithResponse(c.ctx, input)
+	if err != nil {
+		return err
+	}
+	if err := client.Check(resp.HTTPResponse, resp.Body); err != nil {
+		return err
+	}
+	created, err := decodeConfig(*resp.JSON201)
+	if err != nil {
+		return err
+	}
+	return c.printConfigs(created, created)
+}
+
+// lxcShare moves a configuration to an organization, or makes it private
+// without -org.
+func (c *cli) lxcShare(args []string) error {
+	fs := c.flags("lxc share", "<id>")
+	org := fs.Int("org", 0, "ID of the organization to share the configuration with, private if left out")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	id, err := parseID(fs)
+	if err != nil {
+		return err
+	}
+
+	var input lxc.ConfigOrg
+	if *org != 0 {
+		input.OrgID = org
+	}
+
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+	resp, err := api.LXC.ShareConfigWithResponse(c.ctx, id, input)
+	if err != nil {
+		return err
+	}
+	if err := client.Check(resp.HTTPResponse, resp.Body); err != nil {
+		return err
+	}
+	shared, err := decodeConfig(*resp.JSON200)
+	if err != nil {
+		return err
+	}
+	return c.printConfigs(shared, shared)
+}
+
+// lxcProvision starts a job creating a container from a configuration on
+// a Proxmox server. The cores, memory and disk left out are lxc-service's
+// defaults.
+func (c *cli) lxcProvision(args []string) error {
+	fs := c.flags("lxc provision", "<id>")
+	serverID := fs.Int("server", 0, "ID of the Proxmox server to create the container on")
+	ctid := fs.Int("ctid", 0, "ID of the container on the server, from 100, not taken yet")
+	template := fs.String("template", "", "volume of the OS template, e.g. local:vztmpl/debian-12-standard_12.7-1_amd64.tar.zst")
+	hostname := fs.String("hostname", "", "hostname of the container")
+	cores := fs.Int("cores", 0, "CPU cores, 1 if left out")
+	memory := fs.Int("memory", 0, "memory in MiB, 512 if left out")
+	disk := fs.Int("disk", 0, "size of the root disk in GiB, 8 if left out")
+	wait := fs.Bool("wait", false, "follow the job until it finishes")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	id, err := parseID(fs)
+	if err != nil {
+		return err
+	}
+
+	input := lxc.ProvisionInput{
+		ServerID: *serverID,
+		Ctid:     *ctid,
+		Template: *template,
+		Hostname: *hostname,
+	}
+	if *cores != 0 {
+		input.Cores = cores
+	}
+	if *memory != 0 {
+		input.Memory = memory
+	}
+	if *disk != 0 {
+		input.Disk = disk
+	}
+
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+	resp, err := api.LXC.ProvisionContainerWithResponse(c.ctx, id, input)
+	if err != nil {
+		return err
+	}
+	if err := client.Check(resp.HTTPResponse, resp.Body); err != nil {
+		return fmt.Errorf("configuration %d: %w", id, err)
+	}
+	return c.started(resp.JSON202, *wait)
+}
+
+func (c *cli) lxcRemove(args []string) error {
+	fs := c.flags("lxc rm", "<id>...")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ids, err := parseIDs(fs)
+	if err != nil {
+		return err
+	}
+
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		resp, err := api.LXC.DeleteConfigWithResponse(c.ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := client.Check(resp.HTTPResponse, resp.Body); err != nil {
+			return fmt.Errorf("configuration %d: %w", id, err)
+		}
+		if err := c.printMessage(resp.JSON200.Message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
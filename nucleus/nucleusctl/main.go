@@ -0,0 +1,172 @@
+// Command nucleusctl manages nucleus from the command line, through the
+// gateway: Proxmox servers, LXC configurations, containers and their
+// provisioning jobs, and remote commands.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/Talfaza/client"
+)
+
+const usage = `Usage: nucleusctl [flags] <command> [arguments]
+
+Commands:
+  login                     log in with an email and password, or a token
+  logout                    forget the credentials, revoking a session
+  prox list                 list Proxmox servers
+  prox add                  add a Proxmox server
+  prox update <id>          update a Proxmox server
+  prox rm <id>...           delete Proxmox servers
+  lxc list                  list LXC configurations
+  lxc create                create an LXC configuration
+  lxc share <id>            share an LXC configuration, or make it private
+  lxc rm <id>...            delete LXC configurations
+  lxc provision <id>        provision a container from an LXC configuration
+  container start <ctid>    start a container
+  container stop <ctid>     stop a container
+  container destroy <ctid>  destroy a container
+  job list                  list provisioning jobs
+  job tail <id>             follow the steps of a job until it finishes
+  run -- <command>          run a command on a host over SSH
+
+Run nucleusctl <command> -h for the flags of a command.
+
+Flags:
+`
+
+// errUsage reports bad arguments, after the usage has been printed.
+var errUsage = errors.New("invalid arguments")
+
+// cli is the state shared by the commands.
+type cli struct {
+	ctx        context.Context
+	in         *bufio.Reader
+	out        io.Writer
+	errOut     io.Writer
+	configPath string
+	config     *Config
+	// format is how results are printed, table or json
+	format string
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		if !errors.Is(err, errUsage) {
+			fmt.Fprintln(os.Stderr, "nucleusctl:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, in io.Reader, out, errOut io.Writer) error {
+	c := &cli{ctx: ctx, in: bufio.NewReader(in), out: out, errOut: errOut}
+
+	defaultPath, err := defaultConfigPath()
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("nucleusctl", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	fs.Usage = func() {
+		fmt.Fprint(errOut, usage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&c.configPath, "config", defaultPath, "configuration file holding the gateway URL and credentials")
+	fs.StringVar(&c.format, "o", "table", "output format, table or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if c.format != "table" && c.format != "json" {
+		return fmt.Errorf("unknown output format %q, use table or json", c.format)
+	}
+	if c.config, err = loadConfig(c.configPath); err != nil {
+		return err
+	}
+
+	args = fs.Args()
+	if len(args) == 0 {
+		fs.Usage()
+		return errUsage
+	}
+	switch args[0] {
+	case "login":
+		return c.login(args[1:])
+	case "logout":
+		return c.logout(args[1:])
+	case "prox":
+		return c.prox(args[1:])
+	case "lxc":
+		return c.lxc(args[1:])
+	case "container":
+		return c.container(args[1:])
+	case "job":
+		return c.job(args[1:])
+	case "run":
+		return c.run(args[1:])
+	}
+	fs.Usage()
+	return errUsage
+}
+
+// client returns a client of the gateway authenticated by the stored
+// credentials.
+func (c *cli) client() (*client.Client, error) {
+	if c.config.Token == "" {
+		return nil, errors.New("not logged in, run nucleusctl login")
+	}
+	return client.New(c.config.URL, client.WithToken(c.config.Token))
+}
+
+// flags returns the flag set of a command.
+func (c *cli) flags(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.errOut)
+	fs.Usage = func() {
+		fmt.Fprintf(c.errOut, "Usage: nucleusctl %s [flags] %s\n\nFlags:\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// subcommand dispatches args to the subcommand of a command.
+func (c *cli) subcommand(name string, args []string, subcommands map[string]func([]string) error) error {
+	if len(args) > 0 {
+		if run, ok := subcommands[args[0]]; ok {
+			return run(args[1:])
+		}
+	}
+	fmt.Fprintf(c.errOut, "Usage: nucleusctl %s <command>, see nucleusctl -h\n", name)
+	return errUsage
+}
+
+// listFlags page, search and sort a list.
+type listFlags struct {
+	page, perPage *int
+	search, sort  *string
+}
+
+// newListFlags adds the list flags to fs, sorting by sorts, by sortDefault
+// unless asked otherwise.
+func newListFlags(fs *flag.FlagSet, sortDefault, sorts string) listFlags {
+	return listFlags{
+		page:    fs.Int("page", 1, "page to list"),
+		perPage: fs.Int("per-page", 100, "rows a page holds, at most 100"),
+		search:  fs.String("q", "", "list only the rows matching this, ignoring case"),
+		sort:    fs.String("sort", sortDefault, "order of the rows: "+sorts+", prefixed with - for descending order"),
+	}
+}
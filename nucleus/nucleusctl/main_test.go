@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	runningJob = `{"ID":3,"CreatedAt":"2025-01-01T00:00:00Z","UpdatedAt":"2025-01-01T00:00:00Z","DeletedAt":null,"user_id":1,"config_id":2,"server_id":1,"ctid":101,"action":"provision","state":"running","error":"","steps":[` +
+		`{"id":1,"job_id":3,"name":"create","command":"pct create 101","state":"running","output":"","started_at":"2025-01-01T00:00:00Z","finished_at":null},` +
+		`{"id":2,"job_id":3,"name":"start","command":"pct start 101","state":"pending","output":"","started_at":null,"finished_at":null}]}`
+	succeededJob = `{"ID":3,"CreatedAt":"2025-01-01T00:00:00Z","UpdatedAt":"2025-01-01T00:01:00Z","DeletedAt":null,"user_id":1,"config_id":2,"server_id":1,"ctid":101,"action":"provision","state":"succeeded","error":"","steps":[` +
+		`{"id":1,"job_id":3,"name":"create","command":"pct create 101","state":"succeeded","output":"Logical volume created.","started_at":"2025-01-01T00:00:00Z","finished_at":"2025-01-01T00:00:30Z"},` +
+		`{"id":2,"job_id":3,"name":"start","command":"pct start 101","state":"succeeded","output":"","started_at":"2025-01-01T00:00:30Z","finished_at":"2025-01-01T00:01:00Z"}]}`
+	failedJob = `{"ID":4,"CreatedAt":"2025-01-01T00:00:00Z","UpdatedAt":"2025-01-01T00:00:00Z","DeletedAt":null,"user_id":1,"config_id":null,"server_id":1,"ctid":102,"action":"destroy","state":"failed","error":"destroy: CT 102 does not exist","steps":[` +
+		`{"id":3,"job_id":4,"name":"destroy","command":"pct destroy 102 --force --purge","state":"failed","output":"CT 102 does not exist","started_at":"2025-01-01T00:00:00Z","finished_at":"2025-01-01T00:00:01Z"}]}`
+)
+
+const server = `{"ID":1,"CreatedAt":"2025-01-01T00:00:00Z","UpdatedAt":"2025-01-01T00:00:00Z","DeletedAt":null,"user_id":1,"org_id":null,"server_name":"pve","username":"root@pam","host":"pve.lab.local","port":"8006","password":"secret"}`
+
+// newGateway fakes the gateway for a user with 2FA enabled, recording the
+// bodies of the requests it got.
+func newGateway(t *testing.T, bodies map[string]string) *httptest.Server {
+	t.Helper()
+
+	problem := func(w http.ResponseWriter, status int, code, detail string) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"type":"about:blank","title":"` + http.StatusText(status) + `","status":` + strconv.Itoa(status) + `,"detail":"` + detail + `","instance":"/","code":"` + code + `"}`))
+	}
+	answer := func(w http.ResponseWriter, status int, body string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /auth/login", func(w http.ResponseWriter, r *http.Request) {
+		var creds struct{ Email, Password string }
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&creds))
+		if creds.Password != "correct horse battery" {
+			problem(w, http.StatusUnauthorized, "invalid_credentials", "Invalid credentials")
+			return
+		}
+		answer(w, http.StatusOK, `{"mfa_required":true,"mfa_token":"mfa"}`)
+	})
+	mux.HandleFunc("POST /auth/2fa/login", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"code":"123456","mfa_token":"mfa"}` {
+			problem(w, http.StatusUnauthorized, "invalid_code", "Invalid code")
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "jwt", Value: "session"})
+		answer(w, http.StatusOK, `{"message":"Login successful"}`)
+	})
+	mux.HandleFunc("GET /auth/logout", func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("jwt")
+		require.NoError(t, err)
+		bodies["logout"] = cookie.Value
+		answer(w, http.StatusOK, `{"message":"Logged out"}`)
+	})
+
+	api := http.NewServeMux()
+	api.HandleFunc("GET /prox", func(w http.ResponseWriter, r *http.Request) {
+		bodies["GET /prox"] = r.URL.RawQuery
+		w.Header().Set("X-Total-Count", "1")
+		answer(w, http.StatusOK, `[`+server+`]`)
+	})
+	api.HandleFunc("PUT /prox/1", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies["PUT /prox/1"] = string(body)
+		answer(w, http.StatusOK, strings.Replace(server, `"pve.lab.local"`, `"10.0.0.2"`, 1))
+	})
+	api.HandleFunc("POST /lxc", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies["POST /lxc"] = string(body)
+		answer(w, http.StatusCreated, `{"ID":2,"CreatedAt":"2025-01-01T00:00:00Z","UpdatedAt":"2025-01-01T00:00:00Z","DeletedAt":null,"user_id":1,"org_id":7,"name":"web","packages":"{\"curl\":\"latest\",\"nginx\":\"1.24\"}"}`)
+	})
+	api.HandleFunc("PUT /lxc/2/org", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies["PUT /lxc/2/org"] = string(body)
+		answer(w, http.StatusOK, `{"ID":2,"CreatedAt":"2025-01-01T00:00:00Z","UpdatedAt":"2025-01-01T00:00:00Z","DeletedAt":null,"user_id":1,"org_id":null,"name":"web","packages":"{}"}`)
+	})
+	api.HandleFunc("DELETE /lxc/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "2" {
+			problem(w, http.StatusNotFound, "not_found", "Config not found")
+			return
+		}
+		answer(w, http.StatusOK, `{"message":"Config deleted"}`)
+	})
+	api.HandleFunc("POST /lxc/{id}/provision", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies["POST /lxc/2/provision"] = string(body)
+		answer(w, http.StatusAccepted, strings.Replace(runningJob, `"state":"running"`, `"state":"pending"`, 1))
+	})
+	api.HandleFunc("POST /lxc/containers/{ctid}/destroy", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies["POST /lxc/containers/"+r.PathValue("ctid")+"/destroy"] = string(body)
+		answer(w, http.StatusAccepted, strings.Replace(failedJob, `"state":"failed"`, `"state":"pending"`, 1))
+	})
+	api.HandleFunc("GET /lxc/jobs", func(w http.ResponseWriter, r *http.Request) {
+		bodies["GET /lxc/jobs"] = r.URL.RawQuery
+		answer(w, http.StatusOK, `[{"ID":4,"CreatedAt":"2025-01-01T00:00:00Z","UpdatedAt":"2025-01-01T00:00:00Z","DeletedAt":null,"user_id":1,"config_id":null,"server_id":1,"ctid":102,"action":"destroy","state":"failed","error":"destroy: CT 102 does not exist"}]`)
+	})
+	// Job 3 is running the first time it is asked for
+	polled := 0
+	api.HandleFunc("GET /lxc/jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		switch r.PathValue("id") {
+		case "3":
+			polled++
+			if polled == 1 {
+				answer(w, http.StatusOK, runningJob)
+				return
+			}
+			answer(w, http.StatusOK, succeededJob)
+		case "4":
+			answer(w, http.StatusOK, failedJob)
+		default:
+			problem(w, http.StatusNotFound, "not_found", "Job not found")
+		}
+	})
+	api.HandleFunc("POST /execute", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies["POST /execute"] = string(body)
+		answer(w, http.StatusOK, `{"output":"up 3 days\n"}`)
+	})
+	mux.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer session" {
+			problem(w, http.StatusUnauthorized, "unauthorized", "Missing token")
+			return
+		}
+		api.ServeHTTP(w, r)
+	}))
+
+	gateway := httptest.NewServer(mux)
+	t.Cleanup(gateway.Close)
+	return gateway
+}
+
+func TestCommands(t *testing.T) {
+	bodies := map[string]string{}
+	gateway := newGateway(t, bodies)
+	t.Setenv("NUCLEUS_URL", "")
+	t.Setenv("NUCLEUS_TOKEN", "")
+	t.Setenv("NUCLEUS_PASSWORD", "")
+	t.Setenv("NUCLEUS_PROX_PASSWORD", "")
+	t.Setenv("NUCLEUS_SSH_PASSWORD", "")
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+
+	nucleusctl := func(stdin string, args ...string) (string, error) {
+		var out bytes.Buffer
+		args = append([]string{"-config", configPath}, args...)
+		err := run(context.Background(), args, strings.NewReader(stdin), &out, io.Discard)
+		return out.String(), err
+	}
+
+	_, err := nucleusctl("", "prox", "list")
+	assert.EqualError(t, err, "not logged in, run nucleusctl login")
+
+	// The password and the code are read from stdin
+	_, err = nucleusctl("wrong horse battery\n", "login", "-url", gateway.URL, "-email", "alice@lab.local")
+	assert.EqualError(t, err, "Invalid credentials")
+	out, err := nucleusctl("correct horse battery\n123456\n", "login", "-url", gateway.URL, "-email", "alice@lab.local")
+	require.NoError(t, err)
+	assert.Equal(t, "Logged in to "+gateway.URL+" as alice@lab.local\n", out)
+	cfg, err := loadConfig(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, &Config{URL: gateway.URL, Token: "session"}, cfg)
+
+	out, err = nucleusctl("", "prox", "list")
+	require.NoError(t, err)
+	assert.Equal(t, "ID  NAME  HOST           PORT  USERNAME  ORG\n1   pve   pve.lab.local  8006  root@pam  -\n", out)
+	out, err = nucleusctl("", "-o", "json", "prox", "list")
+	require.NoError(t, err)
+	assert.JSONEq(t, `[`+server+`]`, out)
+	_, err = nucleusctl("", "prox", "list", "-q", "pve", "-sort", "-name", "-page", "2")
+	require.NoError(t, err)
+	assert.Equal(t, "page=2&per_page=100&q=pve&sort=-name", bodies["GET /prox"])
+
+	// Fields left out are kept, the password by leaving it out
+	_, err = nucleusctl("", "prox", "update", "-host", "10.0.0.2", "1")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"server_name":"pve","username":"root@pam","host":"10.0.0.2","port":"8006"}`, bodies["PUT /prox/1"])
+	_, err = nucleusctl("", "prox", "update", "-org", "0", "1")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"server_name":"pve","username":"root@pam","host":"pve.lab.local","port":"8006","org_id":null}`, bodies["PUT /prox/1"])
+	_, err = nucleusctl("", "prox", "update", "-host", "10.0.0.2", "3")
+	assert.EqualError(t, err, "server 3 not found")
+
+	out, err = nucleusctl("", "lxc", "create", "-name", "web", "-package", "nginx=1.24", "-package", "curl=latest", "-org", "7")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name":"web","packages":{"nginx":"1.24","curl":"latest"},"org_id":7}`, bodies["POST /lxc"])
+	assert.Equal(t, "ID  NAME  PACKAGES                ORG\n2   web   curl=latest,nginx=1.24  7\n", out)
+	_, err = nucleusctl("", "lxc", "create", "-name", "web", "-package", "nginx")
+	assert.EqualError(t, err, `invalid value "nginx" for flag -package: "nginx" is not name=version`)
+
+	out, err = nucleusctl("", "lxc", "share", "2")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"org_id":null}`, bodies["PUT /lxc/2/org"])
+	assert.Equal(t, "ID  NAME  PACKAGES  ORG\n2   web             -\n", out)
+
+	out, err = nucleusctl("", "lxc", "rm", "2", "3")
+	assert.Equal(t, "Config deleted\n", out)
+	assert.EqualError(t, err, "configuration 3: Config not found")
+
+	// Cores, memory and disk left out are the service's defaults
+	pollInterval = time.Millisecond
+	out, err = nucleusctl("", "lxc", "provision", "-server", "1", "-ctid", "101", "-template", "local:vztmpl/debian-12.tar.zst", "-hostname", "web1", "-memory", "1024", "2")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"server_id":1,"ctid":101,"template":"local:vztmpl/debian-12.tar.zst","hostname":"web1","memory":1024}`, bodies["POST /lxc/2/provision"])
+	assert.Equal(t, "ID  ACTION     SERVER  CTID  STATE    ERROR\n3   provision  1       101   pending  -\n", out)
+	out, err = nucleusctl("", "job", "tail", "3")
+	require.NoError(t, err)
+	assert.Equal(t, "create: running\ncreate: succeeded\nLogical volume created.\nstart: succeeded\nJob 3 succeeded\n", out)
+	_, err = nucleusctl("", "job", "tail", "5")
+	assert.EqualError(t, err, "job 5: Job not found")
+
+	out, err = nucleusctl("", "container", "destroy", "-server", "1", "-wait", "102")
+	assert.EqualError(t, err, "job 4 failed: destroy: CT 102 does not exist")
+	assert.JSONEq(t, `{"server_id":1}`, bodies["POST /lxc/containers/102/destroy"])
+	assert.Equal(t, "destroy: failed\nCT 102 does not exist\n", out)
+
+	out, err = nucleusctl("", "job", "list", "-q", "failed")
+	require.NoError(t, err)
+	assert.Equal(t, "page=1&per_page=100&q=failed&sort=-created", bodies["GET /lxc/jobs"])
+	assert.Equal(t, "ID  ACTION   SERVER  CTID  STATE   ERROR\n4   destroy  1       102   failed  destroy: CT 102 does not exist\n", out)
+
+	out, err = nucleusctl("", "run", "-host", "10.0.0.2", "-password", "secret", "--", "uptime", "-p")
+	require.NoError(t, err)
+	assert.Equal(t, "up 3 days\n", out)
+	assert.JSONEq(t, `{"host":"10.0.0.2","port":"22","username":"root","password":"secret","command":"uptime -p"}`, bodies["POST /execute"])
+
+	out, err = nucleusctl("", "logout")
+	require.NoError(t, err)
+	assert.Equal(t, "Logged out\n", out)
+	assert.Equal(t, "session", bodies["logout"])
+	cfg, err = loadConfig(configPath)
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Token)
+}
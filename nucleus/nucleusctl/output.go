@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// print writes v as indented JSON, or the rows as a table under header.
+func (c *cli) print(v interface{}, header []string, rows [][]string) error {
+	if c.format == "json" {
+		enc := json.NewEncoder(c.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// printMessage writes the message a service answered with.
+func (c *cli) printMessage(message string) error {
+	if c.format == "json" {
+		return c.print(map[string]string{"message": message}, nil, nil)
+	}
+	_, err := fmt.Fprintln(c.out, message)
+	return err
+}
+
+// orgName shows the organization something is shared with, if any.
+func orgName(id *int) string {
+	if id == nil {
+		return "-"
+	}
+	return fmt.Sprint(*id)
+}
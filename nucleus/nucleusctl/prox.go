@@ -0,0 +1,266 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/Talfaza/client"
+	"github.com/Talfaza/client/prox"
+)
+
+func (c *cli) prox(args []string) error {
+	return c.subcommand("prox", args, map[string]func([]string) error{
+		"list":   c.proxList,
+		"add":    c.proxAdd,
+		"update": c.proxUpdate,
+		"rm":     c.proxRemove,
+	})
+}
+
+// serverFlags are the fields of a server. The password can also come from
+// NUCLEUS_PROX_PASSWORD.
+type serverFlags struct {
+	name, username, host, port, password *string
+	org                                  *int
+}
+
+func newServerFlags(fs *flag.FlagSet, port string) serverFlags {
+	return serverFlags{
+		name:     fs.String("name", "", "name of the server"),
+		username: fs.String("username", "root@pam", "Proxmox user"),
+		host:     fs.String("host", "", "hostname or IP address"),
+		port:     fs.String("port", port, "Proxmox API port"),
+		password: fs.String("password", "", "Proxmox password, or set NUCLEUS_PROX_PASSWORD"),
+		org:      fs.Int("org", 0, "ID of the organization to share the server with"),
+	}
+}
+
+func (f serverFlags) orgID() *int {
+	if *f.org == 0 {
+		return nil
+	}
+	return f.org
+}
+
+func (f serverFlags) passwordOrEnv() string {
+	if *f.password != "" {
+		return *f.password
+	}
+	return os.Getenv("NUCLEUS_PROX_PASSWORD")
+}
+
+func (c *cli) printServers(v interface{}, servers ...prox.Server) error {
+	rows := make([][]string, len(servers))
+	for i, s := range servers {
+		rows[i] = []string{strconv.Itoa(s.ID), s.ServerName, s.Host, s.Port, s.Username, orgName(s.OrgID)}
+	}
+	return c.print(v, []string{"ID", "NAME", "HOST", "PORT", "USERNAME", "ORG"}, rows)
+}
+
+func (c *cli) proxList(args []string) error {
+	fs := c.flags("prox list", "")
+	f := newListFlags(fs, "created", "created, updated or name")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	servers, _, err := c.listServers(&prox.ListServersParams{
+		Page:    f.page,
+		PerPage: f.perPage,
+		Q:       f.search,
+		Sort:    (*prox.ListServersParamsSort)(f.sort),
+	})
+	if err != nil {
+		return err
+	}
+	return c.printServers(servers, servers...)
+}
+
+// listServers returns a page of servers and how many there are in all.
+func (c *cli) listServers(params *prox.ListServersParams) ([]prox.Server, int, error) {
+	api, err := c.client()
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := api.Prox.ListServersWithResponse(c.ctx, params)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := client.Check(resp.HTTPResponse, resp.Body); err != nil {
+		return nil, 0, err
+	}
+	total, _ := strconv.Atoi(resp.HTTPResponse.Header.Get("X-Total-Count"))
+	if resp.JSON200 == nil {
+		return []prox.Server{}, total, nil
+	}
+	return *resp.JSON200, total, nil
+}
+
+// findServer looks for a server page after page.
+func (c *cli) findServer(id int) (*prox.Server, error) {
+	page, perPage := 1, 100
+	params := &prox.ListServersParams{Page: &page, PerPage: &perPage}
+	for ; ; page++ {
+		servers, total, err := c.listServers(params)
+		if err != nil {
+			return nil, err
+		}
+		for i := range servers {
+			if servers[i].ID == id {
+				return &servers[i], nil
+			}
+		}
+		if len(servers) == 0 || page*perPage >= total {
+			return nil, fmt.Errorf("server %d not found", id)
+		}
+	}
+}
+
+func (c *cli) proxAdd(args []string) error {
+	fs := c.flags("prox add", "")
+	f := newServerFlags(fs, "8006")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+	resp, err := api.Prox.CreateServerWithResponse(c.ctx, prox.ServerInput{
+		ServerName: *f.name,
+		Username:   *f.username,
+		Host:       *f.host,
+		Port:       *f.port,
+		Password:   f.passwordOrEnv(),
+		OrgID:      f.orgID(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := client.Check(resp.HTTPResponse, resp.Body); err != nil {
+		return err
+	}
+	return c.printServers(resp.JSON201, *resp.JSON201)
+}
+
+// proxUpdate changes the fields given as flags and keeps the others, the
+// organization included.
+func (c *cli) proxUpdate(args []string) error {
+	fs := c.flags("prox update", "<id>")
+	f := newServerFlags(fs, "")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	id, err := parseID(fs)
+	if err != nil {
+		return err
+	}
+
+	current, err := c.findServer(id)
+	if err != nil {
+		return err
+	}
+
+	update := prox.ServerUpdate{
+		ServerName: current.ServerName,
+		Username:   current.Username,
+		Host:       current.Host,
+		Port:       current.Port,
+	}
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "name":
+			update.ServerName = *f.name
+		case "username":
+			update.Username = *f.username
+		case "host":
+			update.Host = *f.host
+		case "port":
+			update.Port = *f.port
+		case "org":
+			// 0 makes the server private again
+			if *f.org == 0 {
+				update.OrgID.SetNull()
+			} else {
+				update.OrgID.Set(*f.org)
+			}
+		}
+	})
+	if password := f.passwordOrEnv(); password != "" {
+		update.Password = &password
+	}
+
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+	resp, err := api.Prox.UpdateServerWithResponse(c.ctx, id, update)
+	if err != nil {
+		return err
+	}
+	if err := client.Check(resp.HTTPResponse, resp.Body); err != nil {
+		return err
+	}
+	return c.printServers(resp.JSON200, *resp.JSON200)
+}
+
+func (c *cli) proxRemove(args []string) error {
+	fs := c.flags("prox rm", "<id>...")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ids, err := parseIDs(fs)
+	if err != nil {
+		return err
+	}
+
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		resp, err := api.Prox.DeleteServerWithResponse(c.ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := client.Check(resp.HTTPResponse, resp.Body); err != nil {
+			return fmt.Errorf("server %d: %w", id, err)
+		}
+		if err := c.printMessage(resp.JSON200.Message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseID returns the ID given as the only argument.
+func parseID(fs *flag.FlagSet) (int, error) {
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 0, errUsage
+	}
+	id, err := strconv.Atoi(fs.Arg(0))
+	if err != nil {
+		return 0, fmt.Errorf("invalid ID %q", fs.Arg(0))
+	}
+	return id, nil
+}
+
+// parseIDs returns the IDs given as arguments, at least one.
+func parseIDs(fs *flag.FlagSet) ([]int, error) {
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return nil, errUsage
+	}
+	ids := make([]int, fs.NArg())
+	for i, arg := range fs.Args() {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ID %q", arg)
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
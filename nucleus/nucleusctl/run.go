@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Talfaza/client"
+	"github.com/Talfaza/client/ssh"
+)
+
+// run runs a command on a host through ssh-service and prints what it
+// wrote. The password can also come from NUCLEUS_SSH_PASSWORD.
+func (c *cli) run(args []string) error {
+	fs := c.flags("run", "-- <command>")
+	host := fs.String("host", "", "hostname or IP address")
+	port := fs.String("port", "22", "SSH port")
+	username := fs.String("username", "root", "SSH user")
+	password := fs.String("password", "", "SSH password, or set NUCLEUS_SSH_PASSWORD")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+	if *password == "" {
+		*password = os.Getenv("NUCLEUS_SSH_PASSWORD")
+	}
+
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+	resp, err := api.SSH.ExecuteWithResponse(c.ctx, ssh.Command{
+		Host:     *host,
+		Port:     *port,
+		Username: *username,
+		Password: *password,
+		Command:  strings.Join(fs.Args(), " "),
+	})
+	if err != nil {
+		return err
+	}
+	if err := client.Check(resp.HTTPResponse, resp.Body); err != nil {
+		return err
+	}
+
+	if c.format == "json" {
+		return c.print(resp.JSON200, nil, nil)
+	}
+	_, err = fmt.Fprint(c.out, resp.JSON200.Output)
+	return err
+}
@@ -0,0 +1,68 @@
+// Package apptest builds apps for the tests of the other packages and
+// makes requests to them as the gateway would.
+package apptest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Talfaza/prox-service/database"
+	"github.com/Talfaza/prox-service/middleware"
+	"github.com/Talfaza/prox-service/problem"
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/require"
+)
+
+// New returns an app without routes over an in-memory SQLite database,
+// which becomes database.DB. Requests made by app.Test come from 0.0.0.0,
+// which the app trusts as the gateway, so callers are authenticated by the
+// identity header.
+func New(t *testing.T) *fiber.App {
+	t.Helper()
+
+	db, err := database.Open("sqlite://:memory:")
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(db))
+	database.DB = db
+
+	return fiber.New(fiber.Config{ErrorHandler: problem.Handler, TrustProxy: true, TrustProxyConfig: fiber.TrustProxyConfig{Proxies: []string{"0.0.0.0"}}})
+}
+
+// Request returns a request carrying identity, a JSON claims object, as
+// the gateway identity. It has none if identity is empty.
+func Request(identity, method, path, body string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if identity != "" {
+		req.Header.Set(middleware.HeaderIdentity, base64.RawURLEncoding.EncodeToString([]byte(identity)))
+	}
+	return req
+}
+
+// Do sends req to app and waits for the answer as long as it takes, rather
+// than the second app.Test allows by default.
+func Do(t *testing.T, app *fiber.App, req *http.Request) *http.Response {
+	t.Helper()
+
+	resp, err := app.Test(req, fiber.TestConfig{Timeout: 0})
+	require.NoError(t, err)
+	return resp
+}
+
+// Call makes a JSON request as identity and decodes the JSON answer into
+// out if it is not nil.
+func Call(t *testing.T, app *fiber.App, identity, method, path, body string, out interface{}) int {
+	t.Helper()
+
+	resp := Do(t, app, Request(identity, method, path, body))
+	if out != nil {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+	}
+	return resp.StatusCode
+}
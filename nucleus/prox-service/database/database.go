@@ -1,40 +1,39 @@
 package database
 
 import (
-	"fmt"
-	"log"
-	"os"
+	"log/slog"
 
-	"github.com/Talfaza/prox-service/models"
-	"github.com/joho/godotenv"
-	"gorm.io/driver/mysql"
+	"github.com/Talfaza/prox-service/logging"
 	"gorm.io/gorm"
 )
 
 var DB *gorm.DB
 
-func Connect() {
-	err := godotenv.Load()
+// Connect opens the database described by dsn, see Open. With migrate it
+// applies the pending migrations, otherwise it refuses to run against an
+// outdated schema.
+func Connect(dsn string, migrate bool) {
+	database, err := Open(dsn)
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		logging.Fatal("Failed to connect to database", "error", err)
 	}
 
-	dsn := os.Getenv("DSN")
-	if dsn == "" {
-		log.Fatal("DSN not found in .env")
-	}
+	slog.Info("Database connected")
 
-	database, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
-	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+	if migrate {
+		if err := Migrate(database); err != nil {
+			logging.Fatal("Failed to migrate database", "error", err)
+		}
+	} else if pending, err := Pending(database, migrations); err != nil {
+		logging.Fatal("Failed to check database migrations", "error", err)
+	} else if pending > 0 {
+		logging.Fatal("Database migrations pending, run migrate up", "pending", pending)
 	}
-
-	fmt.Println("Database connected :3")
-
 	DB = database
-	if err := DB.AutoMigrate(&models.ProxConfig{}); err != nil {
-		log.Fatalf("Failed to migrate database: %v", err)
-	}
-
 }
 
+// Migrate applies the pending migrations.
+func Migrate(database *gorm.DB) error {
+	_, err := Up(database, migrations)
+	return err
+}
@@ -0,0 +1,308 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Migration is one numbered step of the schema. Up applies it and Down
+// reverts it, each in a transaction. MySQL commits DDL implicitly, so a
+// migration that fails halfway there has to be cleaned up by hand.
+//
+// A migration must never change once released; write a new one instead.
+type Migration struct {
+	Version uint
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// MigrationStatus is a migration and when it was applied, nil if pending.
+type MigrationStatus struct {
+	Version   uint
+	Name      string
+	AppliedAt *time.Time
+}
+
+// schemaMigration records an applied migration.
+type schemaMigration struct {
+	Version   uint `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+// migrationLock holds at most one row, inserted by whoever is migrating.
+// The primary key makes inserting it atomic on every driver.
+type migrationLock struct {
+	ID       uint `gorm:"primaryKey;autoIncrement:false"`
+	Owner    string
+	LockedAt time.Time
+}
+
+func (migrationLock) TableName() string { return "schema_migrations_lock" }
+
+// LockTimeout is how long to wait for another process to finish migrating.
+var LockTimeout = time.Minute
+
+const lockPoll = 500 * time.Millisecond
+
+// Up applies the pending migrations in order and returns how many it
+// applied.
+func Up(db *gorm.DB, migrations []Migration) (int, error) {
+	if err := checkMigrations(migrations); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err := withLock(db, func() error {
+		applied, err := appliedMigrations(db)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := m.Up(tx); err != nil {
+					return err
+				}
+				return tx.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d %s failed: %v", m.Version, m.Name, err)
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Down reverts the last steps applied migrations and returns how many it
+// reverted.
+func Down(db *gorm.DB, migrations []Migration, steps int) (int, error) {
+	if err := checkMigrations(migrations); err != nil {
+		return 0, err
+	}
+	known := make(map[uint]Migration, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = m
+	}
+
+	count := 0
+	err := withLock(db, func() error {
+		var applied []schemaMigration
+		if err := db.Order("version DESC").Limit(steps).Find(&applied).Error; err != nil {
+			return fmt.Errorf("failed to read applied migrations: %v", err)
+		}
+		for _, a := range applied {
+			m, ok := known[a.Version]
+			if !ok {
+				return fmt.Errorf("migration %d %s is applied but unknown to this build", a.Version, a.Name)
+			}
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := m.Down(tx); err != nil {
+					return err
+				}
+				return tx.Delete(&schemaMigration{}, a.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("reverting migration %d %s failed: %v", m.Version, m.Name, err)
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Status lists every known migration, and any applied one this build does
+// not know, by version.
+func Status(db *gorm.DB, migrations []Migration) ([]MigrationStatus, error) {
+	if err := createTable(db, &schemaMigration{}); err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if a, ok := applied[m.Version]; ok {
+			status.AppliedAt = &a.AppliedAt
+			delete(applied, m.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, a := range applied {
+		statuses = append(statuses, MigrationStatus{Version: a.Version, Name: a.Name, AppliedAt: &a.AppliedAt})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Pending returns how many known migrations are not applied yet.
+func Pending(db *gorm.DB, migrations []Migration) (int, error) {
+	statuses, err := Status(db, migrations)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Command runs the migrate subcommand against the database at dsn:
+// "up", "down [steps]" or "status".
+func Command(dsn string, args []string, w io.Writer) error {
+	db, err := Open(dsn)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %v", err)
+	}
+
+	if len(args) == 0 {
+		return errors.New("usage: migrate up | down [steps] | status")
+	}
+	switch args[0] {
+	case "up":
+		count, err := Up(db, migrations)
+		fmt.Fprintf(w, "Applied %d migration(s)\n", count)
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("steps: %q is not a positive number", args[1])
+			}
+		}
+		count, err := Down(db, migrations, steps)
+		fmt.Fprintf(w, "Reverted %d migration(s)\n", count)
+		return err
+	case "status":
+		statuses, err := Status(db, migrations)
+		if err != nil {
+			return err
+		}
+		table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "VERSION\tNAME\tAPPLIED")
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(table, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		if err := table.Flush(); err != nil {
+			return err
+		}
+		var lock migrationLock
+		if !db.Migrator().HasTable(&lock) {
+			return nil
+		}
+		if err := db.Limit(1).Find(&lock).Error; err == nil && lock.Owner != "" {
+			fmt.Fprintf(w, "\nLocked by %s since %s\n", lock.Owner, lock.LockedAt.Format(time.RFC3339))
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q, want up, down or status", args[0])
+	}
+}
+
+// checkMigrations makes sure versions are positive, unique and in order.
+func checkMigrations(migrations []Migration) error {
+	var last uint
+	for _, m := range migrations {
+		if m.Version <= last {
+			return fmt.Errorf("migration %d %s is out of order", m.Version, m.Name)
+		}
+		if m.Up == nil || m.Down == nil {
+			return fmt.Errorf("migration %d %s needs both Up and Down", m.Version, m.Name)
+		}
+		last = m.Version
+	}
+	return nil
+}
+
+func appliedMigrations(db *gorm.DB) (map[uint]schemaMigration, error) {
+	var rows []schemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %v", err)
+	}
+	applied := make(map[uint]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// withLock runs fn while holding the migration lock, so replicas starting
+// together migrate one at a time and the others find nothing left to do.
+func withLock(db *gorm.DB, fn func() error) error {
+	if err := createTable(db, &schemaMigration{}); err != nil {
+		return err
+	}
+	if err := createTable(db, &migrationLock{}); err != nil {
+		return err
+	}
+
+	host, _ := os.Hostname()
+	owner := fmt.Sprintf("%s:%d", host, os.Getpid())
+	deadline := time.Now().Add(LockTimeout)
+	// Losing the race for the lock is expected, not worth logging
+	quiet := db.Session(&gorm.Session{Logger: logger.Default.LogMode(logger.Silent)})
+	for {
+		err := quiet.Create(&migrationLock{ID: 1, Owner: owner, LockedAt: time.Now()}).Error
+		if err == nil {
+			break
+		}
+
+		var holder migrationLock
+		if e := db.Limit(1).Find(&holder).Error; e != nil {
+			return fmt.Errorf("failed to take the migration lock: %v", e)
+		}
+		// Without a holder, the lock was released since the insert: try
+		// again, unless the insert keeps failing for another reason
+		if time.Now().After(deadline) {
+			if holder.Owner == "" {
+				return fmt.Errorf("failed to take the migration lock: %v", err)
+			}
+			return fmt.Errorf("migrations are locked by %s since %s; if it is no longer running, delete the row from %s",
+				holder.Owner, holder.LockedAt.Format(time.RFC3339), migrationLock{}.TableName())
+		}
+		time.Sleep(lockPoll)
+	}
+	defer db.Where("owner = ?", owner).Delete(&migrationLock{})
+
+	return fn()
+}
+
+// createTable creates the table of model if it is missing. Another
+// process may create it at the same time, which is not an error.
+func createTable(db *gorm.DB, model interface{}) error {
+	if db.Migrator().HasTable(model) {
+		return nil
+	}
+	if err := db.Migrator().CreateTable(model); err != nil && !db.Migrator().HasTable(model) {
+		return fmt.Errorf("failed to create migration table: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/Talfaza/prox-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func schema(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+
+	var statements []string
+	err := db.Raw("SELECT sql FROM sqlite_master WHERE sql IS NOT NULL AND name NOT LIKE 'schema_migrations%' ORDER BY name").
+		Scan(&statements).Error
+	require.NoError(t, err)
+	return statements
+}
+
+func TestMigrationsMatchModels(t *testing.T) {
+	migrated, err := Open("sqlite://:memory:")
+	require.NoError(t, err)
+	require.NoError(t, Migrate(migrated))
+
+	legacy, err := Open("sqlite://:memory:")
+	require.NoError(t, err)
+	require.NoError(t, legacy.AutoMigrate(&models.ProxConfig{}))
+	want := schema(t, legacy)
+	assert.Equal(t, want, schema(t, migrated), "a model changed without a migration")
+
+	// A database created before migrations existed is adopted as is
+	require.NoError(t, Migrate(legacy))
+	assert.Equal(t, want, schema(t, legacy))
+
+	statuses, err := Status(legacy, migrations)
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.NotNil(t, statuses[0].AppliedAt)
+
+	_, err = Down(legacy, migrations, 1)
+	require.NoError(t, err)
+	assert.False(t, legacy.Migrator().HasTable(&models.ProxConfig{}))
+}
@@ -0,0 +1,34 @@
+package database
+
+import "gorm.io/gorm"
+
+// migrations is the schema of prox-service, oldest first. Each one works on
+// its own copy of the models as they were when it was written, so later
+// changes to package models cannot alter what it does.
+var migrations = []Migration{
+	{Version: 1, Name: "baseline", Up: baselineUp, Down: baselineDown},
+}
+
+// The baseline is the schema the service used to AutoMigrate at startup.
+// Up adopts a database created that way: AutoMigrate only adds what is
+// missing.
+type baselineProxConfig struct {
+	gorm.Model
+	UserID     uint
+	OrgID      *uint `gorm:"index"`
+	ServerName string
+	Username   string
+	Host       string
+	Port       string
+	Password   string
+}
+
+func (baselineProxConfig) TableName() string { return "prox_configs" }
+
+func baselineUp(tx *gorm.DB) error {
+	return tx.AutoMigrate(&baselineProxConfig{})
+}
+
+func baselineDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&baselineProxConfig{})
+}
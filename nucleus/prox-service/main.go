@@ -1,36 +1,81 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
+	"os"
+	"time"
 
+	"github.com/Talfaza/prox-service/api"
+	"github.com/Talfaza/prox-service/config"
 	"github.com/Talfaza/prox-service/database"
+	"github.com/Talfaza/prox-service/health"
+	"github.com/Talfaza/prox-service/logging"
+	"github.com/Talfaza/prox-service/metrics"
 	"github.com/Talfaza/prox-service/middleware"
-	"github.com/Talfaza/prox-service/service"
+	"github.com/Talfaza/prox-service/problem"
+	"github.com/Talfaza/prox-service/routes"
+	"github.com/Talfaza/prox-service/tracing"
 	"github.com/gofiber/fiber/v3"
-	"github.com/gofiber/fiber/v3/middleware/cors"
 )
 
 func main() {
-	database.Connect()
-
-	app := fiber.New()
-
-	// Enable CORS
-	app.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:3000"},
-		AllowCredentials: true,
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-	}))
-
-	// Protected routes
-	protected := app.Group("/", middleware.AuthRequired)
-	protected.Post("/prox", services.ExecuteCommand)
-	protected.Get("/prox", services.GetUserConfigs)
-	protected.Put("/prox/:id", services.UpdateUserConfig)
-	protected.Delete("/prox/:id", services.DeleteUserConfig)
-
-	log.Println("Server running on port 7790 !")
-	log.Fatal(app.Listen(":7790"))
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	if err := logging.Setup(cfg.LogLevel); err != nil {
+		logging.Fatal("Invalid log level", "error", err)
+	}
+	if cfg.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			logging.Fatal("Failed to print configuration", "error", err)
+		}
+		return
+	}
+	if len(cfg.Args) > 0 {
+		if cfg.Args[0] != "migrate" {
+			logging.Fatal("Unknown command", "command", cfg.Args[0])
+		}
+		if err := database.Command(cfg.DSN, cfg.Args[1:], os.Stdout); err != nil {
+			logging.Fatal("Migration failed", "error", err)
+		}
+		return
+	}
+
+	flushSpans, err := tracing.Setup("prox-service", cfg.OTLPEndpoint)
+	if err != nil {
+		logging.Fatal("Failed to set up tracing", "error", err)
+	}
+
+	database.Connect(cfg.DSN, cfg.AutoMigrate)
+	middleware.Keys = middleware.NewKeySet(cfg.JWKSURL)
+	middleware.Tokens = middleware.NewIntrospector(cfg.AuthURL + "/auth/tokens/introspect")
+	middleware.Revoked.URL = cfg.AuthURL + "/auth/sessions/revoked"
+
+	fiberConfig := fiber.Config{ErrorHandler: problem.Handler}
+	if len(cfg.TrustedProxies) > 0 {
+		fiberConfig.TrustProxy = true
+		fiberConfig.TrustProxyConfig = fiber.TrustProxyConfig{Proxies: cfg.TrustedProxies}
+		fiberConfig.ProxyHeader = fiber.HeaderXForwardedFor
+	}
+	app := fiber.New(fiberConfig)
+	health.Register(app, map[string]health.Check{"database": database.Ping})
+	metrics.Register(app)
+	tracing.Register(app)
+	logging.Register(app)
+	api.Register(app)
+
+	routes.Setup(app)
+
+	slog.Info("Server running", "addr", cfg.Addr)
+	if err := health.Serve(app, cfg.Addr, time.Duration(cfg.ShutdownTimeout)*time.Second); err != nil {
+		logging.Fatal("Server failed", "error", err)
+	}
+	_ = database.Close()
+	if err := flushSpans(context.Background()); err != nil {
+		slog.Warn("Failed to export spans", "error", err)
+	}
+	slog.Info("Server stopped")
 }
 
@@ -0,0 +1,64 @@
+// Package metrics exports Prometheus metrics at /metrics.
+package metrics
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	requests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "nucleus_http_requests_total",
+		Help: "HTTP requests by route, method and status.",
+	}, []string{"route", "method", "status"})
+
+	latency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "nucleus_http_request_duration_seconds",
+		Help:    "HTTP request latency by route and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method"})
+)
+
+// unmatched labels requests no route handled, so probing random paths
+// cannot create a series per path.
+const unmatched = "unmatched"
+
+// Register serves the metrics at /metrics and counts the requests to the
+// routes registered after it. Register the health probes first so they
+// are not counted.
+func Register(app *fiber.App) {
+	app.Get("/metrics", adaptor.HTTPHandler(promhttp.Handler()))
+	app.Use(observe)
+}
+
+func observe(c fiber.Ctx) error {
+	start := time.Now()
+	err := c.Next()
+
+	// Errors are only turned into responses after the middleware returns
+	status := c.Response().StatusCode()
+	var ferr *fiber.Error
+	if errors.As(err, &ferr) {
+		status = ferr.Code
+	} else if err != nil {
+		status = fiber.StatusInternalServerError
+	}
+
+	// Only the router fails with a bare *fiber.Error, handlers with
+	// problems
+	route := c.Route().Path
+	if routeErr, ok := err.(*fiber.Error); ok && routeErr.Code == fiber.StatusNotFound {
+		route = unmatched
+	}
+
+	requests.WithLabelValues(route, c.Method(), strconv.Itoa(status)).Inc()
+	latency.WithLabelValues(route, c.Method()).Observe(time.Since(start).Seconds())
+	return err
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthRequiredJWKS(t *testing.T) {
+	// Setup: a fake auth-service publishing one Ed25519 key
+	public, private, _ := ed25519.GenerateKey(rand.Reader)
+	var fetches atomic.Int32
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"keys":[{"kty":"OKP","crv":"Ed25519","kid":"k1","use":"sig","alg":"EdDSA","x":"` +
+			base64.RawURLEncoding.EncodeToString(public) + `"}]}`))
+	}))
+	defer jwksServer.Close()
+
+	Keys = NewKeySet(jwksServer.URL)
+	app := fiber.New()
+	app.Use(AuthRequired)
+	app.Get("/protected", func(c fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	sign := func(kid string, key interface{}, method jwt.SigningMethod) string {
+		token := jwt.NewWithClaims(method, jwt.MapClaims{
+			"sub": "123",
+			"aud": SessionAudience,
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		token.Header["kid"] = kid
+		signed, _ := token.SignedString(key)
+		return signed
+	}
+
+	request := func(token string) int {
+		req := httptest.NewRequest("GET", "/protected", nil)
+		req.AddCookie(&http.Cookie{Name: "jwt", Value: token})
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	t.Run("valid token", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusOK, request(sign("k1", private, jwt.SigningMethodEdDSA)))
+		assert.Equal(t, fiber.StatusOK, request(sign("k1", private, jwt.SigningMethodEdDSA)))
+		// The second request is served from the cache
+		assert.Equal(t, int32(1), fetches.Load())
+	})
+
+	t.Run("unknown kid", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusUnauthorized, request(sign("k2", private, jwt.SigningMethodEdDSA)))
+		// Refetches for unknown kids are throttled
+		assert.Equal(t, int32(1), fetches.Load())
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		_, other, _ := ed25519.GenerateKey(rand.Reader)
+		assert.Equal(t, fiber.StatusUnauthorized, request(sign("k1", other, jwt.SigningMethodEdDSA)))
+	})
+
+	t.Run("hmac token", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusUnauthorized, request(sign("k1", []byte("secret"), jwt.SigningMethodHS256)))
+	})
+}
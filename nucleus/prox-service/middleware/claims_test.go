@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestUserID(t *testing.T) {
+	tests := []struct {
+		name    string
+		claims  interface{}
+		want    uint
+		wantErr error
+	}{
+		{"numeric", &Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "42"}}, 42, nil},
+		{"no claims", nil, 0, ErrNoClaims},
+		{"malformed claims", jwt.MapClaims{"sub": 42.0}, 0, ErrNoClaims},
+		{"missing subject", &Claims{}, 0, ErrMissingSubject},
+		{"non-numeric subject", &Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "alice"}}, 0, ErrInvalidSubject},
+		{"fractional subject", &Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "42.9"}}, 0, ErrInvalidSubject},
+	}
+
+	app := fiber.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := app.AcquireCtx(&fasthttp.RequestCtx{})
+			defer app.ReleaseCtx(c)
+			if tt.claims != nil {
+				c.Locals("claims", tt.claims)
+			}
+
+			got, err := UserID(c)
+			assert.Equal(t, tt.want, got)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
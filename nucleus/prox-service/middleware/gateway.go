@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// HeaderIdentity carries the claims of a caller the gateway has already
+// authenticated, as base64url-encoded JSON.
+const HeaderIdentity = "X-Nucleus-Identity"
+
+var ErrInvalidIdentity = errors.New("malformed identity header")
+
+// gatewayClaims returns the identity forwarded by a trusted gateway. ok is
+// false when there is none, or the request did not come from a trusted
+// proxy, and the credential must be checked here instead.
+func gatewayClaims(c fiber.Ctx) (claims *Claims, ok bool, err error) {
+	header := c.Get(HeaderIdentity)
+	// IsProxyTrusted trusts everyone when no proxy is configured
+	if header == "" || !c.App().Config().TrustProxy || !c.IsProxyTrusted() {
+		return nil, false, nil
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(header)
+	if err != nil {
+		return nil, true, ErrInvalidIdentity
+	}
+	claims = new(Claims)
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return nil, true, ErrInvalidIdentity
+	}
+	return claims, true, nil
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthRequiredGateway(t *testing.T) {
+	identity := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"9","roles":["operator"],"scopes":["read"]}`))
+
+	newApp := func(config fiber.Config) *fiber.App {
+		app := fiber.New(config)
+		app.Use(AuthRequired)
+		app.Get("/prox", RequireScope(ScopeRead), func(c fiber.Ctx) error {
+			id, _ := UserID(c)
+			return c.JSON(id)
+		})
+		app.Post("/prox", RequireScope(ScopeWrite), func(c fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusCreated)
+		})
+		return app
+	}
+	request := func(app *fiber.App, method, header string) int {
+		req := httptest.NewRequest(method, "/prox", nil)
+		req.Header.Set(HeaderIdentity, header)
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	// Requests made by app.Test come from 0.0.0.0
+	trusted := newApp(fiber.Config{TrustProxy: true, TrustProxyConfig: fiber.TrustProxyConfig{Proxies: []string{"0.0.0.0"}}})
+	untrusted := newApp(fiber.Config{TrustProxy: true, TrustProxyConfig: fiber.TrustProxyConfig{Proxies: []string{"10.0.0.1"}}})
+	unconfigured := newApp(fiber.Config{})
+
+	t.Run("trusted gateway", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusOK, request(trusted, "GET", identity))
+		// The forwarded scopes still apply
+		assert.Equal(t, fiber.StatusForbidden, request(trusted, "POST", identity))
+	})
+
+	t.Run("malformed identity", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusUnauthorized, request(trusted, "GET", "not base64!"))
+	})
+
+	t.Run("untrusted source", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusUnauthorized, request(untrusted, "GET", identity))
+	})
+
+	t.Run("no trusted proxies", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusUnauthorized, request(unconfigured, "GET", identity))
+	})
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthRequiredAPIToken(t *testing.T) {
+	// Setup: a fake auth-service that knows one read-only token
+	var calls atomic.Int32
+	authServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var body struct {
+			Token string `json:"token"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		w.Header().Set("Content-Type", "application/json")
+		if body.Token != "nuc_valid" {
+			_, _ = w.Write([]byte(`{"active":false}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"active": true,
+			"sub":    "5",
+			"roles":  []string{RoleOperator},
+			"scopes": []string{ScopeRead},
+			"exp":    time.Now().Add(time.Hour).Unix(),
+		})
+	}))
+	defer authServer.Close()
+
+	Tokens = NewIntrospector(authServer.URL)
+	app := fiber.New()
+	app.Use(AuthRequired)
+	app.Get("/prox", RequireScope(ScopeRead), func(c fiber.Ctx) error {
+		id, _ := UserID(c)
+		return c.JSON(id)
+	})
+	app.Post("/prox", RequireScope(ScopeWrite), func(c fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusCreated)
+	})
+
+	request := func(method, token string) int {
+		req := httptest.NewRequest(method, "/prox", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	t.Run("valid token", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusOK, request("GET", "nuc_valid"))
+		assert.Equal(t, fiber.StatusOK, request("GET", "nuc_valid"))
+		// The second request is served from the cache
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("missing scope", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusForbidden, request("POST", "nuc_valid"))
+	})
+
+	t.Run("revoked token", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusUnauthorized, request("GET", "nuc_revoked"))
+		assert.Equal(t, fiber.StatusUnauthorized, request("GET", "nuc_revoked"))
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("bearer jwt", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusUnauthorized, request("GET", "not.a.jwt"))
+	})
+}
@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// jwksTTL is how long fetched keys are trusted before a refresh.
+	jwksTTL = 10 * time.Minute
+	// jwksMinRefresh throttles refetches triggered by unknown kids so a
+	// flood of forged tokens cannot hammer auth-service.
+	jwksMinRefresh = 30 * time.Second
+)
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// KeySet caches the public keys auth-service publishes at
+// /.well-known/jwks.json. Tokens are verified against the cache only; this
+// service never holds a key that can sign.
+type KeySet struct {
+	URL string
+	// Client makes the requests, a client with a 5s timeout if nil
+	Client *http.Client
+
+	mu        sync.RWMutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+// Keys is the key set used by AuthRequired. main sets it up from the
+// configuration.
+var Keys = &KeySet{}
+
+// NewKeySet returns an empty cache for the JWKS at url.
+func NewKeySet(url string) *KeySet {
+	return &KeySet{URL: url, Client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// Methods lists the algorithms accepted from auth-service.
+func (k *KeySet) Methods() []string {
+	return []string{jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodRS256.Alg()}
+}
+
+// Keyfunc resolves the public key for a token by its kid, refreshing the
+// cache when it is stale or the kid is unknown (a key was rotated in).
+func (k *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("token has no kid")
+	}
+
+	k.mu.RLock()
+	key, ok := k.keys[kid]
+	age := time.Since(k.fetchedAt)
+	k.mu.RUnlock()
+
+	if ok && age < jwksTTL {
+		return key, nil
+	}
+	if !ok && age < jwksMinRefresh {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if err := k.refresh(); err != nil {
+		// Keep serving known keys if auth-service is briefly unreachable
+		if ok {
+			return key, nil
+		}
+		return nil, err
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if key, ok := k.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (k *KeySet) refresh() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	// Another request may have refreshed while we waited for the lock
+	if time.Since(k.fetchedAt) < jwksMinRefresh {
+		return nil
+	}
+	k.fetchedAt = time.Now()
+
+	if k.URL == "" {
+		return errors.New("no URL to fetch the JWKS from")
+	}
+	client := k.Client
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+
+	resp, err := client.Get(k.URL)
+	if err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("failed to decode JWKS: %v", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, key := range set.Keys {
+		pub, err := key.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[key.Kid] = pub
+	}
+	k.keys = keys
+	return nil
+}
+
+func (j jwk) publicKey() (interface{}, error) {
+	switch j.Kty {
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", j.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return nil, errors.New("invalid RSA modulus")
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", j.Kty)
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import "strconv"
+
+// Organization permissions, weakest first. Each one includes the ones
+// before it.
+const (
+	PermissionRead  = "read"
+	PermissionWrite = "write"
+	PermissionAdmin = "admin"
+)
+
+var permissionRank = map[string]int{PermissionRead: 1, PermissionWrite: 2, PermissionAdmin: 3}
+
+// CanInOrg reports whether the user holds at least perm in the organization.
+func (c *Claims) CanInOrg(orgID uint, perm string) bool {
+	have := c.Orgs[strconv.FormatUint(uint64(orgID), 10)]
+	return permissionRank[have] > 0 && permissionRank[have] >= permissionRank[perm]
+}
+
+// OrgIDs returns the organizations in which the user holds at least perm.
+func (c *Claims) OrgIDs(perm string) []uint {
+	var ids []uint
+	for key := range c.Orgs {
+		id, err := strconv.ParseUint(key, 10, strconv.IntSize)
+		if err != nil {
+			continue
+		}
+		if c.CanInOrg(uint(id), perm) {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
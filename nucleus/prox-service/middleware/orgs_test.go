@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrgPermissions(t *testing.T) {
+	claims := &Claims{Orgs: map[string]string{"1": PermissionRead, "2": PermissionWrite, "3": PermissionAdmin, "x": PermissionAdmin, "4": "owner"}}
+
+	assert.True(t, claims.CanInOrg(1, PermissionRead))
+	assert.False(t, claims.CanInOrg(1, PermissionWrite))
+	assert.True(t, claims.CanInOrg(3, PermissionWrite))
+	assert.False(t, claims.CanInOrg(4, PermissionRead))
+	assert.False(t, claims.CanInOrg(5, PermissionRead))
+
+	ids := claims.OrgIDs(PermissionWrite)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	assert.Equal(t, []uint{2, 3}, ids)
+	assert.Len(t, claims.OrgIDs(PermissionRead), 3)
+	assert.Empty(t, (&Claims{}).OrgIDs(PermissionRead))
+}
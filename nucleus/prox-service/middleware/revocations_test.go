@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthRequiredRevokedSession(t *testing.T) {
+	// Setup: a fake auth-service publishing its key and one revoked session
+	public, private, _ := ed25519.GenerateKey(rand.Reader)
+	var fetches atomic.Int32
+	authServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/jwks":
+			_, _ = w.Write([]byte(`{"keys":[{"kty":"OKP","crv":"Ed25519","kid":"k1","x":"` +
+				base64.RawURLEncoding.EncodeToString(public) + `"}]}`))
+		case "/auth/sessions/revoked":
+			fetches.Add(1)
+			_, _ = w.Write([]byte(`{"revoked":["revoked-session"]}`))
+		}
+	}))
+	defer authServer.Close()
+
+	Keys = NewKeySet(authServer.URL + "/jwks")
+	Revoked = &RevocationList{URL: authServer.URL + "/auth/sessions/revoked"}
+	defer func() { Revoked = &RevocationList{} }()
+
+	app := fiber.New()
+	app.Use(AuthRequired)
+	app.Get("/protected", func(c fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	request := func(jti string) int {
+		token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{
+			"sub": "1",
+			"aud": SessionAudience,
+			"jti": jti,
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		token.Header["kid"] = "k1"
+		signed, _ := token.SignedString(private)
+
+		req := httptest.NewRequest("GET", "/protected", nil)
+		req.AddCookie(&http.Cookie{Name: "jwt", Value: signed})
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, fiber.StatusOK, request("active-session"))
+	assert.Equal(t, fiber.StatusUnauthorized, request("revoked-session"))
+	// The list is cached between requests
+	assert.Equal(t, int32(1), fetches.Load())
+}
+
+func TestRevocationListRefreshDoesNotBlock(t *testing.T) {
+	// Setup: a fake auth-service that hangs once the list has been fetched
+	fetched, release := make(chan struct{}, 2), make(chan struct{})
+	var fetches atomic.Int32
+	authServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches.Add(1) > 1 {
+			fetched <- struct{}{}
+			<-release
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"revoked":["revoked-session"]}`))
+	}))
+	defer authServer.Close()
+	defer close(release)
+
+	list := &RevocationList{URL: authServer.URL}
+	assert.True(t, list.Contains("revoked-session"))
+
+	// The request that refreshes the stale list waits for auth-service...
+	list.mu.Lock()
+	list.fetchedAt = time.Time{}
+	list.mu.Unlock()
+	go list.Contains("active-session")
+	<-fetched
+
+	// ...the others keep using the previous list, without fetching it again
+	done := make(chan bool)
+	go func() { done <- list.Contains("revoked-session") }()
+	select {
+	case revoked := <-done:
+		assert.True(t, revoked)
+	case <-time.After(time.Second):
+		t.Fatal("waited for the refresh")
+	}
+	assert.Equal(t, int32(2), fetches.Load())
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"slices"
+
+	"github.com/Talfaza/prox-service/problem"
+	"github.com/gofiber/fiber/v3"
+)
+
+// Scopes a personal access token can be limited to.
+const (
+	ScopeRead    = "read"
+	ScopeWrite   = "write"
+	ScopeExecute = "execute"
+)
+
+// Roles issued by auth-service.
+const (
+	RoleAdmin    = "admin"
+	RoleOperator = "operator"
+	RoleViewer   = "viewer"
+)
+
+// HasRole reports whether the claims carry any of roles.
+func (c *Claims) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if slices.Contains(c.Roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRole reports whether the authenticated user has any of roles.
+func HasRole(c fiber.Ctx, roles ...string) bool {
+	claims, err := ClaimsFrom(c)
+	if err != nil {
+		return false
+	}
+	return claims.HasRole(roles...)
+}
+
+// RequireRole only lets through users holding at least one of roles. It
+// must run after AuthRequired.
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if !HasRole(c, roles...) {
+			return problem.New(fiber.StatusForbidden, "Forbidden")
+		}
+		return c.Next()
+	}
+}
+
+// RequireScope rejects personal access tokens that were not granted scope.
+// Sessions are never limited by scope. It must run after AuthRequired.
+func RequireScope(scope string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		claims, err := ClaimsFrom(c)
+		if err != nil || !claims.HasScope(scope) {
+			return problem.New(fiber.StatusForbidden, "Token lacks the "+scope+" scope").WithCode(problem.CodeInsufficientScope)
+		}
+		return c.Next()
+	}
+}
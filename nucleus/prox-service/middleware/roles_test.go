@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireRole(t *testing.T) {
+	// Setup: fake authentication from an X-Roles header
+	app := fiber.New()
+	app.Use(func(c fiber.Ctx) error {
+		if roles := c.Get("X-Roles"); roles != "" {
+			c.Locals("claims", &Claims{
+				Roles:            []string{roles},
+				RegisteredClaims: jwt.RegisteredClaims{Subject: "1"},
+			})
+		}
+		return c.Next()
+	})
+	app.Get("/read", RequireRole(RoleAdmin, RoleOperator, RoleViewer), func(c fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	app.Post("/write", RequireRole(RoleAdmin, RoleOperator), func(c fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	tests := []struct {
+		role   string
+		method string
+		path   string
+		want   int
+	}{
+		{RoleViewer, "GET", "/read", fiber.StatusOK},
+		{RoleViewer, "POST", "/write", fiber.StatusForbidden},
+		{RoleOperator, "POST", "/write", fiber.StatusOK},
+		{RoleAdmin, "POST", "/write", fiber.StatusOK},
+		{"", "GET", "/read", fiber.StatusForbidden},
+		{"superuser", "GET", "/read", fiber.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.role+" "+tt.method, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("X-Roles", tt.role)
+			resp, _ := app.Test(req)
+			assert.Equal(t, tt.want, resp.StatusCode)
+		})
+	}
+}
@@ -5,10 +5,11 @@ import "gorm.io/gorm"
 type ProxConfig struct {
 	gorm.Model
 	UserID     uint   `json:"user_id"`
-	ServerName string `json:"server_name"`
-	Username   string `json:"username"`
-	Host       string `json:"host"`
-	Port       string `json:"port"`
-	Password   string `json:"password"`
+	OrgID      *uint  `json:"org_id" gorm:"index"`
+	ServerName string `json:"server_name" validate:"required,max=255"`
+	Username   string `json:"username" validate:"required,max=255"`
+	Host       string `json:"host" validate:"required,hostname|ip"`
+	Port       string `json:"port" validate:"required,port"`
+	Password   string `json:"password" validate:"required"`
 }
 
@@ -0,0 +1,147 @@
+// Package problem answers the errors handlers return as RFC 7807 problem
+// details, served as application/problem+json:
+//
+//	{"type":"about:blank","title":"Not Found","status":404,"detail":"Configuration not found","instance":"/prox/7","code":"not_found","request_id":"9f2c..."}
+package problem
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Talfaza/prox-service/logging"
+	"github.com/gofiber/fiber/v3"
+)
+
+// ContentType is the media type of problem details.
+const ContentType = "application/problem+json"
+
+// Codes tell problems apart without parsing their detail. Every status has
+// one; handlers use a more precise code where clients act on it.
+const (
+	CodeBadRequest       = "bad_request"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodeInvalid          = "validation_failed"
+	CodeTooManyRequests  = "too_many_requests"
+	CodeInternal         = "internal"
+	CodeBadGateway       = "bad_gateway"
+	CodeUnavailable      = "unavailable"
+)
+
+// CodeInsufficientScope is forbidden to a personal access token lacking
+// the scope of the endpoint.
+const CodeInsufficientScope = "insufficient_scope"
+
+var statusCodes = map[int]string{
+	fiber.StatusBadRequest:          CodeBadRequest,
+	fiber.StatusUnauthorized:        CodeUnauthorized,
+	fiber.StatusForbidden:           CodeForbidden,
+	fiber.StatusNotFound:            CodeNotFound,
+	fiber.StatusMethodNotAllowed:    CodeMethodNotAllowed,
+	fiber.StatusConflict:            CodeConflict,
+	fiber.StatusUnprocessableEntity: CodeInvalid,
+	fiber.StatusTooManyRequests:     CodeTooManyRequests,
+	fiber.StatusInternalServerError: CodeInternal,
+	fiber.StatusBadGateway:          CodeBadGateway,
+	fiber.StatusServiceUnavailable:  CodeUnavailable,
+}
+
+// Error is a problem a handler answers with.
+type Error struct {
+	Status int
+	Code   string
+	// Detail explains the problem to the user
+	Detail string
+	// Errors lists the fields of a CodeInvalid problem
+	Errors []FieldError
+}
+
+// FieldError is a field of the body that failed validation, named as in
+// the JSON. The message names it too, so it can be shown on its own.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// New returns a problem with the code of its status.
+func New(status int, detail string) *Error {
+	code, ok := statusCodes[status]
+	if !ok {
+		code = CodeBadRequest
+		if status >= fiber.StatusInternalServerError {
+			code = CodeInternal
+		}
+	}
+	return &Error{Status: status, Code: code, Detail: detail}
+}
+
+// Invalid returns the 422 problem of a body with invalid fields.
+func Invalid(errs ...FieldError) *Error {
+	return &Error{
+		Status: fiber.StatusUnprocessableEntity,
+		Code:   CodeInvalid,
+		Detail: "The request has invalid fields",
+		Errors: errs,
+	}
+}
+
+// WithCode sets a more precise code than the one of the status.
+func (e *Error) WithCode(code string) *Error {
+	e.Code = code
+	return e
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+// As reads the problem as a *fiber.Error, so middleware looking for the
+// status of a failed request finds it.
+func (e *Error) As(target interface{}) bool {
+	if ferr, ok := target.(**fiber.Error); ok {
+		*ferr = &fiber.Error{Code: e.Status, Message: e.Detail}
+		return true
+	}
+	return false
+}
+
+// document is a problem as it is answered.
+type document struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// Handler is the fiber.Config ErrorHandler answering errors as problems.
+// A *fiber.Error, e.g. for a route that does not exist, keeps its status.
+// Any other error is a 500 whose message stays in the request log.
+func Handler(c fiber.Ctx, err error) error {
+	var problem *Error
+	var ferr *fiber.Error
+	switch {
+	case errors.As(err, &problem):
+	case errors.As(err, &ferr):
+		problem = New(ferr.Code, ferr.Message)
+	default:
+		problem = New(fiber.StatusInternalServerError, "Internal server error")
+	}
+
+	return c.Status(problem.Status).JSON(document{
+		Type:      "about:blank",
+		Title:     http.StatusText(problem.Status),
+		Status:    problem.Status,
+		Detail:    problem.Detail,
+		Instance:  c.Path(),
+		Code:      problem.Code,
+		RequestID: logging.ID(c),
+		Errors:    problem.Errors,
+	}, ContentType)
+}
@@ -0,0 +1,168 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Talfaza/prox-service/api"
+	"github.com/Talfaza/prox-service/apptest"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestApp serves the API and its document from an in-memory SQLite
+// database.
+func newTestApp(t *testing.T) *fiber.App {
+	t.Helper()
+
+	app := apptest.New(t)
+	api.Register(app)
+	Setup(app)
+	return app
+}
+
+// contract makes requests to the app, failing the test when an answer does
+// not match the OpenAPI document the app serves, or a request the document
+// rejects is not rejected by the app too.
+type contract struct {
+	t      *testing.T
+	app    *fiber.App
+	doc    *openapi3.T
+	router routers.Router
+}
+
+func newContract(t *testing.T, app *fiber.App) *contract {
+	t.Helper()
+
+	resp := apptest.Do(t, app, httptest.NewRequest("GET", "/openapi.json", nil))
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	spec, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	doc, err := openapi3.NewLoader().LoadFromData(spec)
+	require.NoError(t, err)
+	require.NoError(t, doc.Validate(context.Background()))
+	router, err := gorillamux.NewRouter(doc)
+	require.NoError(t, err)
+	return &contract{t: t, app: app, doc: doc, router: router}
+}
+
+// call makes a JSON request as the gateway identity and returns the
+// answer, its body left to read.
+func (c *contract) call(identity, method, path, body string) *http.Response {
+	c.t.Helper()
+
+	req := apptest.Request(identity, method, path, body)
+
+	route, params, err := c.router.FindRoute(req)
+	require.NoError(c.t, err, "%s %s is not documented", method, path)
+	input := &openapi3filter.RequestValidationInput{
+		Request:    req,
+		PathParams: params,
+		Route:      route,
+		Options:    &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+	}
+	invalid := openapi3filter.ValidateRequest(context.Background(), input)
+
+	// The validation consumed the body, and filled in defaults
+	req.Body = io.NopCloser(strings.NewReader(body))
+	req.ContentLength = int64(len(body))
+	resp := apptest.Do(c.t, c.app, req)
+	answer, err := io.ReadAll(resp.Body)
+	require.NoError(c.t, err)
+	assert.NoError(c.t, openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 resp.StatusCode,
+		Header:                 resp.Header,
+		Body:                   io.NopCloser(bytes.NewReader(answer)),
+	}), "%s %s answered %d %s", method, path, resp.StatusCode, answer)
+	if invalid != nil {
+		assert.GreaterOrEqual(c.t, resp.StatusCode, fiber.StatusBadRequest, "%s %s accepted an invalid request: %v", method, path, invalid)
+		assert.Less(c.t, resp.StatusCode, fiber.StatusInternalServerError, "%s %s failed on an invalid request: %v", method, path, invalid)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(answer))
+	return resp
+}
+
+var routeParam = regexp.MustCompile(`:(\w+)`)
+
+// TestRoutesDocumented checks every route is documented and every
+// documented operation is routed.
+func TestRoutesDocumented(t *testing.T) {
+	app := newTestApp(t)
+	c := newContract(t, app)
+
+	var routed []string
+	for _, route := range app.GetRoutes(true) {
+		if route.Method == fiber.MethodHead || route.Path == "/openapi.json" {
+			continue
+		}
+		path := routeParam.ReplaceAllString(route.Path, "{$1}")
+		if path != "/" {
+			path = strings.TrimSuffix(path, "/")
+		}
+		routed = append(routed, route.Method+" "+path)
+	}
+	var documented []string
+	for path, item := range c.doc.Paths.Map() {
+		for method := range item.Operations() {
+			documented = append(documented, method+" "+path)
+		}
+	}
+	sort.Strings(routed)
+	sort.Strings(documented)
+	assert.Equal(t, documented, routed)
+}
+
+func TestContract(t *testing.T) {
+	c := newContract(t, newTestApp(t))
+	alice := `{"sub":"1","roles":["operator"],"orgs":{"7":"write"}}`
+	viewer := `{"sub":"2","roles":["viewer"]}`
+	admin := `{"sub":"3","roles":["admin"]}`
+
+	server := `{"server_name":"pve","username":"root","host":"pve.lab.local","port":"8006","password":"secret","org_id":7}`
+	resp := c.call(alice, "POST", "/prox", server)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	resp = c.call(alice, "POST", "/prox", `{"server_name":"pve","host":"not a host","port":"8006"}`)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+	resp = c.call(viewer, "POST", "/prox", server)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+
+	resp = c.call(alice, "GET", "/prox", "")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(viewer, "GET", "/prox", "")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(alice, "GET", "/prox?q=pve&sort=-name&page=2&per_page=10", "")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(alice, "GET", "/prox?sort=password", "")
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	resp = c.call(alice, "GET", "/prox/1", "")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(viewer, "GET", "/prox/1", "")
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	resp = c.call(alice, "PUT", "/prox/1", `{"server_name":"pve2","username":"root","host":"10.0.0.2","port":"8006"}`)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(alice, "PUT", "/prox/2", `{"server_name":"pve2","username":"root","host":"10.0.0.2","port":"8006"}`)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	resp = c.call(alice, "DELETE", "/prox/1", "")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(alice, "DELETE", "/admin/users/1", "")
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	resp = c.call(admin, "DELETE", "/admin/users/1", "")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
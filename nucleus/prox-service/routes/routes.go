@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"github.com/Talfaza/prox-service/middleware"
+	"github.com/Talfaza/prox-service/service"
+	"github.com/gofiber/fiber/v3"
+)
+
+// Setup registers the API, as documented in api/openapi.yaml. Viewers can
+// only read, operators manage their own configurations and admins manage
+// everyone's.
+func Setup(app *fiber.App) {
+	protected := app.Group("/", middleware.AuthRequired)
+	read := middleware.RequireRole(middleware.RoleAdmin, middleware.RoleOperator, middleware.RoleViewer)
+	write := middleware.RequireRole(middleware.RoleAdmin, middleware.RoleOperator)
+	readScope := middleware.RequireScope(middleware.ScopeRead)
+	writeScope := middleware.RequireScope(middleware.ScopeWrite)
+	admin := middleware.RequireRole(middleware.RoleAdmin)
+	protected.Post("/prox", write, writeScope, services.ExecuteCommand)
+	protected.Get("/prox", read, readScope, services.GetUserConfigs)
+	protected.Get("/prox/:id", read, readScope, services.GetUserConfig)
+	protected.Put("/prox/:id", write, writeScope, services.UpdateUserConfig)
+	protected.Delete("/prox/:id", write, writeScope, services.DeleteUserConfig)
+	protected.Delete("/admin/users/:id", admin, writeScope, services.DeleteUserConfigs)
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"encoding/json"
+
+	"github.com/Talfaza/prox-service/middleware"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+)
+
+// readable scopes a query to the configurations the user can see: their
+// own and those shared with organizations they belong to. Admins see
+// every configuration.
+func readable(c fiber.Ctx, userID uint) func(*gorm.DB) *gorm.DB {
+	return accessible(c, userID, middleware.PermissionRead)
+}
+
+// writable scopes a query to the configurations the user can change: their
+// own and those of organizations where they have the write permission.
+func writable(c fiber.Ctx, userID uint) func(*gorm.DB) *gorm.DB {
+	return accessible(c, userID, middleware.PermissionWrite)
+}
+
+func accessible(c fiber.Ctx, userID uint, perm string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		claims, err := middleware.ClaimsFrom(c)
+		if err != nil {
+			return db.Where("1 = 0")
+		}
+		if claims.HasRole(middleware.RoleAdmin) {
+			return db
+		}
+		if orgs := claims.OrgIDs(perm); len(orgs) > 0 {
+			owned := db.Session(&gorm.Session{NewDB: true}).Where("user_id = ?", userID).Or("org_id IN ?", orgs)
+			return db.Where(owned)
+		}
+		return db.Where("user_id = ?", userID)
+	}
+}
+
+// canShareWith reports whether the user may put a configuration in the
+// organization. A nil orgID keeps it private.
+func canShareWith(c fiber.Ctx, orgID *uint) bool {
+	if orgID == nil {
+		return true
+	}
+	claims, err := middleware.ClaimsFrom(c)
+	if err != nil {
+		return false
+	}
+	return claims.HasRole(middleware.RoleAdmin) || claims.CanInOrg(*orgID, middleware.PermissionWrite)
+}
+
+// sameOrg reports whether a and b are the same organization, or both
+// private.
+func sameOrg(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// canMove reports whether the user may take a configuration owned by ownerID out
+// of its organization, into another or back to private: only its owner and
+// admins can, not every member who can write it.
+func canMove(c fiber.Ctx, userID, ownerID uint) bool {
+	if userID == ownerID {
+		return true
+	}
+	claims, err := middleware.ClaimsFrom(c)
+	return err == nil && claims.HasRole(middleware.RoleAdmin)
+}
+
+// orgIDGiven reports whether the request body sets org_id, to null
+// included, which the configuration struct cannot tell from leaving it
+// out.
+func orgIDGiven(c fiber.Ctx) bool {
+	var body struct {
+		OrgID json.RawMessage `json:"org_id"`
+	}
+	return json.Unmarshal(c.Body(), &body) == nil && body.OrgID != nil
+}
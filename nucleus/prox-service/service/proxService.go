@@ -1,9 +1,17 @@
 package services
 
 import (
+	"errors"
+
 	"github.com/Talfaza/prox-service/database"
+	"github.com/Talfaza/prox-service/middleware"
 	"github.com/Talfaza/prox-service/models"
+	"github.com/Talfaza/prox-service/pagination"
+	"github.com/Talfaza/prox-service/problem"
+	"github.com/Talfaza/prox-service/tracing"
+	"github.com/Talfaza/prox-service/validation"
 	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
 )
 
 // ExecuteCommand handles adding SSH credentials into the database
@@ -12,88 +20,120 @@ func ExecuteCommand(c fiber.Ctx) error {
 
 	// Parse JSON body into struct
 	if err := c.Bind().Body(&config); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return problem.New(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if errs := validation.Struct(&config); errs != nil {
+		return problem.Invalid(errs...)
 	}
 
 	// Get user ID from middleware
-	userID := c.Locals("userID")
-	if userID == nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "User not authenticated",
-		})
+	userID, err := middleware.UserID(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, "User not authenticated")
 	}
 
-	// Convert userID to uint
-	config.UserID = uint(userID.(float64))
+	config.UserID = userID
+
+	// Sharing with an organization requires write access to it
+	if !canShareWith(c, config.OrgID) {
+		return problem.New(fiber.StatusForbidden, "Not allowed to share with this organization")
+	}
 
 	// Save to database
-	if err := database.DB.Create(&config).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to save credentials",
-		})
+	if err := database.DB.WithContext(tracing.Context(c)).Create(&config).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to save credentials")
 	}
 
 	// Return saved record
 	return c.Status(fiber.StatusCreated).JSON(config)
 }
 
-// GetUserConfigs retrieves all configurations for a specific user
+// configSorts are the orders configurations can be listed in.
+var configSorts = map[string]string{"created": "created_at", "updated": "updated_at", "name": "server_name"}
+
+// GetUserConfigs retrieves the configurations of a specific user and the
+// organizations they belong to, or of every user when called by an admin,
+// a page at a time. q searches their names and hosts.
 func GetUserConfigs(c fiber.Ctx) error {
 	// Get user ID from middleware
-	userID := c.Locals("userID")
-	if userID == nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "User not authenticated",
-		})
+	userID, err := middleware.UserID(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	page, err := pagination.Parse(c, configSorts, "created")
+	if err != nil {
+		return err
+	}
+	query := database.DB.WithContext(tracing.Context(c)).Model(&models.ProxConfig{}).
+		Scopes(readable(c, userID), page.Filter("server_name", "host"))
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to count configurations")
 	}
 
 	var configs []models.ProxConfig
-	
-	// Find all configurations for this user
-	if err := database.DB.Where("user_id = ?", uint(userID.(float64))).Find(&configs).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve configurations",
-		})
+	if err := query.Scopes(page.Paginate).Find(&configs).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to retrieve configurations")
 	}
 
+	pagination.SetTotal(c, total)
 	return c.JSON(configs)
 }
 
+// GetUserConfig retrieves a configuration the user can see, as listed by
+// GetUserConfigs. lxc-service calls it for the credentials of the server
+// it provisions containers on.
+func GetUserConfig(c fiber.Ctx) error {
+	userID, err := middleware.UserID(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	var config models.ProxConfig
+	if err := database.DB.WithContext(tracing.Context(c)).Scopes(readable(c, userID)).Where("id = ?", c.Params("id")).First(&config).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem.New(fiber.StatusNotFound, "Configuration not found")
+		}
+		return problem.New(fiber.StatusInternalServerError, "Failed to load configuration")
+	}
+
+	return c.JSON(config)
+}
+
 // UpdateUserConfig updates a specific configuration for a user
 func UpdateUserConfig(c fiber.Ctx) error {
 	// Get user ID from middleware
-	userID := c.Locals("userID")
-	if userID == nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "User not authenticated",
-		})
+	userID, err := middleware.UserID(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, "User not authenticated")
 	}
 
 	// Get config ID from URL parameter
 	configID := c.Params("id")
 	if configID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Config ID is required",
-		})
+		return problem.New(fiber.StatusBadRequest, "Config ID is required")
 	}
 
 	var config models.ProxConfig
 	
 	// Parse JSON body into struct
 	if err := c.Bind().Body(&config); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return problem.New(fiber.StatusBadRequest, "Invalid request body")
+	}
+	// The password is kept when left out
+	if errs := validation.Struct(&config, "Password"); errs != nil {
+		return problem.Invalid(errs...)
 	}
 
 	// Find existing config that belongs to this user
 	var existingConfig models.ProxConfig
-	if err := database.DB.Where("id = ? AND user_id = ?", configID, uint(userID.(float64))).First(&existingConfig).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Configuration not found",
-		})
+	if err := database.DB.WithContext(tracing.Context(c)).Scopes(writable(c, userID)).Where("id = ?", configID).First(&existingConfig).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem.New(fiber.StatusNotFound, "Configuration not found")
+		}
+		return problem.New(fiber.StatusInternalServerError, "Failed to load configuration")
 	}
 
 	// Update the config
@@ -104,11 +144,20 @@ func UpdateUserConfig(c fiber.Ctx) error {
 	if config.Password != "" { // Only update password if provided
 		existingConfig.Password = config.Password
 	}
+	// Only move to another organization if org_id is given, null making
+	// the configuration private again
+	if orgIDGiven(c) && !sameOrg(existingConfig.OrgID, config.OrgID) {
+		if !canMove(c, userID, existingConfig.UserID) {
+			return problem.New(fiber.StatusForbidden, "Only the owner can move this configuration")
+		}
+		if !canShareWith(c, config.OrgID) {
+			return problem.New(fiber.StatusForbidden, "Not allowed to share with this organization")
+		}
+		existingConfig.OrgID = config.OrgID
+	}
 
-	if err := database.DB.Save(&existingConfig).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update configuration",
-		})
+	if err := database.DB.WithContext(tracing.Context(c)).Save(&existingConfig).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to update configuration")
 	}
 
 	return c.JSON(existingConfig)
@@ -117,34 +166,42 @@ func UpdateUserConfig(c fiber.Ctx) error {
 // DeleteUserConfig deletes a specific configuration for a user
 func DeleteUserConfig(c fiber.Ctx) error {
 	// Get user ID from middleware
-	userID := c.Locals("userID")
-	if userID == nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "User not authenticated",
-		})
+	userID, err := middleware.UserID(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, "User not authenticated")
 	}
 
 	// Get config ID from URL parameter
 	configID := c.Params("id")
 	if configID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Config ID is required",
-		})
+		return problem.New(fiber.StatusBadRequest, "Config ID is required")
 	}
 
 	// Delete config that belongs to this user
-	result := database.DB.Where("id = ? AND user_id = ?", configID, uint(userID.(float64))).Delete(&models.ProxConfig{})
+	result := database.DB.WithContext(tracing.Context(c)).Scopes(writable(c, userID)).Where("id = ?", configID).Delete(&models.ProxConfig{})
 	if result.Error != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to delete configuration",
-		})
+		return problem.New(fiber.StatusInternalServerError, "Failed to delete configuration")
 	}
 
 	if result.RowsAffected == 0 {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Configuration not found",
-		})
+		return problem.New(fiber.StatusNotFound, "Configuration not found")
 	}
 
 	return c.JSON(fiber.Map{"message": "Configuration deleted successfully"})
 }
+
+// DeleteUserConfigs permanently deletes every configuration owned by a
+// user. auth-service calls it on behalf of an admin deleting the user.
+func DeleteUserConfigs(c fiber.Ctx) error {
+	ownerID := c.Params("id")
+	if ownerID == "" {
+		return problem.New(fiber.StatusBadRequest, "User ID is required")
+	}
+
+	result := database.DB.WithContext(tracing.Context(c)).Unscoped().Where("user_id = ?", ownerID).Delete(&models.ProxConfig{})
+	if result.Error != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to delete configurations")
+	}
+
+	return c.JSON(fiber.Map{"deleted": result.RowsAffected})
+}
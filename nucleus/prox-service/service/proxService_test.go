@@ -0,0 +1,209 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Talfaza/prox-service/apptest"
+	"github.com/Talfaza/prox-service/middleware"
+	"github.com/Talfaza/prox-service/models"
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestApp serves the API from an in-memory SQLite database.
+func newTestApp(t *testing.T) *fiber.App {
+	t.Helper()
+
+	app := apptest.New(t)
+	protected := app.Group("/", middleware.AuthRequired)
+	protected.Post("/prox", ExecuteCommand)
+	protected.Get("/prox", GetUserConfigs)
+	protected.Get("/prox/:id", GetUserConfig)
+	protected.Put("/prox/:id", UpdateUserConfig)
+	protected.Delete("/prox/:id", DeleteUserConfig)
+	protected.Delete("/admin/users/:id", DeleteUserConfigs)
+	return app
+}
+
+// list returns the server names identity can see.
+func list(t *testing.T, app *fiber.App, identity string) []string {
+	t.Helper()
+
+	var configs []models.ProxConfig
+	apptest.Call(t, app, identity, "GET", "/prox", "", &configs)
+	names := []string{}
+	for _, config := range configs {
+		names = append(names, config.ServerName)
+	}
+	return names
+}
+
+// server is the body of a valid configuration, shared with org unless 0.
+func server(name string, org int) string {
+	body := map[string]interface{}{
+		"server_name": name,
+		"username":    "root",
+		"host":        "pve.lab.local",
+		"port":        "22",
+		"password":    "secret",
+	}
+	if org != 0 {
+		body["org_id"] = org
+	}
+	payload, _ := json.Marshal(body)
+	return string(payload)
+}
+
+func TestConfigAccess(t *testing.T) {
+	app := newTestApp(t)
+	alice := `{"sub":"1","orgs":{"7":"write"}}`
+	bob := `{"sub":"2","orgs":{"7":"read"}}`
+	carol := `{"sub":"3"}`
+	admin := `{"sub":"4","roles":["admin"]}`
+
+	status := apptest.Call(t, app, alice, "POST", "/prox", server("private", 0), nil)
+	require.Equal(t, fiber.StatusCreated, status)
+	status = apptest.Call(t, app, alice, "POST", "/prox", server("shared", 7), nil)
+	require.Equal(t, fiber.StatusCreated, status)
+	// Reading an organization is not enough to share with it
+	status = apptest.Call(t, app, bob, "POST", "/prox", server("bob's", 7), nil)
+	assert.Equal(t, fiber.StatusForbidden, status)
+
+	assert.Equal(t, []string{"private", "shared"}, list(t, app, alice))
+	assert.Equal(t, []string{"shared"}, list(t, app, bob))
+	assert.Empty(t, list(t, app, carol))
+
+	// Bob can see the shared server but not change it
+	var shared models.ProxConfig
+	status = apptest.Call(t, app, bob, "GET", "/prox/2", "", &shared)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, "shared", shared.ServerName)
+	status = apptest.Call(t, app, bob, "GET", "/prox/1", "", nil)
+	assert.Equal(t, fiber.StatusNotFound, status)
+	status = apptest.Call(t, app, bob, "PUT", "/prox/2", server("renamed", 0), nil)
+	assert.Equal(t, fiber.StatusNotFound, status)
+	status = apptest.Call(t, app, bob, "DELETE", "/prox/2", "", nil)
+	assert.Equal(t, fiber.StatusNotFound, status)
+
+	// Deleting a user removes everything they own, shared or not
+	var deleted map[string]int
+	status = apptest.Call(t, app, admin, "DELETE", "/admin/users/1", "", &deleted)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, map[string]int{"deleted": 2}, deleted)
+	assert.Empty(t, list(t, app, admin))
+}
+
+func TestConfigValidation(t *testing.T) {
+	app := newTestApp(t)
+	alice := `{"sub":"1"}`
+
+	var invalid struct {
+		Errors []map[string]string `json:"errors"`
+	}
+	status := apptest.Call(t, app, alice, "POST", "/prox", `{"server_name":"pve","host":"not a host","port":"abc"}`, &invalid)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, status)
+	assert.Equal(t, []map[string]string{
+		{"field": "username", "message": "username is required"},
+		{"field": "host", "message": "host must be a hostname or IP address"},
+		{"field": "port", "message": "port must be a port number from 1 to 65535"},
+		{"field": "password", "message": "password is required"},
+	}, invalid.Errors)
+	assert.Empty(t, list(t, app, alice))
+
+	status = apptest.Call(t, app, alice, "POST", "/prox", server("pve", 0), nil)
+	require.Equal(t, fiber.StatusCreated, status)
+
+	// Updates may leave the password out, but not the rest
+	status = apptest.Call(t, app, alice, "PUT", "/prox/1", `{"server_name":"pve2","username":"root","host":"10.0.0.2","port":"8006"}`, nil)
+	assert.Equal(t, fiber.StatusOK, status)
+	status = apptest.Call(t, app, alice, "PUT", "/prox/1", `{"server_name":"pve2","username":"root","host":"10.0.0.2","port":"0"}`, &invalid)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, status)
+	assert.Equal(t, []map[string]string{{"field": "port", "message": "port must be a port number from 1 to 65535"}}, invalid.Errors)
+}
+
+func TestListConfigs(t *testing.T) {
+	app := newTestApp(t)
+	alice := `{"sub":"1","orgs":{"7":"write"}}`
+	bob := `{"sub":"2","orgs":{"7":"read"}}`
+
+	for _, body := range []string{
+		`{"server_name":"pve-b","username":"root","host":"10.0.0.2","port":"8006","password":"secret"}`,
+		`{"server_name":"pve-a","username":"root","host":"pve.lab.local","port":"8006","password":"secret"}`,
+		`{"server_name":"backup","username":"root","host":"backup.lab.local","port":"8006","password":"secret","org_id":7}`,
+	} {
+		require.Equal(t, fiber.StatusCreated, apptest.Call(t, app, alice, "POST", "/prox", body, nil))
+	}
+
+	tests := []struct {
+		identity, query string
+		want            []string
+		total           string
+	}{
+		{alice, "", []string{"pve-b", "pve-a", "backup"}, "3"},
+		{alice, "?sort=name", []string{"backup", "pve-a", "pve-b"}, "3"},
+		{alice, "?sort=-name&per_page=2&page=2", []string{"backup"}, "3"},
+		{alice, "?q=LAB.local", []string{"pve-a", "backup"}, "2"},
+		{alice, "?q=10.0.", []string{"pve-b"}, "1"},
+		// % is not a wildcard
+		{alice, "?q=%25", []string{}, "0"},
+		// The search does not widen what the user can see
+		{bob, "?q=pve", []string{}, "0"},
+		{bob, "?q=backup", []string{"backup"}, "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			resp := apptest.Do(t, app, apptest.Request(tt.identity, "GET", "/prox"+tt.query, ""))
+			require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+			var configs []models.ProxConfig
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&configs))
+			names := []string{}
+			for _, config := range configs {
+				names = append(names, config.ServerName)
+			}
+			assert.Equal(t, tt.want, names)
+			assert.Equal(t, tt.total, resp.Header.Get("X-Total-Count"))
+		})
+	}
+
+	status := apptest.Call(t, app, alice, "GET", "/prox?sort=password", "", nil)
+	assert.Equal(t, fiber.StatusBadRequest, status)
+}
+
+func TestUnshareConfig(t *testing.T) {
+	app := newTestApp(t)
+	alice := `{"sub":"1","orgs":{"7":"write"}}`
+	bob := `{"sub":"2","orgs":{"7":"read"}}`
+
+	status := apptest.Call(t, app, alice, "POST", "/prox", server("shared", 7), nil)
+	require.Equal(t, fiber.StatusCreated, status)
+
+	// Leaving org_id out keeps the organization
+	var updated models.ProxConfig
+	status = apptest.Call(t, app, alice, "PUT", "/prox/1", `{"server_name":"renamed","username":"root","host":"pve.lab.local","port":"22"}`, &updated)
+	require.Equal(t, fiber.StatusOK, status)
+	require.NotNil(t, updated.OrgID)
+	assert.Equal(t, uint(7), *updated.OrgID)
+	assert.Equal(t, []string{"renamed"}, list(t, app, bob))
+
+	// Other writers of the organization can change the server, but not take
+	// it out of the organization
+	carol := `{"sub":"3","orgs":{"7":"write","9":"write"}}`
+	status = apptest.Call(t, app, carol, "PUT", "/prox/1", `{"server_name":"renamed","username":"root","host":"pve.lab.local","port":"22","org_id":null}`, nil)
+	assert.Equal(t, fiber.StatusForbidden, status)
+	status = apptest.Call(t, app, carol, "PUT", "/prox/1", `{"server_name":"renamed","username":"root","host":"pve.lab.local","port":"22","org_id":9}`, nil)
+	assert.Equal(t, fiber.StatusForbidden, status)
+	status = apptest.Call(t, app, carol, "PUT", "/prox/1", `{"server_name":"renamed","username":"admin","host":"pve.lab.local","port":"22","org_id":7}`, nil)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, []string{"renamed"}, list(t, app, bob))
+
+	// A null one makes the server private again
+	updated = models.ProxConfig{}
+	status = apptest.Call(t, app, alice, "PUT", "/prox/1", `{"server_name":"renamed","username":"root","host":"pve.lab.local","port":"22","org_id":null}`, &updated)
+	require.Equal(t, fiber.StatusOK, status)
+	assert.Nil(t, updated.OrgID)
+	assert.Empty(t, list(t, app, bob))
+	assert.Equal(t, []string{"renamed"}, list(t, app, alice))
+}
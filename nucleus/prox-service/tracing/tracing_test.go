@@ -0,0 +1,89 @@
+package tracing
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm"
+)
+
+func TestSpans(t *testing.T) {
+	// Setup: spans kept in memory, a database and a service to call
+	defaultProvider, defaultPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	defer func() {
+		otel.SetTracerProvider(defaultProvider)
+		otel.SetTextMapPropagator(defaultPropagator)
+	}()
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(GormPlugin{}))
+	type widget struct{ ID uint }
+	require.NoError(t, db.AutoMigrate(&widget{}))
+
+	var traceparent string
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer service.Close()
+	client := &http.Client{Transport: Transport{}}
+
+	app := fiber.New()
+	Register(app)
+	app.Get("/widgets/:id", func(c fiber.Ctx) error {
+		var w widget
+		if err := db.WithContext(Context(c)).First(&w, c.Params("id")).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		req, _ := http.NewRequestWithContext(Context(c), http.MethodGet, service.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return fiber.NewError(fiber.StatusBadGateway, "upstream failed")
+	})
+
+	// The caller's trace is continued
+	req := httptest.NewRequest("GET", "/widgets/3", nil)
+	req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadGateway, resp.StatusCode)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 3)
+	query, call, server := spans[0], spans[1], spans[2]
+
+	assert.Equal(t, "GET /widgets/:id", server.Name)
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", server.SpanContext.TraceID().String())
+	assert.Equal(t, "b7ad6b7169203331", server.Parent.SpanID().String())
+	assert.Equal(t, codes.Error, server.Status.Code)
+
+	assert.Equal(t, "gorm.query", query.Name)
+	assert.Equal(t, server.SpanContext.SpanID(), query.Parent.SpanID())
+	assert.Equal(t, codes.Unset, query.Status.Code, "a missing row is not an error")
+
+	assert.Equal(t, "GET", call.Name)
+	assert.Equal(t, server.SpanContext.SpanID(), call.Parent.SpanID())
+	assert.Contains(t, traceparent, call.SpanContext.SpanID().String())
+
+	// Queries outside of a request are not recorded
+	exporter.Reset()
+	require.NoError(t, db.Find(&[]widget{}).Error)
+	assert.Empty(t, exporter.GetSpans())
+}
@@ -33,6 +33,20 @@ check_env() {
     return 0
 }
 
+# Function to wait until a service answers its liveness probe
+wait_healthy() {
+    local port=$1
+    local service_name=$2
+    for _ in $(seq 1 60); do
+        if curl -sf "http://localhost:$port/healthz" >/dev/null; then
+            return 0
+        fi
+        sleep 1
+    done
+    echo -e "${RED}❌ $service_name did not start on port $port${NC}"
+    return 1
+}
+
 # Check prerequisites
 echo -e "${YELLOW}🔍 Checking prerequisites...${NC}"
 
@@ -40,6 +54,7 @@ echo -e "${YELLOW}🔍 Checking prerequisites...${NC}"
 check_port 3000 || exit 1
 check_port 9872 || exit 1
 check_port 7790 || exit 1
+check_port 8080 || exit 1
 
 # Check .env files
 check_env "auth-service" "Auth Service" || exit 1
@@ -70,8 +85,7 @@ go run main.go &
 AUTH_PID=$!
 cd ..
 
-# Wait a moment for auth service to start
-sleep 2
+wait_healthy 9872 "Auth Service" || exit 1
 
 # Start Prox Service
 echo -e "${BLUE}🔧 Starting Prox Service (port 7790)...${NC}"
@@ -80,8 +94,16 @@ go run main.go &
 PROX_PID=$!
 cd ..
 
-# Wait a moment for prox service to start
-sleep 2
+wait_healthy 7790 "Prox Service" || exit 1
+
+# Start Gateway
+echo -e "${BLUE}🚪 Starting Gateway (port 8080)...${NC}"
+cd gateway
+go run main.go &
+GATEWAY_PID=$!
+cd ..
+
+wait_healthy 8080 "Gateway" || exit 1
 
 # Start Frontend
 echo -e "${BLUE}🌐 Starting Frontend (port 3000)...${NC}"
@@ -95,16 +117,18 @@ echo ""
 echo -e "${GREEN}🎉 All services started successfully!${NC}"
 echo -e "${GREEN}=================================${NC}"
 echo -e "${BLUE}🌐 Frontend:    ${NC}http://localhost:3000"
+echo -e "${BLUE}🚪 Gateway:     ${NC}http://localhost:8080"
 echo -e "${BLUE}🔐 Auth API:    ${NC}http://localhost:9872"
 echo -e "${BLUE}⚙️  Prox API:    ${NC}http://localhost:7790"
 echo ""
 echo -e "${YELLOW}📝 Service PIDs:${NC}"
 echo -e "   Auth Service: $AUTH_PID"
 echo -e "   Prox Service: $PROX_PID"
+echo -e "   Gateway: $GATEWAY_PID"
 echo -e "   Frontend: $FRONTEND_PID"
 echo ""
 echo -e "${YELLOW}⚠️  To stop all services, run:${NC}"
-echo -e "   kill $AUTH_PID $PROX_PID $FRONTEND_PID"
+echo -e "   kill $AUTH_PID $PROX_PID $GATEWAY_PID $FRONTEND_PID"
 echo ""
 echo -e "${GREEN}🚀 Open http://localhost:3000 to get started!${NC}"
 
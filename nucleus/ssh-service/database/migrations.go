@@ -0,0 +1,53 @@
+package database
+
+import "gorm.io/gorm"
+
+// migrations is the schema of ssh-service, oldest first. Each one works on
+// its own copy of the models as they were when it was written, so later
+// changes to package models cannot alter what it does.
+var migrations = []Migration{
+	{Version: 1, Name: "baseline", Up: baselineUp, Down: baselineDown},
+	{Version: 2, Name: "ssh config request ids", Up: requestIDsUp, Down: requestIDsDown},
+}
+
+// The baseline is the schema the service used to AutoMigrate at startup.
+// Up adopts a database created that way: AutoMigrate only adds what is
+// missing.
+type baselineSSHConfig struct {
+	gorm.Model
+	Username string
+	Host     string
+	Port     string
+}
+
+func (baselineSSHConfig) TableName() string { return "ssh_configs" }
+
+func baselineUp(tx *gorm.DB) error {
+	return tx.AutoMigrate(&baselineSSHConfig{})
+}
+
+func baselineDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&baselineSSHConfig{})
+}
+
+// Version 2 records the request that ran each command.
+type requestIDsSSHConfig struct {
+	gorm.Model
+	Username  string
+	Host      string
+	Port      string
+	RequestID string `gorm:"index;size:128"`
+}
+
+func (requestIDsSSHConfig) TableName() string { return "ssh_configs" }
+
+func requestIDsUp(tx *gorm.DB) error {
+	return tx.AutoMigrate(&requestIDsSSHConfig{})
+}
+
+func requestIDsDown(tx *gorm.DB) error {
+	if err := tx.Migrator().DropIndex(&requestIDsSSHConfig{}, "RequestID"); err != nil {
+		return err
+	}
+	return tx.Exec("ALTER TABLE ssh_configs DROP COLUMN request_id").Error
+}
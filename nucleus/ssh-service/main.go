@@ -1,28 +1,85 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
+	"os"
+	"time"
 
+	"github.com/Talfaza/ssh-service/api"
+	"github.com/Talfaza/ssh-service/config"
 	"github.com/Talfaza/ssh-service/database"
+	"github.com/Talfaza/ssh-service/health"
+	"github.com/Talfaza/ssh-service/logging"
+	"github.com/Talfaza/ssh-service/metrics"
+	"github.com/Talfaza/ssh-service/middleware"
+	"github.com/Talfaza/ssh-service/problem"
+	"github.com/Talfaza/ssh-service/routes"
 	"github.com/Talfaza/ssh-service/service"
+	"github.com/Talfaza/ssh-service/tracing"
 	"github.com/gofiber/fiber/v3"
-	"github.com/gofiber/fiber/v3/middleware/cors"
 )
 
 func main() {
-	database.Connect()
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	if err := logging.Setup(cfg.LogLevel); err != nil {
+		logging.Fatal("Invalid log level", "error", err)
+	}
+	if cfg.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			logging.Fatal("Failed to print configuration", "error", err)
+		}
+		return
+	}
+	if len(cfg.Args) > 0 {
+		if cfg.Args[0] != "migrate" {
+			logging.Fatal("Unknown command", "command", cfg.Args[0])
+		}
+		if err := database.Command(cfg.DSN, cfg.Args[1:], os.Stdout); err != nil {
+			logging.Fatal("Migration failed", "error", err)
+		}
+		return
+	}
 
-	app := fiber.New()
+	flushSpans, err := tracing.Setup("ssh-service", cfg.OTLPEndpoint)
+	if err != nil {
+		logging.Fatal("Failed to set up tracing", "error", err)
+	}
 
-	app.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:3000"},
-		AllowCredentials: true,
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		AllowMethods:     []string{"GET", "POST", "OPTIONS"},
-	}))
+	database.Connect(cfg.DSN, cfg.AutoMigrate)
+	middleware.Keys = middleware.NewKeySet(cfg.JWKSURL)
+	middleware.Tokens = middleware.NewIntrospector(cfg.AuthURL + "/auth/tokens/introspect")
+	middleware.Revoked.URL = cfg.AuthURL + "/auth/sessions/revoked"
 
-	app.Post("/execute", services.ExecuteCommand)
+	fiberConfig := fiber.Config{ErrorHandler: problem.Handler}
+	if len(cfg.TrustedProxies) > 0 {
+		fiberConfig.TrustProxy = true
+		fiberConfig.TrustProxyConfig = fiber.TrustProxyConfig{Proxies: cfg.TrustedProxies}
+		fiberConfig.ProxyHeader = fiber.HeaderXForwardedFor
+	}
+	app := fiber.New(fiberConfig)
+	checks := map[string]health.Check{"database": database.Ping}
+	if len(cfg.CheckHosts) > 0 {
+		checks["ssh_hosts"] = services.HostsReachable(cfg.CheckHosts)
+	}
+	health.Register(app, checks)
+	metrics.Register(app)
+	tracing.Register(app)
+	logging.Register(app)
+	api.Register(app)
 
-	log.Println("Server running on port 7789")
-	log.Fatal(app.Listen(":7789"))
+	routes.Setup(app)
+
+	slog.Info("Server running", "addr", cfg.Addr)
+	if err := health.Serve(app, cfg.Addr, time.Duration(cfg.ShutdownTimeout)*time.Second); err != nil {
+		logging.Fatal("Server failed", "error", err)
+	}
+	_ = database.Close()
+	if err := flushSpans(context.Background()); err != nil {
+		slog.Warn("Failed to export spans", "error", err)
+	}
+	slog.Info("Server stopped")
 }
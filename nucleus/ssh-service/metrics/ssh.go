@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/crypto/ssh"
+)
+
+// Stages of running a command over SSH.
+const (
+	StageDial    = "dial"
+	StageSession = "session"
+	StageExec    = "exec"
+)
+
+var (
+	// SSHDial times connecting to a host, including the handshake and
+	// authentication, whether it succeeds or not.
+	SSHDial = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "nucleus_ssh_dial_duration_seconds",
+		Help:    "Time to connect and authenticate to an SSH server.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	// SSHExec times running a command once connected.
+	SSHExec = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "nucleus_ssh_exec_duration_seconds",
+		Help:    "Time to run a command over SSH.",
+		Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	})
+
+	sshErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "nucleus_ssh_errors_total",
+		Help: "Failed SSH operations by stage and error class.",
+	}, []string{"stage", "class"})
+)
+
+// SSHFailed counts err, which happened at stage, by its class.
+func SSHFailed(stage string, err error) {
+	sshErrors.WithLabelValues(stage, ErrorClass(err)).Inc()
+}
+
+// ErrorClass sorts SSH errors into a few classes worth alerting on
+// separately.
+func ErrorClass(err error) string {
+	var netErr net.Error
+	var dnsErr *net.DNSError
+	var exitErr *ssh.ExitError
+	switch {
+	case errors.As(err, &dnsErr):
+		return "dns"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "refused"
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return "unreachable"
+	case errors.As(err, &exitErr):
+		return "exit_status"
+	case strings.Contains(err.Error(), "unable to authenticate"):
+		return "auth"
+	case strings.Contains(err.Error(), "handshake failed"):
+		return "handshake"
+	default:
+		return "other"
+	}
+}
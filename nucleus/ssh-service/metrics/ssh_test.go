@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestErrorClass(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	var dialer net.Dialer
+	_, timeout := dialer.DialContext(ctx, "tcp", "10.255.255.1:22")
+
+	dial := func(err error) error {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", err)}
+	}
+	for _, test := range []struct {
+		err   error
+		class string
+	}{
+		{&net.DNSError{Err: "no such host", Name: "pve.invalid", IsNotFound: true}, "dns"},
+		{timeout, "timeout"},
+		{dial(syscall.ECONNREFUSED), "refused"},
+		{dial(syscall.EHOSTUNREACH), "unreachable"},
+		{dial(syscall.ENETUNREACH), "unreachable"},
+		{&ssh.ExitError{}, "exit_status"},
+		{errors.New("ssh: handshake failed: ssh: unable to authenticate, attempted methods [none password]"), "auth"},
+		{errors.New("ssh: handshake failed: EOF"), "handshake"},
+		{errors.New("ssh: rejected: administratively prohibited"), "other"},
+	} {
+		t.Run(test.class, func(t *testing.T) {
+			// Wrapping, as ConnectAndExecute does, keeps the class
+			assert.Equal(t, test.class, ErrorClass(fmt.Errorf("failed to dial: %w", test.err)))
+		})
+	}
+}
+
+func TestSSHFailed(t *testing.T) {
+	before := testutil.ToFloat64(sshErrors.WithLabelValues(StageExec, "exit_status"))
+	SSHFailed(StageExec, &ssh.ExitError{})
+	assert.Equal(t, before+1, testutil.ToFloat64(sshErrors.WithLabelValues(StageExec, "exit_status")))
+}
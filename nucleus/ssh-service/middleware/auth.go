@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/Talfaza/ssh-service/logging"
+	"github.com/Talfaza/ssh-service/problem"
+	"github.com/gofiber/fiber/v3"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// AuthRequired accepts the identity forwarded by a trusted gateway, the
+// jwt session cookie, or an Authorization: Bearer header carrying either a
+// JWT or a personal access token.
+func AuthRequired(c fiber.Ctx) error {
+	claims, forwarded, err := gatewayClaims(c)
+	if !forwarded {
+		raw := bearerToken(c)
+		if raw == "" {
+			raw = c.Cookies("jwt")
+		}
+
+		if strings.HasPrefix(raw, apiTokenPrefix) {
+			claims, err = Tokens.Claims(logging.Context(c), raw)
+		} else {
+			claims, err = parseJWT(raw)
+			if err == nil && Revoked.Contains(claims.ID) {
+				err = ErrSessionRevoked
+			}
+		}
+	}
+
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	if _, err := claims.UserID(); err != nil {
+		return problem.New(fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	c.Locals("claims", claims)
+	return c.Next()
+}
+
+func bearerToken(c fiber.Ctx) string {
+	header := c.Get(fiber.HeaderAuthorization)
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+func parseJWT(raw string) (*Claims, error) {
+	claims := new(Claims)
+	token, err := jwt.ParseWithClaims(raw, claims, Keys.Keyfunc,
+		jwt.WithValidMethods(Keys.Methods()),
+		// Only full sessions; auth-service also signs short-lived 2FA tokens
+		jwt.WithAudience(SessionAudience),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, jwt.ErrTokenUnverifiable
+	}
+	return claims, nil
+}
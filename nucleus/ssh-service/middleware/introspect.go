@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Talfaza/ssh-service/logging"
+	"github.com/Talfaza/ssh-service/tracing"
+)
+
+// apiTokenPrefix starts every personal access token issued by auth-service.
+const apiTokenPrefix = "nuc_"
+
+// introspectTTL bounds how long an answer is reused, and so how long a
+// revoked token keeps working here.
+const introspectTTL = 30 * time.Second
+
+var ErrInactiveToken = errors.New("API token is not active")
+
+type introspection struct {
+	claims    *Claims
+	expiresAt time.Time
+}
+
+// Introspector resolves personal access tokens through auth-service's
+// introspection endpoint and caches the answers briefly.
+type Introspector struct {
+	URL string
+	// Client makes the requests, http.DefaultClient if nil
+	Client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]introspection
+}
+
+// Tokens is the introspector used by AuthRequired. main sets it up from
+// the configuration.
+var Tokens = &Introspector{}
+
+// NewIntrospector returns an introspector asking the endpoint at url,
+// passing on the request ID and trace of each request.
+func NewIntrospector(url string) *Introspector {
+	return &Introspector{
+		URL:    url,
+		Client: &http.Client{Timeout: 5 * time.Second, Transport: logging.Transport{Base: tracing.Transport{}}},
+	}
+}
+
+// Claims returns the claims of an active token. The request ID in ctx, if
+// any, is passed on to auth-service.
+func (i *Introspector) Claims(ctx context.Context, token string) (*Claims, error) {
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+
+	i.mu.Lock()
+	entry, ok := i.cache[key]
+	i.mu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		if entry.claims == nil {
+			return nil, ErrInactiveToken
+		}
+		return entry.claims, nil
+	}
+
+	claims, err := i.introspect(ctx, token)
+	if err != nil && !errors.Is(err, ErrInactiveToken) {
+		return nil, err
+	}
+
+	entry = introspection{claims: claims, expiresAt: time.Now().Add(introspectTTL)}
+	if claims != nil && claims.ExpiresAt != nil && claims.ExpiresAt.Before(entry.expiresAt) {
+		entry.expiresAt = claims.ExpiresAt.Time
+	}
+
+	i.mu.Lock()
+	if i.cache == nil {
+		i.cache = make(map[string]introspection)
+	}
+	for k, e := range i.cache {
+		if time.Now().After(e.expiresAt) {
+			delete(i.cache, k)
+		}
+	}
+	i.cache[key] = entry
+	i.mu.Unlock()
+
+	return claims, err
+}
+
+func (i *Introspector) introspect(ctx context.Context, token string) (*Claims, error) {
+	if i.URL == "" {
+		return nil, errors.New("no URL to introspect tokens at")
+	}
+	client := i.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	body, _ := json.Marshal(map[string]string{"token": token})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to introspect token: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to introspect token: status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Active bool `json:"active"`
+		Claims
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode introspection: %v", err)
+	}
+	if !result.Active {
+		return nil, ErrInactiveToken
+	}
+
+	// A token without scopes must not be mistaken for a session
+	if result.Scopes == nil {
+		result.Scopes = []string{}
+	}
+	return &result.Claims, nil
+}
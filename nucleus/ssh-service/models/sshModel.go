@@ -7,12 +7,15 @@ type SSHConfig struct {
 	Username string `json:"username"`
 	Host     string `json:"host"`
 	Port     string `json:"port"`
+	// RequestID ties the record to the log lines of the request that ran
+	// the command, in every service it went through
+	RequestID string `json:"request_id" gorm:"index;size:128"`
 }
 
 type SSHRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
-	Host     string `json:"host"`
-	Port     string `json:"port"`
-	Command  string `json:"command"`
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+	Host     string `json:"host" validate:"required,hostname|ip"`
+	Port     string `json:"port" validate:"required,port"`
+	Command  string `json:"command" validate:"required"`
 }
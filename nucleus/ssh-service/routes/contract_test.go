@@ -0,0 +1,209 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Talfaza/ssh-service/api"
+	"github.com/Talfaza/ssh-service/apptest"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// newTestApp serves the API and its document from an in-memory SQLite
+// database.
+func newTestApp(t *testing.T) *fiber.App {
+	t.Helper()
+
+	app := apptest.New(t)
+	api.Register(app)
+	Setup(app)
+	return app
+}
+
+// contract makes requests to the app, failing the test when an answer does
+// not match the OpenAPI document the app serves, or a request the document
+// rejects is not rejected by the app too.
+type contract struct {
+	t      *testing.T
+	app    *fiber.App
+	doc    *openapi3.T
+	router routers.Router
+}
+
+func newContract(t *testing.T, app *fiber.App) *contract {
+	t.Helper()
+
+	resp := apptest.Do(t, app, httptest.NewRequest("GET", "/openapi.json", nil))
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	spec, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	doc, err := openapi3.NewLoader().LoadFromData(spec)
+	require.NoError(t, err)
+	require.NoError(t, doc.Validate(context.Background()))
+	router, err := gorillamux.NewRouter(doc)
+	require.NoError(t, err)
+	return &contract{t: t, app: app, doc: doc, router: router}
+}
+
+// call makes a JSON request as the gateway identity and returns the
+// answer, its body left to read.
+func (c *contract) call(identity, method, path, body string) *http.Response {
+	c.t.Helper()
+
+	req := apptest.Request(identity, method, path, body)
+
+	route, params, err := c.router.FindRoute(req)
+	require.NoError(c.t, err, "%s %s is not documented", method, path)
+	input := &openapi3filter.RequestValidationInput{
+		Request:    req,
+		PathParams: params,
+		Route:      route,
+		Options:    &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+	}
+	invalid := openapi3filter.ValidateRequest(context.Background(), input)
+
+	// The validation consumed the body, and filled in defaults
+	req.Body = io.NopCloser(strings.NewReader(body))
+	req.ContentLength = int64(len(body))
+	resp := apptest.Do(c.t, c.app, req)
+	answer, err := io.ReadAll(resp.Body)
+	require.NoError(c.t, err)
+	assert.NoError(c.t, openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 resp.StatusCode,
+		Header:                 resp.Header,
+		Body:                   io.NopCloser(bytes.NewReader(answer)),
+	}), "%s %s answered %d %s", method, path, resp.StatusCode, answer)
+	if invalid != nil {
+		assert.GreaterOrEqual(c.t, resp.StatusCode, fiber.StatusBadRequest, "%s %s accepted an invalid request: %v", method, path, invalid)
+		assert.Less(c.t, resp.StatusCode, fiber.StatusInternalServerError, "%s %s failed on an invalid request: %v", method, path, invalid)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(answer))
+	return resp
+}
+
+var routeParam = regexp.MustCompile(`:(\w+)`)
+
+// TestRoutesDocumented checks every route is documented and every
+// documented operation is routed.
+func TestRoutesDocumented(t *testing.T) {
+	app := newTestApp(t)
+	c := newContract(t, app)
+
+	var routed []string
+	for _, route := range app.GetRoutes(true) {
+		if route.Method == fiber.MethodHead || route.Path == "/openapi.json" {
+			continue
+		}
+		path := routeParam.ReplaceAllString(route.Path, "{$1}")
+		if path != "/" {
+			path = strings.TrimSuffix(path, "/")
+		}
+		routed = append(routed, route.Method+" "+path)
+	}
+	var documented []string
+	for path, item := range c.doc.Paths.Map() {
+		for method := range item.Operations() {
+			documented = append(documented, method+" "+path)
+		}
+	}
+	sort.Strings(routed)
+	sort.Strings(documented)
+	assert.Equal(t, documented, routed)
+}
+
+// sshServer starts an SSH server accepting any password and answering
+// every command with output, and returns its host and port.
+func sshServer(t *testing.T, output string) (string, string) {
+	t.Helper()
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(private)
+	require.NoError(t, err)
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) { return nil, nil },
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSSH(conn, config, output)
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	return host, port
+}
+
+func serveSSH(conn net.Conn, config *ssh.ServerConfig, output string) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range requests {
+				_ = req.Reply(req.Type == "exec", nil)
+				if req.Type == "exec" {
+					_, _ = channel.Write([]byte(output))
+					_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+					channel.Close()
+				}
+			}
+		}()
+	}
+}
+
+func TestContract(t *testing.T) {
+	c := newContract(t, newTestApp(t))
+	operator := `{"sub":"1","roles":["operator"]}`
+	viewer := `{"sub":"2","roles":["viewer"]}`
+
+	host, port := sshServer(t, "pve-manager/8.2.4\n")
+	command := `{"username":"root","password":"secret","host":"` + host + `","port":"` + port + `","command":"pveversion"}`
+	resp := c.call(operator, "POST", "/execute", command)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp = c.call(viewer, "POST", "/execute", command)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	resp = c.call(operator, "POST", "/execute", `{"username":"root","host":"`+host+`","port":"0","command":"pveversion"}`)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+
+	// Nothing listens on the port once the server is closed
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	_, closed, _ := net.SplitHostPort(listener.Addr().String())
+	listener.Close()
+	resp = c.call(operator, "POST", "/execute", `{"username":"root","password":"secret","host":"127.0.0.1","port":"`+closed+`","command":"pveversion"}`)
+	assert.Equal(t, fiber.StatusBadGateway, resp.StatusCode)
+}
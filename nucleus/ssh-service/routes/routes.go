@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/Talfaza/ssh-service/middleware"
+	"github.com/Talfaza/ssh-service/service"
+	"github.com/gofiber/fiber/v3"
+)
+
+// Setup registers the API, as documented in api/openapi.yaml. Running
+// commands on a hypervisor is a write; viewers cannot do it.
+func Setup(app *fiber.App) {
+	protected := app.Group("/", middleware.AuthRequired)
+	protected.Post("/execute",
+		middleware.RequireRole(middleware.RoleAdmin, middleware.RoleOperator),
+		middleware.RequireScope(middleware.ScopeExecute),
+		services.ExecuteCommand)
+}
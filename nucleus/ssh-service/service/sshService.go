@@ -1,15 +1,32 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/Talfaza/ssh-service/database"
+	"github.com/Talfaza/ssh-service/logging"
+	"github.com/Talfaza/ssh-service/metrics"
+	"github.com/Talfaza/ssh-service/middleware"
 	"github.com/Talfaza/ssh-service/models"
+	"github.com/Talfaza/ssh-service/problem"
+	"github.com/Talfaza/ssh-service/tracing"
+	"github.com/Talfaza/ssh-service/validation"
 	"github.com/gofiber/fiber/v3"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 	"golang.org/x/crypto/ssh"
 )
 
-func ConnectAndExecute(req models.SSHRequest) (string, error) {
+// ConnectAndExecute runs req.Command on req.Host, recording a span of each
+// step under the span in ctx.
+func ConnectAndExecute(ctx context.Context, req models.SSHRequest) (string, error) {
 	sshConfig := &ssh.ClientConfig{
 		User: req.Username,
 		Auth: []ssh.AuthMethod{
@@ -18,61 +35,145 @@ func ConnectAndExecute(req models.SSHRequest) (string, error) {
 		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
 	}
 
+	_, span := tracing.Start(ctx, "ssh.dial",
+		semconv.ServerAddress(req.Host),
+		attribute.String("server.port", req.Port),
+		attribute.String("ssh.user", req.Username))
+	start := time.Now()
 	client, err := ssh.Dial("tcp", fmt.Sprintf("%s:%s", req.Host, req.Port), sshConfig)
+	metrics.SSHDial.Observe(time.Since(start).Seconds())
+	tracing.End(span, err)
 	if err != nil {
+		metrics.SSHFailed(metrics.StageDial, err)
 		return "", fmt.Errorf("failed to dial: %v", err)
 	}
 	defer client.Close()
 
+	_, span = tracing.Start(ctx, "ssh.session")
 	session, err := client.NewSession()
+	tracing.End(span, err)
 	if err != nil {
+		metrics.SSHFailed(metrics.StageSession, err)
 		return "", fmt.Errorf("failed to create session: %v", err)
 	}
 	defer session.Close()
 
+	// The command is left out of the span: it may carry secrets
+	_, span = tracing.Start(ctx, "ssh.exec")
+	start = time.Now()
 	output, err := session.CombinedOutput(req.Command)
+	metrics.SSHExec.Observe(time.Since(start).Seconds())
+	tracing.End(span, err)
 	if err != nil {
+		metrics.SSHFailed(metrics.StageExec, err)
 		return "", fmt.Errorf("failed to run command: %v", err)
 	}
 
 	return string(output), nil
 }
 
+// greetTimeout bounds how long a host gets to greet, so a slow one does not
+// use up the time of the others.
+const greetTimeout = 2 * time.Second
+
+// HostsReachable returns a readiness check that every host, a host:port
+// address, accepts connections and greets with an SSH banner. Hosts are
+// checked concurrently, each with its own deadline.
+func HostsReachable(hosts []string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		errs := make([]error, len(hosts))
+		var wg sync.WaitGroup
+		for i, host := range hosts {
+			wg.Add(1)
+			go func(i int, host string) {
+				defer wg.Done()
+				ctx, cancel := context.WithTimeout(ctx, greetTimeout)
+				defer cancel()
+
+				if err := greets(ctx, host); err != nil {
+					errs[i] = fmt.Errorf("%s: %v", host, err)
+				}
+			}(i, host)
+		}
+		wg.Wait()
+		return errors.Join(errs...)
+	}
+}
+
+func greets(ctx context.Context, host string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetReadDeadline(deadline)
+	}
+	banner := make([]byte, 4)
+	if _, err := io.ReadFull(conn, banner); err != nil {
+		return fmt.Errorf("no SSH banner: %v", err)
+	}
+	if !strings.HasPrefix(string(banner), "SSH-") {
+		return errors.New("not an SSH server")
+	}
+	return nil
+}
+
 func ExecuteCommand(c fiber.Ctx) error {
 	var req models.SSHRequest
 
 	if err := c.Bind().Body(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request payload",
-		})
+		return problem.New(fiber.StatusBadRequest, "Invalid request payload")
+	}
+	if errs := validation.Struct(&req); errs != nil {
+		return problem.Invalid(errs...)
 	}
 
 	if database.DB == nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Database not connected",
-		})
+		return problem.New(fiber.StatusServiceUnavailable, "Database not connected")
 	}
 
 	config := models.SSHConfig{
-		Username: req.Username,
-		Host:     req.Host,
-		Port:     req.Port,
+		Username:  req.Username,
+		Host:      req.Host,
+		Port:      req.Port,
+		RequestID: logging.ID(c),
 	}
 
-	if err := database.DB.Create(&config).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to store SSH config",
-		})
+	if err := database.DB.WithContext(tracing.Context(c)).Create(&config).Error; err != nil {
+		return problem.New(fiber.StatusInternalServerError, "Failed to store SSH config")
 	}
 
-	output, err := ConnectAndExecute(req)
+	start := time.Now()
+	output, err := ConnectAndExecute(tracing.Context(c), req)
+	audit(c, config, time.Since(start), err)
+	// The host failing is not ours to answer for
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": fmt.Sprintf("Error executing command: %v", err),
-		})
+		return problem.New(fiber.StatusBadGateway, fmt.Sprintf("Error executing command: %v", err))
 	}
 
 	return c.JSON(fiber.Map{
 		"output": output,
 	})
 }
+
+// audit logs who ran a command where, and how it went. The command itself
+// is left out: it may carry secrets.
+func audit(c fiber.Ctx, config models.SSHConfig, duration time.Duration, err error) {
+	userID, _ := middleware.UserID(c)
+	attrs := []any{
+		"ssh_config_id", config.ID,
+		"user_id", userID,
+		"username", config.Username,
+		"host", config.Host,
+		"port", config.Port,
+		"duration_ms", duration.Milliseconds(),
+	}
+	if err != nil {
+		logging.Logger(c).Warn("SSH command failed", append(attrs, "error", err)...)
+		return
+	}
+	logging.Logger(c).Info("SSH command ran", attrs...)
+}
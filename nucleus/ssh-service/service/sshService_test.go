@@ -0,0 +1,432 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Talfaza/ssh-service/apptest"
+	"github.com/Talfaza/ssh-service/database"
+	"github.com/Talfaza/ssh-service/logging"
+	"github.com/Talfaza/ssh-service/middleware"
+	"github.com/Talfaza/ssh-service/models"
+	"github.com/Talfaza/ssh-service/problem"
+	"github.com/gofiber/fiber/v3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"golang.org/x/crypto/ssh"
+)
+
+// newTestApp serves the API from an in-memory SQLite database, tagging
+// requests with IDs.
+func newTestApp(t *testing.T) *fiber.App {
+	t.Helper()
+
+	app := apptest.New(t)
+	logging.Register(app)
+	app.Post("/execute", middleware.AuthRequired, ExecuteCommand)
+	return app
+}
+
+// execute runs a command as the gateway identity in the request requestID
+// and returns the answer's status and body.
+func execute(t *testing.T, app *fiber.App, requestID, body string) (int, string) {
+	t.Helper()
+
+	req := apptest.Request(`{"sub":"1","roles":["operator"]}`, "POST", "/execute", body)
+	req.Header.Set(fiber.HeaderXRequestID, requestID)
+	resp := apptest.Do(t, app, req)
+	var out bytes.Buffer
+	_, err := out.ReadFrom(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, out.String()
+}
+
+// captureLogs sends the default logger's lines to the returned buffer
+// until the test ends.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+	return &logs
+}
+
+// logLine returns the first JSON log line with msg.
+func logLine(t *testing.T, logs *bytes.Buffer, msg string) map[string]interface{} {
+	t.Helper()
+
+	for _, line := range strings.Split(logs.String(), "\n") {
+		var entry map[string]interface{}
+		if json.Unmarshal([]byte(line), &entry) == nil && entry["msg"] == msg {
+			return entry
+		}
+	}
+	t.Fatalf("no %q log line in:\n%s", msg, logs.String())
+	return nil
+}
+
+// listen serves each connection with serve on a local port and returns
+// its host:port address.
+func listen(t *testing.T, serve func(net.Conn)) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				serve(conn)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// greeter greets like an SSH server after delay.
+func greeter(delay time.Duration) func(net.Conn) {
+	return func(conn net.Conn) {
+		time.Sleep(delay)
+		_, _ = conn.Write([]byte("SSH-2.0-OpenSSH_9.6\r\n"))
+	}
+}
+
+func TestHostsReachable(t *testing.T) {
+	web := listen(t, func(conn net.Conn) {
+		_, _ = conn.Write([]byte("HTTP/1.1 400 Bad Request\r\n\r\n"))
+	})
+	silent := listen(t, func(conn net.Conn) {
+		_, _ = conn.Read(make([]byte, 1))
+	})
+	closed := listen(t, func(net.Conn) {})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	ssh := listen(t, greeter(0))
+	err := HostsReachable([]string{ssh, web, silent, closed})(ctx)
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), ssh)
+	assert.Contains(t, err.Error(), web+": not an SSH server")
+	assert.Contains(t, err.Error(), silent+": no SSH banner")
+	assert.Contains(t, err.Error(), closed+": no SSH banner")
+
+	assert.NoError(t, HostsReachable(nil)(context.Background()))
+}
+
+func TestHostsReachableConcurrently(t *testing.T) {
+	// Checked one after the other, the hosts would need 600ms
+	hosts := []string{listen(t, greeter(200*time.Millisecond)), listen(t, greeter(200*time.Millisecond)), listen(t, greeter(200*time.Millisecond))}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	assert.NoError(t, HostsReachable(hosts)(ctx))
+}
+
+// sshServer starts an SSH server accepting any password but "wrong". It
+// answers the command "false" with exit status 1 and any other with output,
+// and returns its host and port.
+func sshServer(t *testing.T, output string) (string, string) {
+	t.Helper()
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(private)
+	require.NoError(t, err)
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(_ ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if string(password) == "wrong" {
+				return nil, errors.New("wrong password")
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(signer)
+
+	host, port, _ := net.SplitHostPort(listen(t, func(conn net.Conn) { serveSSH(conn, config, output) }))
+	return host, port
+}
+
+func serveSSH(conn net.Conn, config *ssh.ServerConfig, output string) {
+	server, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	defer server.Close()
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range requests {
+				_ = req.Reply(req.Type == "exec", nil)
+				if req.Type != "exec" {
+					continue
+				}
+				var exec struct{ Command string }
+				_ = ssh.Unmarshal(req.Payload, &exec)
+				status := uint32(0)
+				if exec.Command == "false" {
+					status = 1
+				} else {
+					_, _ = channel.Write([]byte(output))
+				}
+				_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+				channel.Close()
+			}
+		}()
+	}
+}
+
+// closedPort returns a local port nothing listens on.
+func closedPort(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	ln.Close()
+	return port
+}
+
+// observed returns the value of the counter, or the number of samples of
+// the histogram, name with labels.
+func observed(t *testing.T, name string, labels map[string]string) float64 {
+	t.Helper()
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, metric := range family.GetMetric() {
+			if len(metric.GetLabel()) != len(labels) {
+				continue
+			}
+			for _, label := range metric.GetLabel() {
+				if labels[label.GetName()] != label.GetValue() {
+					continue metrics
+				}
+			}
+			if histogram := metric.GetHistogram(); histogram != nil {
+				return float64(histogram.GetSampleCount())
+			}
+			return metric.GetCounter().GetValue()
+		}
+	}
+	return 0
+}
+
+func TestConnectAndExecuteMetrics(t *testing.T) {
+	host, port := sshServer(t, "pve-manager/8.2.4\n")
+	req := models.SSHRequest{Username: "root", Password: "secret", Host: host, Port: port, Command: "pveversion"}
+	dials := observed(t, "nucleus_ssh_dial_duration_seconds", nil)
+	execs := observed(t, "nucleus_ssh_exec_duration_seconds", nil)
+	errorCount := func(stage, class string) float64 {
+		return observed(t, "nucleus_ssh_errors_total", map[string]string{"stage": stage, "class": class})
+	}
+	auth, exit, refused := errorCount("dial", "auth"), errorCount("exec", "exit_status"), errorCount("dial", "refused")
+
+	output, err := ConnectAndExecute(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "pve-manager/8.2.4\n", output)
+	assert.Equal(t, dials+1, observed(t, "nucleus_ssh_dial_duration_seconds", nil))
+	assert.Equal(t, execs+1, observed(t, "nucleus_ssh_exec_duration_seconds", nil))
+
+	// Failed steps are timed too, and counted by class
+	failing := req
+	failing.Command = "false"
+	_, err = ConnectAndExecute(context.Background(), failing)
+	require.Error(t, err)
+	assert.Equal(t, exit+1, errorCount("exec", "exit_status"))
+	assert.Equal(t, execs+2, observed(t, "nucleus_ssh_exec_duration_seconds", nil))
+
+	failing = req
+	failing.Password = "wrong"
+	_, err = ConnectAndExecute(context.Background(), failing)
+	require.Error(t, err)
+	assert.Equal(t, auth+1, errorCount("dial", "auth"))
+
+	failing = req
+	failing.Port = closedPort(t)
+	_, err = ConnectAndExecute(context.Background(), failing)
+	require.Error(t, err)
+	assert.Equal(t, refused+1, errorCount("dial", "refused"))
+	assert.Equal(t, dials+4, observed(t, "nucleus_ssh_dial_duration_seconds", nil))
+	assert.Equal(t, execs+2, observed(t, "nucleus_ssh_exec_duration_seconds", nil))
+}
+
+func TestAudit(t *testing.T) {
+	app := newTestApp(t)
+	logs := captureLogs(t)
+	host, port := sshServer(t, "ok\n")
+	command := func(password, cmd string) string {
+		return `{"username":"root","password":"` + password + `","host":"` + host + `","port":"` + port + `","command":"` + cmd + `"}`
+	}
+
+	status, _ := execute(t, app, "req-42", command("hunter2", "pveversion"))
+	require.Equal(t, fiber.StatusOK, status)
+	status, _ = execute(t, app, "req-43", command("hunter2", "false"))
+	require.Equal(t, fiber.StatusBadGateway, status)
+
+	ran := logLine(t, logs, "SSH command ran")
+	assert.Equal(t, "req-42", ran["request_id"])
+	assert.Equal(t, 1.0, ran["user_id"])
+	assert.Equal(t, host, ran["host"])
+	assert.Equal(t, 1.0, ran["ssh_config_id"])
+	failed := logLine(t, logs, "SSH command failed")
+	assert.Equal(t, "req-43", failed["request_id"])
+	assert.Contains(t, failed["error"], "failed to run command")
+	// Neither the password nor the command, which may carry secrets, are logged
+	assert.NotContains(t, logs.String(), "hunter2")
+	assert.NotContains(t, logs.String(), "pveversion")
+
+	// The stored record leads to the log lines of its request
+	var configs []models.SSHConfig
+	require.NoError(t, database.DB.Order("id").Find(&configs).Error)
+	require.Len(t, configs, 2)
+	assert.Equal(t, "req-42", configs[0].RequestID)
+	assert.Equal(t, "req-43", configs[1].RequestID)
+}
+
+// recorded keeps the spans of every test in memory. The tracer of the
+// tracing package only follows the first provider set, so it is set once.
+var recorded = struct {
+	once     sync.Once
+	exporter *tracetest.InMemoryExporter
+	provider *sdktrace.TracerProvider
+}{exporter: tracetest.NewInMemoryExporter()}
+
+func TestConnectAndExecuteSpans(t *testing.T) {
+	recorded.once.Do(func() {
+		recorded.provider = sdktrace.NewTracerProvider(sdktrace.WithSyncer(recorded.exporter))
+		otel.SetTracerProvider(recorded.provider)
+	})
+	exporter, provider := recorded.exporter, recorded.provider
+
+	host, port := sshServer(t, "ok\n")
+	req := models.SSHRequest{Username: "root", Password: "secret", Host: host, Port: port, Command: "pveversion"}
+	run := func(req models.SSHRequest) tracetest.SpanStubs {
+		t.Helper()
+		exporter.Reset()
+		ctx, parent := provider.Tracer("test").Start(context.Background(), "POST /execute")
+		_, _ = ConnectAndExecute(ctx, req)
+		parent.End()
+
+		spans := exporter.GetSpans()
+		require.NotEmpty(t, spans)
+		for _, span := range spans[:len(spans)-1] {
+			assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID(), span.Name)
+		}
+		return spans[:len(spans)-1]
+	}
+	names := func(spans tracetest.SpanStubs) []string {
+		var names []string
+		for _, span := range spans {
+			names = append(names, span.Name)
+		}
+		return names
+	}
+
+	spans := run(req)
+	require.Equal(t, []string{"ssh.dial", "ssh.session", "ssh.exec"}, names(spans))
+	dial := attribute.NewSet(spans[0].Attributes...)
+	value, _ := dial.Value("server.address")
+	assert.Equal(t, host, value.AsString())
+	value, _ = dial.Value("server.port")
+	assert.Equal(t, port, value.AsString())
+	value, _ = dial.Value("ssh.user")
+	assert.Equal(t, "root", value.AsString())
+	for _, span := range spans {
+		assert.Equal(t, codes.Unset, span.Status.Code, span.Name)
+		// The command may carry secrets
+		for _, attr := range span.Attributes {
+			assert.NotContains(t, attr.Value.Emit(), "pveversion")
+		}
+	}
+
+	failing := req
+	failing.Command = "false"
+	spans = run(failing)
+	require.Equal(t, []string{"ssh.dial", "ssh.session", "ssh.exec"}, names(spans))
+	assert.Equal(t, codes.Error, spans[2].Status.Code)
+
+	failing = req
+	failing.Port = closedPort(t)
+	spans = run(failing)
+	require.Equal(t, []string{"ssh.dial"}, names(spans))
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+}
+
+func TestExecuteValidation(t *testing.T) {
+	app := newTestApp(t)
+
+	var invalid struct {
+		Errors []map[string]string `json:"errors"`
+	}
+	status, body := execute(t, app, "req-1", `{"username":"root","host":"not a host","port":"0","command":"pveversion"}`)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, status)
+	require.NoError(t, json.Unmarshal([]byte(body), &invalid))
+	assert.Equal(t, []map[string]string{
+		{"field": "password", "message": "password is required"},
+		{"field": "host", "message": "host must be a hostname or IP address"},
+		{"field": "port", "message": "port must be a port number from 1 to 65535"},
+	}, invalid.Errors)
+
+	status, body = execute(t, app, "req-2", `{"username":"root","password":"secret","host":"10.0.0.2","port":"22"}`)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, status)
+	require.NoError(t, json.Unmarshal([]byte(body), &invalid))
+	assert.Equal(t, []map[string]string{{"field": "command", "message": "command is required"}}, invalid.Errors)
+
+	// Nothing is recorded, let alone run, for invalid requests
+	var count int64
+	require.NoError(t, database.DB.Model(&models.SSHConfig{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestExecuteProblem(t *testing.T) {
+	app := newTestApp(t)
+
+	// The host failing is answered as a problem of the gateway
+	body := `{"username":"root","password":"secret","host":"127.0.0.1","port":"` + closedPort(t) + `","command":"pveversion"}`
+	req := apptest.Request(`{"sub":"1"}`, "POST", "/execute", body)
+	req.Header.Set(fiber.HeaderXRequestID, "req-7")
+	resp := apptest.Do(t, app, req)
+	assert.Equal(t, fiber.StatusBadGateway, resp.StatusCode)
+	assert.Equal(t, problem.ContentType, resp.Header.Get(fiber.HeaderContentType))
+
+	var answer map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&answer))
+	assert.Equal(t, "Bad Gateway", answer["title"])
+	assert.Equal(t, 502.0, answer["status"])
+	assert.Equal(t, "/execute", answer["instance"])
+	assert.Equal(t, "req-7", answer["request_id"])
+	assert.Contains(t, answer["detail"], "failed to dial")
+	assert.Equal(t, problem.CodeBadGateway, answer["code"])
+}
@@ -0,0 +1,128 @@
+// Package validation checks request bodies against the validate tags of
+// the structs they are bound to, see github.com/go-playground/validator.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/Talfaza/ssh-service/problem"
+	"github.com/go-playground/validator/v10"
+)
+
+// Errors lists the fields of a body that failed validation, answered with
+// problem.Invalid.
+type Errors []problem.FieldError
+
+var validate = newValidator()
+
+// messages explains the tags whose explanation takes no parameter.
+var messages = map[string]string{
+	"required":    "is required",
+	"email":       "must be an email address",
+	"hostname|ip": "must be a hostname or IP address",
+	"port":        "must be a port number from 1 to 65535",
+}
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	_ = v.RegisterValidation("port", port)
+	return v
+}
+
+// port accepts a TCP port as a number or a string.
+func port(fl validator.FieldLevel) bool {
+	var n int64
+	switch field := fl.Field(); field.Kind() {
+	case reflect.String:
+		var err error
+		if n, err = strconv.ParseInt(field.String(), 10, 32); err != nil {
+			return false
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = field.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = int64(field.Uint())
+	default:
+		return false
+	}
+	return n >= 1 && n <= 65535
+}
+
+// Struct checks v, a pointer to a struct, against its validate tags and
+// returns the fields that fail, or nil. The fields named in except, by
+// their Go name, are not checked.
+func Struct(v interface{}, except ...string) Errors {
+	var err error
+	if len(except) > 0 {
+		err = validate.StructExcept(v, except...)
+	} else {
+		err = validate.Struct(v)
+	}
+
+	if err == nil {
+		return nil
+	}
+	var invalid validator.ValidationErrors
+	if !errors.As(err, &invalid) {
+		// v is not a struct, a bug in the handler
+		panic(err)
+	}
+	errs := make(Errors, len(invalid))
+	for i, fe := range invalid {
+		field := fieldName(fe)
+		errs[i] = problem.FieldError{Field: field, Message: field + " " + describe(fe)}
+	}
+	return errs
+}
+
+// fieldName is the path to the field from the body, e.g. packages[nginx],
+// without the name of the struct it was bound to.
+func fieldName(fe validator.FieldError) string {
+	_, name, found := strings.Cut(fe.Namespace(), ".")
+	if !found {
+		return fe.Field()
+	}
+	return name
+}
+
+func describe(fe validator.FieldError) string {
+	if message, ok := messages[fe.Tag()]; ok {
+		return message
+	}
+
+	counted := ""
+	switch fe.Kind() {
+	case reflect.String:
+		counted = " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		counted = " items"
+	}
+	switch fe.Tag() {
+	case "min":
+		if counted == " items" && fe.Param() == "1" {
+			return "must not be empty"
+		}
+		return fmt.Sprintf("must be at least %s%s", fe.Param(), counted)
+	case "max":
+		return fmt.Sprintf("must be at most %s%s", fe.Param(), counted)
+	case "len":
+		return fmt.Sprintf("must be exactly %s%s", fe.Param(), counted)
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fe.Param()), ", ")
+	}
+	return "is invalid"
+}